@@ -0,0 +1,346 @@
+// Package cd provides coordinate descent implementation for elastic net regularized models.
+//
+// Coordinate descent minimizes a cost function with respect to one coefficient at a time. The L1 penalty term
+// isn't differentiable at zero, so gradient descent cannot produce exactly zero coefficients. Coordinate descent
+// solves every one-dimensional problem exactly through soft-thresholding, which yields sparse models.
+//
+// A design matrix must include the dummy feature (equal 1) as the first column. The intercept is never penalized.
+package cd
+
+import (
+	"context"
+	"math"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/matrix"
+)
+
+const (
+	// tolerance determines the convergence of the coordinate descent. It's compared against the largest change
+	// of the fitted values caused by a single coefficient update, relative to the target vector variance.
+	tolerance = 1e-16
+	// maxPasses limits the number of passes over coefficients in a single coordinate descent run.
+	maxPasses = 100000
+	// maxReweightings limits the number of iteratively reweighted least squares iterations for logistic regression.
+	maxReweightings = 100
+	// minWeight is the smallest weight of a training example used by iteratively reweighted least squares.
+	minWeight = 1e-5
+)
+
+// Penalty holds the elastic net penalty strengths.
+//
+// The penalty term equals L1*sum(|Oj|)+L2/2*sum(Oj^2), where the intercept is excluded from both sums.
+type Penalty struct {
+	L1 float64
+	L2 float64
+}
+
+// LeastSquares finds the coefficients minimizing the elastic net penalized least squares cost function
+// 1/2*sum((y-OX)^2)+penalty. The coordinate descent starts from the coefficients init (warm start).
+// If init is nil, it starts from the zero vector.
+func LeastSquares(ctx context.Context, x [][]float64, y []float64, init []float64, p Penalty) ([]float64, error) {
+	coeffs, err := start(x, init)
+	if err != nil {
+		return nil, err
+	}
+	w := make([]float64, len(x))
+	for i := range w {
+		w[i] = 1
+	}
+	if err := solve(ctx, x, y, w, coeffs, p); err != nil {
+		return nil, err
+	}
+	return coeffs, nil
+}
+
+// Logistic finds the coefficients minimizing the elastic net penalized logistic regression cost function
+// -sum(y*log(h)+(1-y)*log(1-h))+penalty, where h is the sigmoid function.
+// The coordinate descent starts from the coefficients init (warm start). If init is nil, it starts from the zero vector.
+//
+// The cost function is minimized through iteratively reweighted least squares. Each iteration approximates
+// the cost function with a weighted least squares problem solved by the coordinate descent.
+func Logistic(ctx context.Context, x [][]float64, y []float64, init []float64, p Penalty) ([]float64, error) {
+	coeffs, err := start(x, init)
+	if err != nil {
+		return nil, err
+	}
+	m := len(x)
+	z := make([]float64, m)
+	w := make([]float64, m)
+	prev := make([]float64, len(coeffs))
+	for k := 0; k < maxReweightings; k++ {
+		// Approximate the cost function around the current coefficients.
+		for i := 0; i < m; i++ {
+			eta := dot(x[i], coeffs)
+			h := 1 / (1 + math.Exp(-eta))
+			w[i] = math.Max(h*(1-h), minWeight)
+			z[i] = eta + (y[i]-h)/w[i]
+		}
+		copy(prev, coeffs)
+		if err := solve(ctx, x, z, w, coeffs, p); err != nil {
+			return nil, err
+		}
+		var d, s float64
+		for j := range coeffs {
+			d = math.Max(d, math.Abs(coeffs[j]-prev[j]))
+			s = math.Max(s, math.Abs(coeffs[j]))
+		}
+		if d <= 1e-9*(1+s) {
+			return coeffs, nil
+		}
+	}
+	return nil, regression.ErrCannotConverge
+}
+
+// start returns initial coefficients for a design matrix x.
+func start(x [][]float64, init []float64) ([]float64, error) {
+	coeffs := make([]float64, len(x[0]))
+	if init == nil {
+		return coeffs, nil
+	}
+	if len(init) != len(coeffs) {
+		return nil, regression.ErrInvalidFeatureVector
+	}
+	copy(coeffs, init)
+	return coeffs, nil
+}
+
+// solve minimizes the elastic net penalized weighted least squares cost function 1/2*sum(w*(z-OX)^2)+penalty
+// in place, starting from the passed coefficients.
+//
+// Features are centered around their weighted means, which decouples the intercept from other coefficients
+// and doesn't change the penalty. The intercept is then calculated directly.
+//
+// It uses an active set strategy. After a full pass over all coefficients, passes are limited to non-zero
+// coefficients until they converge. Then a full pass checks if the active set is complete.
+func solve(ctx context.Context, x [][]float64, z, w, coeffs []float64, p Penalty) error {
+	m, n := len(x), len(coeffs)
+	var sw, mz float64
+	for i := 0; i < m; i++ {
+		sw += w[i]
+		mz += w[i] * z[i]
+	}
+	mz /= sw
+	// mu holds the weighted means of features.
+	mu := make([]float64, n)
+	for j := 1; j < n; j++ {
+		for i := 0; i < m; i++ {
+			mu[j] += w[i] * x[i][j]
+		}
+		mu[j] /= sw
+	}
+	// r is the residuals vector of the centered problem.
+	r := make([]float64, m)
+	// v is the weighted variance of the target vector used to make the tolerance scale-invariant.
+	var v float64
+	for i := 0; i < m; i++ {
+		r[i] = z[i] - mz
+		v += w[i] * r[i] * r[i]
+		for j := 1; j < n; j++ {
+			r[i] -= (x[i][j] - mu[j]) * coeffs[j]
+		}
+	}
+	if v == 0 {
+		v = 1
+	}
+	// s holds the weighted sums of squares of centered features.
+	s := make([]float64, n)
+	for j := 1; j < n; j++ {
+		for i := 0; i < m; i++ {
+			s[j] += w[i] * (x[i][j] - mu[j]) * (x[i][j] - mu[j])
+		}
+	}
+	all := make([]int, 0, n-1)
+	for j := 1; j < n; j++ {
+		all = append(all, j)
+	}
+	pass := func(set []int) (float64, error) {
+		var d float64
+		for _, j := range set {
+			if s[j] == 0 {
+				// A constant feature cannot explain anything.
+				coeffs[j] = 0
+				continue
+			}
+			var rho float64
+			for i := 0; i < m; i++ {
+				rho += w[i] * (x[i][j] - mu[j]) * r[i]
+			}
+			rho += s[j] * coeffs[j]
+			nc := softThreshold(rho, p.L1) / (s[j] + p.L2)
+			if math.IsNaN(nc) || math.IsInf(nc, 0) {
+				return 0, regression.ErrCannotConverge
+			}
+			if delta := nc - coeffs[j]; delta != 0 {
+				for i := 0; i < m; i++ {
+					r[i] -= (x[i][j] - mu[j]) * delta
+				}
+				coeffs[j] = nc
+				d = math.Max(d, s[j]*delta*delta)
+			}
+		}
+		return d / v, nil
+	}
+	for k := 0; k < maxPasses; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		d, err := pass(all)
+		if err != nil {
+			return err
+		}
+		k++
+		if d < tolerance {
+			// Recover the intercept of the original (not centered) problem.
+			coeffs[0] = mz
+			for j := 1; j < n; j++ {
+				coeffs[0] -= mu[j] * coeffs[j]
+			}
+			return nil
+		}
+		var active []int
+		for _, j := range all {
+			if coeffs[j] != 0 {
+				active = append(active, j)
+			}
+		}
+		for ; k < maxPasses; k++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			d, err := pass(active)
+			if err != nil {
+				return err
+			}
+			if d < tolerance {
+				break
+			}
+		}
+	}
+	return regression.ErrCannotConverge
+}
+
+// softThreshold calculates the soft-thresholding operator value S(z,t)=sign(z)*max(|z|-t,0).
+func softThreshold(z, t float64) float64 {
+	switch {
+	case z > t:
+		return z - t
+	case z < -t:
+		return z + t
+	default:
+		return 0
+	}
+}
+
+func dot(x, coeffs []float64) float64 {
+	var v float64
+	for j := range x {
+		v += x[j] * coeffs[j]
+	}
+	return v
+}
+
+// A Solver finds elastic net penalized coefficients starting from the coefficients init.
+// LeastSquares and Logistic are solvers.
+type Solver func(ctx context.Context, x [][]float64, y []float64, init []float64, p Penalty) ([]float64, error)
+
+// Path computes coefficients for a decreasing geometric sequence of n regularization strengths.
+// The strengths start from the smallest value for which all coefficients (except the intercept) equal zero
+// and end at ratio of it. The alpha is the elastic net mixing parameter, the regularization strength lambda
+// is split into L1=alpha*lambda and L2=(1-alpha)*lambda.
+//
+// Every fit is warm-started from the coefficients of the previous one.
+func Path(ctx context.Context, solve Solver, x [][]float64, y []float64, alpha float64, n int, ratio float64) ([]float64, [][]float64, error) {
+	lambdas := make([]float64, n)
+	lambdas[0] = MaxLambda(x, y, alpha)
+	for k := 1; k < n; k++ {
+		lambdas[k] = lambdas[0] * math.Pow(ratio, float64(k)/float64(n-1))
+	}
+	coeffs := make([][]float64, n)
+	var init []float64
+	for k, l := range lambdas {
+		c, err := solve(ctx, x, y, init, Penalty{L1: alpha * l, L2: (1 - alpha) * l})
+		if err != nil {
+			return nil, nil, err
+		}
+		coeffs[k] = c
+		init = c
+	}
+	return lambdas, coeffs, nil
+}
+
+// MaxLambda calculates the smallest regularization strength for which all coefficients (except the intercept)
+// of the elastic net penalized least squares or logistic regression equal zero. The alpha is the elastic net mixing parameter.
+//
+// Both cost functions have the same gradient at the intercept-only model, so the strength equals max(|sum((y-mean(y))*Xj)|)/alpha.
+// Pure ridge regression never zeroes coefficients, so alpha is bounded below by 0.001.
+func MaxLambda(x [][]float64, y []float64, alpha float64) float64 {
+	var my float64
+	for _, v := range y {
+		my += v
+	}
+	my /= float64(len(y))
+	var l float64
+	for j := 1; j < len(x[0]); j++ {
+		var g float64
+		for i := range x {
+			g += (y[i] - my) * x[i][j]
+		}
+		l = math.Max(l, math.Abs(g))
+	}
+	// Enlarge the strength slightly, so that rounding errors don't leave tiny non-zero coefficients.
+	return l / math.Max(alpha, 1e-3) * (1 + 1e-9)
+}
+
+// DegreesOfFreedom calculates the effective number of parameters (excluding the intercept) of an elastic net
+// penalized model with weights of training examples w. For the least squares cost function all weights equal 1.
+//
+// It equals tr((Xa'WXa+L2*I)^-1*Xa'WXa), where Xa consists of centered features with non-zero coefficients.
+// Without the L2 penalty it's the number of non-zero coefficients.
+func DegreesOfFreedom(ctx context.Context, x [][]float64, w []float64, coeffs []float64, l2 float64) (float64, error) {
+	var active []int
+	for j := 1; j < len(coeffs); j++ {
+		if coeffs[j] != 0 {
+			active = append(active, j)
+		}
+	}
+	if l2 == 0 || len(active) == 0 {
+		return float64(len(active)), nil
+	}
+	var sw float64
+	for i := range x {
+		sw += w[i]
+	}
+	mu := make([]float64, len(active))
+	for a, j := range active {
+		for i := range x {
+			mu[a] += w[i] * x[i][j]
+		}
+		mu[a] /= sw
+	}
+	k := len(active)
+	g := make([][]float64, k)
+	p := make([][]float64, k)
+	for a := 0; a < k; a++ {
+		g[a] = make([]float64, k)
+		p[a] = make([]float64, k)
+		for b := 0; b < k; b++ {
+			for i := range x {
+				g[a][b] += w[i] * (x[i][active[a]] - mu[a]) * (x[i][active[b]] - mu[b])
+			}
+			p[a][b] = g[a][b]
+		}
+		p[a][a] += l2
+	}
+	inv, err := matrix.Inverse(ctx, p)
+	if err != nil {
+		return 0, err
+	}
+	var df float64
+	for a := 0; a < k; a++ {
+		for b := 0; b < k; b++ {
+			df += inv[a][b] * g[b][a]
+		}
+	}
+	return df, nil
+}
@@ -0,0 +1,250 @@
+package cd
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/erni27/regression/internal/regressiontest"
+)
+
+var (
+	x = [][]float64{
+		{1, 4, 1},
+		{1, 10, 3},
+		{1, 21, 2},
+		{1, 22, 5},
+		{1, 29, 1},
+		{1, 35, 8},
+		{1, 39, 2},
+		{1, 44, 7},
+		{1, 50, 4},
+		{1, 51, 9},
+	}
+	y = []float64{50, 33, 14, 13.5, 10, 8, 7.6, 7, 5, 4}
+	c = []float64{0, 0, 0, 1, 0, 1, 1, 1, 1, 1}
+)
+
+func TestSoftThreshold(t *testing.T) {
+	tests := []struct {
+		name string
+		z    float64
+		t    float64
+		want float64
+	}{
+		{name: "positive above threshold", z: 5, t: 2, want: 3},
+		{name: "negative below threshold", z: -5, t: 2, want: -3},
+		{name: "within threshold", z: 1.5, t: 2, want: 0},
+		{name: "zero threshold", z: -1.5, t: 0, want: -1.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := softThreshold(tt.z, tt.t); got != tt.want {
+				t.Fatalf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLeastSquares(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Penalty
+		want []float64
+	}{
+		{name: "no penalty", p: Penalty{}, want: []float64{39.483, -0.816, 0.143}},
+		{name: "lasso", p: Penalty{L1: 50}, want: []float64{38.96, -0.779, 0}},
+		{name: "ridge", p: Penalty{L2: 100}, want: []float64{38.624, -0.766, -0.009}},
+		{name: "elastic net", p: Penalty{L1: 20, L2: 100}, want: []float64{38.367, -0.759, 0}},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LeastSquares(ctx, x, y, nil, tt.p)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if !regressiontest.AreFloatSlicesEqual(got, tt.want, 3) {
+				t.Fatalf("want %v, got %v", tt.want, got)
+			}
+			checkOptimality(t, x, y, got, tt.p, func(v float64) float64 { return v })
+		})
+	}
+}
+
+func TestLeastSquares_WarmStart(t *testing.T) {
+	p := Penalty{L1: 50}
+	ctx := context.Background()
+	want, err := LeastSquares(ctx, x, y, nil, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, init := range [][]float64{{0, 0, 0}, {100, -5, 5}, want} {
+		got, err := LeastSquares(ctx, x, y, init, p)
+		if err != nil {
+			t.Fatalf("want nil, got error %v", err)
+		}
+		if !regressiontest.AreFloatSlicesEqual(got, want, 6) {
+			t.Fatalf("want %v, got %v", want, got)
+		}
+	}
+}
+
+func TestLogistic(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Penalty
+		want []float64
+	}{
+		{name: "lasso", p: Penalty{L1: 1}, want: []float64{-8.523, 0.221, 0.942}},
+		{name: "strong lasso", p: Penalty{L1: 10}, want: []float64{-2.996, 0.118, 0}},
+		{name: "elastic net", p: Penalty{L1: 1, L2: 1}, want: []float64{-6.71, 0.188, 0.594}},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Logistic(ctx, x, c, nil, tt.p)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if !regressiontest.AreFloatSlicesEqual(got, tt.want, 3) {
+				t.Fatalf("want %v, got %v", tt.want, got)
+			}
+			checkOptimality(t, x, c, got, tt.p, func(v float64) float64 { return 1 / (1 + math.Exp(-v)) })
+		})
+	}
+}
+
+func TestLeastSquares_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := LeastSquares(ctx, x, y, nil, Penalty{L1: 1}); err != context.Canceled {
+		t.Fatalf("want %v, got %v", context.Canceled, err)
+	}
+}
+
+// checkOptimality checks the optimality conditions of the elastic net penalized cost function, where
+// h maps a linear combination of features into the prediction.
+func checkOptimality(t *testing.T, x [][]float64, y []float64, coeffs []float64, p Penalty, h func(float64) float64) {
+	t.Helper()
+	for j := range coeffs {
+		// g is the negative partial derivative of the unpenalized cost function.
+		var g float64
+		for i := range x {
+			g += (y[i] - h(dot(x[i], coeffs))) * x[i][j]
+		}
+		switch {
+		case j == 0:
+			if !regressiontest.AreFloatEqual(g, 0, 4) {
+				t.Errorf("want zero derivative for the intercept, got %v", g)
+			}
+		case coeffs[j] == 0:
+			if math.Abs(g) > p.L1+1e-4 {
+				t.Errorf("want derivative within [-%v, %v] for zero coefficient %d, got %v", p.L1, p.L1, j, g)
+			}
+		default:
+			want := p.L1*math.Copysign(1, coeffs[j]) + p.L2*coeffs[j]
+			if !regressiontest.AreFloatEqual(g, want, 4) {
+				t.Errorf("want derivative %v for coefficient %d, got %v", want, j, g)
+			}
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		solve Solver
+		y     []float64
+		alpha float64
+	}{
+		{name: "least squares lasso", solve: LeastSquares, y: y, alpha: 1},
+		{name: "least squares elastic net", solve: LeastSquares, y: y, alpha: 0.5},
+		{name: "logistic lasso", solve: Logistic, y: c, alpha: 1},
+		{name: "logistic elastic net", solve: Logistic, y: c, alpha: 0.5},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lambdas, coeffs, err := Path(ctx, tt.solve, x, tt.y, tt.alpha, 5, 0.01)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if len(lambdas) != 5 || len(coeffs) != 5 {
+				t.Fatalf("want 5 lambdas and coefficients, got %d and %d", len(lambdas), len(coeffs))
+			}
+			if l := MaxLambda(x, tt.y, tt.alpha); lambdas[0] != l {
+				t.Errorf("want the first lambda %v, got %v", l, lambdas[0])
+			}
+			if !regressiontest.AreFloatEqual(lambdas[4]/lambdas[0], 0.01, 9) {
+				t.Errorf("want lambdas ratio %v, got %v", 0.01, lambdas[4]/lambdas[0])
+			}
+			for k, l := range lambdas {
+				if k > 0 && l >= lambdas[k-1] {
+					t.Errorf("want decreasing lambdas, got %v after %v", l, lambdas[k-1])
+				}
+				p := Penalty{L1: tt.alpha * l, L2: (1 - tt.alpha) * l}
+				want, err := tt.solve(ctx, x, tt.y, nil, p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !regressiontest.AreFloatSlicesEqual(coeffs[k], want, 4) {
+					t.Errorf("want %v for lambda %v, got %v", want, l, coeffs[k])
+				}
+			}
+		})
+	}
+}
+
+func TestMaxLambda(t *testing.T) {
+	ctx := context.Background()
+	for _, alpha := range []float64{1, 0.5} {
+		l := MaxLambda(x, y, alpha)
+		got, err := LeastSquares(ctx, x, y, nil, Penalty{L1: alpha * l, L2: (1 - alpha) * l})
+		if err != nil {
+			t.Fatalf("want nil, got error %v", err)
+		}
+		if got[1] != 0 || got[2] != 0 {
+			t.Errorf("want zero coefficients for alpha %v, got %v", alpha, got)
+		}
+		l *= 0.99
+		got, err = LeastSquares(ctx, x, y, nil, Penalty{L1: alpha * l, L2: (1 - alpha) * l})
+		if err != nil {
+			t.Fatalf("want nil, got error %v", err)
+		}
+		if got[1] == 0 && got[2] == 0 {
+			t.Errorf("want non-zero coefficients below the maximum for alpha %v, got %v", alpha, got)
+		}
+	}
+}
+
+func TestDegreesOfFreedom(t *testing.T) {
+	w := make([]float64, len(x))
+	for i := range w {
+		w[i] = 1
+	}
+	tests := []struct {
+		name   string
+		coeffs []float64
+		l2     float64
+		want   float64
+	}{
+		{name: "no active coefficients", coeffs: []float64{10, 0, 0}, l2: 1, want: 0},
+		{name: "lasso", coeffs: []float64{10, -1, 0.5}, l2: 0, want: 2},
+		// The centered first feature's sum of squares equals 2362.5.
+		{name: "ridge single feature", coeffs: []float64{10, -1, 0}, l2: 2362.5, want: 0.5},
+		{name: "ridge weak penalty", coeffs: []float64{10, -1, 0.5}, l2: 1e-9, want: 2},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DegreesOfFreedom(ctx, x, w, tt.coeffs, tt.l2)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if !regressiontest.AreFloatEqual(got, tt.want, 6) {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
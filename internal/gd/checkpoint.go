@@ -0,0 +1,304 @@
+package gd
+
+import (
+	"math"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/options"
+)
+
+// A resumable stepper saves its state to checkpoints and restores it from them.
+// Steppers count their own iterations, so they're restored to the checkpoint's iteration.
+type resumable interface {
+	// save saves the state of the stepper to the checkpoint c.
+	save(c *options.Checkpoint)
+	// restore restores the state of the stepper from the checkpoint c.
+	// If the checkpoint doesn't match the stepper, regression.ErrInvalidCheckpoint is returned.
+	restore(c options.Checkpoint) error
+}
+
+// A statefulOptimizer is an optimizer keeping state between steps.
+type statefulOptimizer interface {
+	Optimizer
+	// moments returns a copy of the optimizer's state.
+	moments() [][]float64
+	// restore restores the optimizer's state returned by moments.
+	restore(m [][]float64) error
+}
+
+func (s *baseStepper) save(c *options.Checkpoint) {
+	c.Coefficients = copyVector(s.coeffs)
+	if so, ok := s.opt.(statefulOptimizer); ok {
+		c.Moments = so.moments()
+	}
+}
+
+// restore restores the coefficients, the iteration and the optimizer's state. A checkpoint without moments
+// leaves the optimizer's state untouched.
+func (s *baseStepper) restore(c options.Checkpoint) error {
+	if c.Iteration < 0 || !isFinite(c.Coefficients, len(s.coeffs)) {
+		return regression.ErrInvalidCheckpoint
+	}
+	if c.Moments != nil {
+		so, ok := s.opt.(statefulOptimizer)
+		if !ok {
+			return regression.ErrInvalidCheckpoint
+		}
+		if err := so.restore(c.Moments); err != nil {
+			return err
+		}
+	}
+	s.coeffs = copyVector(c.Coefficients)
+	s.t = c.Iteration
+	return nil
+}
+
+// restoreCursor restores the base stepper and the cursor i of a stochastic stepper from the checkpoint c.
+func (s *baseStepper) restoreCursor(c options.Checkpoint, i *int) error {
+	if c.Cursor < 0 || c.Cursor >= s.m {
+		return regression.ErrInvalidCheckpoint
+	}
+	if err := s.restore(c); err != nil {
+		return err
+	}
+	*i = c.Cursor
+	return nil
+}
+
+func (s *stochasticStepper) save(c *options.Checkpoint) {
+	s.baseStepper.save(c)
+	c.Cursor = s.i
+}
+
+func (s *stochasticStepper) restore(c options.Checkpoint) error {
+	return s.restoreCursor(c, &s.i)
+}
+
+func (s *stochasticGradientStepper) save(c *options.Checkpoint) {
+	s.baseStepper.save(c)
+	c.Cursor = s.i
+}
+
+func (s *stochasticGradientStepper) restore(c options.Checkpoint) error {
+	return s.restoreCursor(c, &s.i)
+}
+
+// save saves coefficients brought up to date, with lazy updates.
+func (s *sparseBaseStepper) save(c *options.Checkpoint) {
+	s.baseStepper.save(c)
+	if s.lazy != nil {
+		c.Coefficients = s.CurrentCoefficients()
+	}
+}
+
+// restore restores the base stepper and starts lazy updates anew from the restored coefficients.
+func (s *sparseBaseStepper) restore(c options.Checkpoint) error {
+	if err := s.baseStepper.restore(c); err != nil {
+		return err
+	}
+	if s.lazy != nil {
+		s.lazy.reset(s.coeffs)
+	}
+	return nil
+}
+
+func (s *sparseStochasticStepper) save(c *options.Checkpoint) {
+	s.sparseBaseStepper.save(c)
+	c.Cursor = s.i
+}
+
+func (s *sparseStochasticStepper) restore(c options.Checkpoint) error {
+	if err := s.restoreCursor(c, &s.i); err != nil {
+		return err
+	}
+	if s.lazy != nil {
+		s.lazy.reset(s.coeffs)
+	}
+	return nil
+}
+
+func (s *miniBatchStepper) restore(c options.Checkpoint) error {
+	if err := s.baseStepper.restore(c); err != nil {
+		return err
+	}
+	s.skip(c.Iteration)
+	return nil
+}
+
+func (s *miniBatchGradientStepper) restore(c options.Checkpoint) error {
+	if err := s.baseStepper.restore(c); err != nil {
+		return err
+	}
+	s.skip(c.Iteration)
+	return nil
+}
+
+func (s *sparseMiniBatchStepper) restore(c options.Checkpoint) error {
+	if err := s.sparseBaseStepper.restore(c); err != nil {
+		return err
+	}
+	s.skip(c.Iteration)
+	return nil
+}
+
+// skip skips the given number of epochs, so that the next mini-batch is the first one of the following epoch.
+// Checkpoints are taken between epochs, so the random source is restored by drawing the skipped permutations.
+func (b *batcher) skip(epochs int) {
+	for k := 0; k < epochs; k++ {
+		b.perm = b.rnd.Perm(len(b.perm))
+	}
+	b.pos = len(b.perm)
+}
+
+// save saves the coefficients and the search direction history followed by the last step length
+// and directional derivative.
+func (s *lineSearchStepper) save(c *options.Checkpoint) {
+	c.Coefficients = copyVector(s.coeffs)
+	c.Moments = append(s.dir.state(), []float64{s.a, s.da})
+}
+
+// restore restores the coefficients and the search direction history. The cost function value and gradient
+// at the restored coefficients are recomputed by the next step.
+func (s *lineSearchStepper) restore(c options.Checkpoint) error {
+	if c.Iteration < 0 || !isFinite(c.Coefficients, len(s.coeffs)) {
+		return regression.ErrInvalidCheckpoint
+	}
+	if c.Moments != nil {
+		k := len(c.Moments) - 1
+		if k < 0 || !isFinite(c.Moments[k], 2) {
+			return regression.ErrInvalidCheckpoint
+		}
+		if err := s.dir.restore(c.Moments[:k], len(s.coeffs)); err != nil {
+			return err
+		}
+		s.a, s.da = c.Moments[k][0], c.Moments[k][1]
+	}
+	s.coeffs = copyVector(c.Coefficients)
+	s.g = nil
+	return nil
+}
+
+// state returns a copy of the history as consecutive pairs of steps and gradient changes.
+func (l *lbfgs) state() [][]float64 {
+	m := make([][]float64, 0, 2*len(l.s))
+	for i := range l.s {
+		m = append(m, copyVector(l.s[i]), copyVector(l.y[i]))
+	}
+	return m
+}
+
+func (l *lbfgs) restore(m [][]float64, n int) error {
+	if len(m)%2 != 0 || len(m) > 2*l.history || !isFiniteMatrix(m, n) {
+		return regression.ErrInvalidCheckpoint
+	}
+	l.reset()
+	for i := 0; i < len(m); i += 2 {
+		l.s = append(l.s, copyVector(m[i]))
+		l.y = append(l.y, copyVector(m[i+1]))
+	}
+	return nil
+}
+
+// state returns a copy of the previous direction and gradient change, if any.
+func (c *conjugateGradient) state() [][]float64 {
+	if c.d == nil {
+		return [][]float64{}
+	}
+	return [][]float64{copyVector(c.d), copyVector(c.y)}
+}
+
+func (c *conjugateGradient) restore(m [][]float64, n int) error {
+	if (len(m) != 0 && len(m) != 2) || !isFiniteMatrix(m, n) {
+		return regression.ErrInvalidCheckpoint
+	}
+	c.reset()
+	if len(m) == 2 {
+		c.d, c.y = copyVector(m[0]), copyVector(m[1])
+	}
+	return nil
+}
+
+func (o *momentum) moments() [][]float64 {
+	return [][]float64{copyVector(o.v)}
+}
+
+func (o *momentum) restore(m [][]float64) error {
+	return restoreMoments(m, o.v)
+}
+
+func (o *adaGrad) moments() [][]float64 {
+	return [][]float64{copyVector(o.s)}
+}
+
+func (o *adaGrad) restore(m [][]float64) error {
+	return restoreMoments(m, o.s)
+}
+
+func (o *rmsProp) moments() [][]float64 {
+	return [][]float64{copyVector(o.s)}
+}
+
+func (o *rmsProp) restore(m [][]float64) error {
+	return restoreMoments(m, o.s)
+}
+
+// moments returns the moving averages followed by the number of updates, needed by the bias correction.
+func (o *adam) moments() [][]float64 {
+	return [][]float64{copyVector(o.m), copyVector(o.v), {float64(o.t)}}
+}
+
+func (o *adam) restore(m [][]float64) error {
+	if len(m) != 3 || !isFinite(m[2], 1) || m[2][0] < 0 || m[2][0] != math.Trunc(m[2][0]) {
+		return regression.ErrInvalidCheckpoint
+	}
+	if err := restoreMoments(m[:2], o.m, o.v); err != nil {
+		return err
+	}
+	o.t = int(m[2][0])
+	return nil
+}
+
+// restoreMoments copies the saved moments m to the optimizer's state vectors dst.
+func restoreMoments(m [][]float64, dst ...[]float64) error {
+	if len(m) != len(dst) {
+		return regression.ErrInvalidCheckpoint
+	}
+	for i := range dst {
+		if !isFinite(m[i], len(dst[i])) {
+			return regression.ErrInvalidCheckpoint
+		}
+	}
+	for i := range dst {
+		copy(dst[i], m[i])
+	}
+	return nil
+}
+
+// isFiniteMatrix checks if every vector of m has the length n and consists of finite numbers.
+func isFiniteMatrix(m [][]float64, n int) bool {
+	for _, v := range m {
+		if !isFinite(v, n) {
+			return false
+		}
+	}
+	return true
+}
+
+// isFinite checks if the vector v has the length n and consists of finite numbers.
+func isFinite(v []float64, n int) bool {
+	if len(v) != n {
+		return false
+	}
+	for _, e := range v {
+		if math.IsNaN(e) || math.IsInf(e, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func copyVector(v []float64) []float64 {
+	c := make([]float64, len(v))
+	copy(c, v)
+	return c
+}
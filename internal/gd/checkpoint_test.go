@@ -0,0 +1,223 @@
+package gd
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
+	"github.com/erni27/regression/internal/ts"
+	"github.com/erni27/regression/options"
+)
+
+func TestRun_Resume(t *testing.T) {
+	x := [][]float64{
+		{1, 2, 1},
+		{1, 3, 0},
+		{1, 5, 2},
+		{1, 1, 4},
+		{1, 4, 3},
+	}
+	y := []float64{4, 5.5, 9, 6, 8.5}
+	variants := []struct {
+		name string
+		opt  options.Options
+	}{
+		{name: "batch", opt: options.WithIterativeConvergence(0.01, options.Batch, 12).WithStepDecay(0.5, 5)},
+		{name: "batch momentum", opt: options.WithIterativeConvergence(0.01, options.Batch, 12).WithMomentum(0.9)},
+		{name: "stochastic adagrad", opt: options.WithIterativeConvergence(0.1, options.Stochastic, 12).WithAdaGrad(1e-8)},
+		{name: "mini-batch adam", opt: options.WithIterativeConvergence(0.1, options.Batch, 12).WithMiniBatch(2, 7).WithAdam(0.9, 0.999, 1e-8)},
+		{name: "mini-batch rmsprop", opt: options.WithIterativeConvergence(0.01, options.Batch, 12).WithMiniBatch(3, 7).WithRMSProp(0.9, 1e-8)},
+		{name: "l-bfgs", opt: options.WithIterativeConvergence(0, options.LBFGS, 6).WithLBFGS(2)},
+		{name: "conjugate gradient", opt: options.WithIterativeConvergence(0, options.ConjugateGradient, 6)},
+	}
+	gds := []struct {
+		name string
+		gd   GradientDescent
+	}{
+		{name: "hyphothesis", gd: gd},
+		{name: "gradient", gd: NewWithGradient(gradStub, costStub, 3)},
+	}
+	ctx := context.Background()
+	for _, v := range variants {
+		for _, g := range gds {
+			t.Run(v.name+" "+g.name, func(t *testing.T) {
+				want, err := g.gd.Run(ctx, v.opt, x, y)
+				if err != nil {
+					t.Fatalf("want nil, got error %v", err)
+				}
+				var c options.Checkpoint
+				o := v.opt.WithCheckpointer(options.CheckpointerFunc(func(cp options.Checkpoint) error {
+					if cp.Iteration == 3 {
+						c = cp
+						return context.Canceled
+					}
+					return nil
+				}), 0)
+				if _, err := g.gd.Run(ctx, o, x, y); err != context.Canceled {
+					t.Fatalf("want %v, got %v", context.Canceled, err)
+				}
+				got, err := g.gd.Run(ctx, v.opt.WithResume(c), x, y)
+				if err != nil {
+					t.Fatalf("want nil, got error %v", err)
+				}
+				if !areIdentical(got.Coefficients, want.Coefficients) {
+					t.Errorf("got %v, want %v", got.Coefficients, want.Coefficients)
+				}
+				if got.Report.Iterations != want.Report.Iterations {
+					t.Errorf("got %d iterations, want %d", got.Report.Iterations, want.Report.Iterations)
+				}
+			})
+		}
+	}
+}
+
+func TestRun_ResumeEarlyStopping(t *testing.T) {
+	x := [][]float64{{1, 1}, {1, 2}, {1, 3}, {1, 4}}
+	y := []float64{1.2, 1.9, 3.1, 4.2}
+	vx := [][]float64{{1, 1.5}, {1, 3.5}}
+	vy := []float64{1.4, 3.8}
+	o := options.WithIterativeConvergence(0.1, options.Batch, 200).WithEarlyStopping(options.WithValidationSplit(0.5, 1, 3))
+	ctx := context.Background()
+	want, err := gd.RunWithValidation(ctx, o, x, y, vx, vy)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	var c options.Checkpoint
+	co := o.WithCheckpointer(options.CheckpointerFunc(func(cp options.Checkpoint) error {
+		c = cp
+		if cp.Iteration == want.Report.Iterations-1 {
+			return context.Canceled
+		}
+		return nil
+	}), 0)
+	if _, err := gd.RunWithValidation(ctx, co, x, y, vx, vy); err != context.Canceled {
+		t.Fatalf("want %v, got %v", context.Canceled, err)
+	}
+	if c.BestCoefficients == nil || c.Stale == 0 {
+		t.Fatalf("want the best coefficients with stale evaluations, got %+v", c)
+	}
+	got, err := gd.RunWithValidation(ctx, o.WithResume(c), x, y, vx, vy)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if !areIdentical(got.Coefficients, want.Coefficients) {
+		t.Errorf("got %v, want %v", got.Coefficients, want.Coefficients)
+	}
+	if got.Report.StopReason != want.Report.StopReason || got.Report.BestIteration != want.Report.BestIteration {
+		t.Errorf("got stop reason %v at best iteration %d, want %v at %d",
+			got.Report.StopReason, got.Report.BestIteration, want.Report.StopReason, want.Report.BestIteration)
+	}
+}
+
+func TestSparseRun_Resume(t *testing.T) {
+	s := regressiontest.ToSparse(regression.TrainingSet{X: [][]float64{{0, 2}, {3, 0}, {1, 1}, {0, 4}}, Y: []float64{5, 4, 3, 9}})
+	g := NewSparse(ts.Dot, sparseCostStub)
+	o := options.WithIterativeConvergence(0.05, options.Stochastic, 10).WithNesterov(0.5)
+	ctx := context.Background()
+	want, err := g.Run(ctx, o, s.X, s.Y)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	var c options.Checkpoint
+	co := o.WithCheckpointer(options.CheckpointerFunc(func(cp options.Checkpoint) error { c = cp; return nil }), 7)
+	if _, err := g.Run(ctx, co, s.X, s.Y); err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if c.Iteration != 7 || c.Cursor != 3 {
+		t.Fatalf("want a checkpoint after iteration 7 with cursor 3, got iteration %d with cursor %d", c.Iteration, c.Cursor)
+	}
+	got, err := g.Run(ctx, o.WithResume(c), s.X, s.Y)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if !areIdentical(got.Coefficients, want.Coefficients) {
+		t.Errorf("got %v, want %v", got.Coefficients, want.Coefficients)
+	}
+}
+
+func TestSparseRun_ResumeLazy(t *testing.T) {
+	s := regressiontest.ToSparse(regression.TrainingSet{X: [][]float64{{0, 2}, {3, 0}, {1, 1}, {0, 4}}, Y: []float64{5, 4, 3, 9}})
+	g := NewSparse(ts.Dot, sparseCostStub)
+	o := options.WithIterativeConvergence(0.05, options.Stochastic, 10).WithRegularization(1)
+	ctx := context.Background()
+	want, err := g.Run(ctx, o, s.X, s.Y)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	var c options.Checkpoint
+	co := o.WithCheckpointer(options.CheckpointerFunc(func(cp options.Checkpoint) error { c = cp; return nil }), 7)
+	if _, err := g.Run(ctx, co, s.X, s.Y); err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	got, err := g.Run(ctx, o.WithResume(c), s.X, s.Y)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	// Shrinking by the regularization is deferred, so a resumed run applies it in a different order.
+	if !regressiontest.AreFloatSlicesEqual(got.Coefficients, want.Coefficients, 12) {
+		t.Errorf("got %v, want %v", got.Coefficients, want.Coefficients)
+	}
+}
+
+func TestRun_WarmStart(t *testing.T) {
+	x := [][]float64{{1, 2}, {3, 4}, {5, 6}}
+	y := []float64{3, 7, 11}
+	o := options.WithIterativeConvergence(0.01, options.Batch, 10)
+	ctx := context.Background()
+	cold, err := gd.Run(ctx, o, x, y)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	warm, err := gd.Run(ctx, o.WithWarmStart([]float64{1, 1.1}), x, y)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if warm.Report.Cost >= cold.Report.Cost {
+		t.Errorf("got warm start cost %v, want lower than %v", warm.Report.Cost, cold.Report.Cost)
+	}
+	if warm.Report.Iterations != 10 {
+		t.Errorf("got %d iterations, want %d", warm.Report.Iterations, 10)
+	}
+}
+
+func TestRun_InvalidCheckpoint(t *testing.T) {
+	x := [][]float64{{1, 2}, {3, 4}, {5, 6}}
+	y := []float64{3, 7, 11}
+	o := options.WithIterativeConvergence(0.01, options.Batch, 10)
+	noop := options.CheckpointerFunc(func(options.Checkpoint) error { return nil })
+	tests := []struct {
+		name string
+		o    options.Options
+	}{
+		{name: "too few coefficients", o: o.WithWarmStart([]float64{1})},
+		{name: "non-finite coefficient", o: o.WithWarmStart([]float64{1, math.NaN()})},
+		{name: "negative iteration", o: o.WithResume(options.Checkpoint{Iteration: -1, Coefficients: []float64{1, 1}})},
+		{name: "moments of a stateless optimizer", o: o.WithResume(options.Checkpoint{Coefficients: []float64{1, 1}, Moments: [][]float64{{0, 0}}})},
+		{name: "moments of another optimizer", o: o.WithAdam(0.9, 0.999, 1e-8).WithResume(options.Checkpoint{Coefficients: []float64{1, 1}, Moments: [][]float64{{0, 0}}})},
+		{name: "cursor out of range", o: options.WithIterativeConvergence(0.01, options.Stochastic, 10).WithResume(options.Checkpoint{Coefficients: []float64{1, 1}, Cursor: 3})},
+		{name: "l-bfgs history too long", o: options.WithIterativeConvergence(0, options.LBFGS, 10).WithLBFGS(1).WithResume(options.Checkpoint{Coefficients: []float64{1, 1}, Moments: [][]float64{{1, 0}, {1, 0}, {0, 1}, {0, 1}, {1, 0}}})},
+		{name: "negative cadence", o: o.WithCheckpointer(noop, -1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := gd.Run(context.Background(), tt.o, x, y); err != regression.ErrInvalidCheckpoint {
+				t.Fatalf("want %v, got %v", regression.ErrInvalidCheckpoint, err)
+			}
+		})
+	}
+}
+
+// areIdentical checks if two float slices are exactly equal.
+func areIdentical(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -2,34 +2,73 @@ package gd
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"time"
 
 	"github.com/erni27/regression"
 	"github.com/erni27/regression/options"
 )
 
+// A Result is the outcome of gradient descent.
+type Result struct {
+	// Coefficients are the trained coefficients.
+	Coefficients []float64
+	// Report describes the course of gradient descent.
+	Report regression.Report
+}
+
 // Converger is the interface that wraps the basic Converge method.
 type Converger interface {
-	Converge(context.Context, Stepper) ([]float64, error)
+	Converge(context.Context, Stepper) (Result, error)
 }
 
 // ConvergerFunc is an adapter to allow the use of plain functions as convergers.
-type ConvergerFunc func(context.Context, Stepper) ([]float64, error)
+type ConvergerFunc func(context.Context, Stepper) (Result, error)
 
-func (f ConvergerFunc) Converge(ctx context.Context, s Stepper) ([]float64, error) {
+func (f ConvergerFunc) Converge(ctx context.Context, s Stepper) (Result, error) {
 	return f(ctx, s)
 }
 
-// NewConverger returns a new converger. If unsupported ConvergenceType is passed, an error is returned.
-func NewConverger(ct options.ConvergenceType, ci float64, c CostFunc) (Converger, error) {
+// NewConverger returns a new converger for the convergence type chosen in options. The cost function c is used
+// to check the automatic convergence and to report the final cost. If unsupported ConvergenceType is passed
+// or the observer's or checkpointer's cadence is negative, an error is returned.
+//
+// A converger resumes a stepper from the checkpoint in options and passes checkpoints to the checkpointer.
+// Both need a stepper created by this package, otherwise regression.ErrInvalidCheckpoint is returned.
+//
+// Early stopping options are ignored, since the converger has no validation set.
+func NewConverger(o options.Options, c CostFunc) (Converger, error) {
+	return newConverger(o, c, nil)
+}
+
+// newConverger returns a new converger which stops early according to the validator v, unless it's nil.
+func newConverger(o options.Options, c CostFunc, v *validator) (Converger, error) {
+	if o.ObserveEvery < 0 {
+		return nil, regression.ErrInvalidObserver
+	}
+	if o.CheckpointEvery < 0 {
+		return nil, regression.ErrInvalidCheckpoint
+	}
 	var cf ConvergerFunc
-	switch ct {
+	switch o.ConvergenceType {
 	case options.Iterative:
-		cf = func(ctx context.Context, s Stepper) ([]float64, error) {
-			return convergeAfter(ctx, s, int(ci))
+		cf = func(ctx context.Context, s Stepper) (Result, error) {
+			t, err := newTracker(o, s, c, v)
+			if err != nil {
+				return Result{}, err
+			}
+			t.class, _ = ctx.Value(classKey{}).(*class)
+			return convergeAfter(ctx, t, int(o.ConvergenceIndicator))
 		}
 	case options.Automatic:
-		cf = func(ctx context.Context, s Stepper) ([]float64, error) {
-			return convergeAutomatically(ctx, s, c, ci)
+		cf = func(ctx context.Context, s Stepper) (Result, error) {
+			t, err := newTracker(o, s, c, v)
+			if err != nil {
+				return Result{}, err
+			}
+			t.class, _ = ctx.Value(classKey{}).(*class)
+			return convergeAutomatically(ctx, t, o.ConvergenceIndicator)
 		}
 	default:
 		return nil, regression.ErrUnsupportedConvergenceType
@@ -38,48 +77,339 @@ func NewConverger(ct options.ConvergenceType, ci float64, c CostFunc) (Converger
 }
 
 // convergeAfter runs gradient descent with iterative convergence.
-// It converges after i iterations.
-func convergeAfter(ctx context.Context, s Stepper, i int) ([]float64, error) {
-	for k := 0; k < i; k++ {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-			err := s.TakeStep()
-			if err != nil {
-				return nil, err
+// It converges after i iterations, including the ones taken before a checkpoint it's resumed from.
+// An iteration of an EpochStepper is a whole epoch.
+func convergeAfter(ctx context.Context, t *tracker, i int) (Result, error) {
+	reason := regression.IterationLimit
+	for k := t.r.Iterations; k < i; k++ {
+		if err := iterate(ctx, t.s); err == errStationary {
+			reason = regression.Stationary
+			break
+		} else if err != nil {
+			return Result{}, err
+		}
+		if err := t.iterated(t.current); err != nil {
+			var ok bool
+			if reason, ok = stopReason(err); !ok {
+				return Result{}, err
 			}
+			break
 		}
 	}
-	return s.CurrentCoefficients(), nil
+	cost, err := t.current()
+	if err != nil {
+		return Result{}, err
+	}
+	return t.stop(reason, cost)
 }
 
 // convergeAutomatically runs gradient descent with automatic convergence.
 // It converges if cost function decreases by lower value than t threshold.
-func convergeAutomatically(ctx context.Context, s Stepper, c CostFunc, t float64) ([]float64, error) {
+// The cost function of an EpochStepper is compared between epochs.
+func convergeAutomatically(ctx context.Context, t *tracker, th float64) (Result, error) {
 	var coeffs []float64
 	for {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-			coeffs = s.CurrentCoefficients()
-			err := s.TakeStep()
+		coeffs = t.s.CurrentCoefficients()
+		if err := iterate(ctx, t.s); err == errStationary {
+			cost, err := t.current()
 			if err != nil {
-				return nil, err
+				return Result{}, err
 			}
-			// Check if cost function decreases by lower value than established threshold.
-			oc, err := c(s.X(), s.Y(), coeffs)
-			if err != nil {
-				return nil, err
+			return t.stop(regression.Stationary, cost)
+		} else if err != nil {
+			return Result{}, err
+		}
+		// Check if cost function decreases by lower value than established threshold.
+		oc, err := t.cost(coeffs)
+		if err != nil {
+			return Result{}, err
+		}
+		nc, err := t.cost(t.s.CurrentCoefficients())
+		if err != nil {
+			return Result{}, err
+		}
+		if err := t.iterated(func() (float64, error) { return nc, nil }); err != nil {
+			reason, ok := stopReason(err)
+			if !ok {
+				return Result{}, err
 			}
-			nc, err := c(s.X(), s.Y(), s.CurrentCoefficients())
-			if err != nil {
+			return t.stop(reason, nc)
+		}
+		if r := 1 - nc/oc; r > 0 && r < th {
+			return t.stop(regression.Converged, nc)
+		}
+	}
+}
+
+// errEarlyStop is returned by a tracker if the validation cost hasn't improved for the patience of early stopping.
+var errEarlyStop = errors.New("early stop")
+
+// stopReason returns the stop reason corresponding to an error returned by a tracker.
+// It returns false if the error aborts gradient descent.
+func stopReason(err error) (regression.StopReason, bool) {
+	switch {
+	case errors.Is(err, regression.ErrStopTraining):
+		return regression.Stopped, true
+	case err == errEarlyStop:
+		return regression.EarlyStopped, true
+	default:
+		return 0, false
+	}
+}
+
+// iterate takes a single step, or steps until the end of an epoch if s is an EpochStepper.
+func iterate(ctx context.Context, s Stepper) error {
+	es, ok := s.(EpochStepper)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.TakeStep(ctx); err != nil {
+			return err
+		}
+		if !ok || es.EpochDone() {
+			return nil
+		}
+	}
+}
+
+// A tracker records the course of gradient descent driven by a stepper, notifies the observer, stops early
+// according to the validator and passes checkpoints to the checkpointer.
+type tracker struct {
+	s        Stepper
+	c        CostFunc
+	curve    bool
+	observer options.Observer
+	every    int
+	v        *validator
+	// rs is the stepper s if it's resumable, nil otherwise.
+	rs              resumable
+	checkpointer    options.Checkpointer
+	checkpointEvery int
+	// class is the binary model of a multiclass one trained by the tracked gradient descent, nil if there's none.
+	class *class
+	start time.Time
+	r     regression.Report
+}
+
+// A class identifies a binary model of a multiclass one, trained in parallel with binary models of other classes.
+// Observers are shared by all of them, so their calls are serialized by mu.
+type class struct {
+	label int
+	mu    *sync.Mutex
+}
+
+// classKey is the context key of a class.
+type classKey struct{}
+
+// WithClass returns a copy of ctx under which gradient descent trains the binary model of the class label.
+// It passes the label to the observer in options.Progress and calls the observer while holding mu,
+// which should be shared by all binary models of a multiclass one.
+func WithClass(ctx context.Context, label int, mu *sync.Mutex) context.Context {
+	return context.WithValue(ctx, classKey{}, &class{label: label, mu: mu})
+}
+
+// newTracker returns a new tracker of the stepper s with the cost function c and the validator v, which may be nil.
+// The learning curve, the observer and the checkpointer are taken from options. If options hold a checkpoint,
+// the stepper and the validator are restored from it.
+func newTracker(o options.Options, s Stepper, c CostFunc, v *validator) (*tracker, error) {
+	every := o.ObserveEvery
+	if every == 0 {
+		every = 1
+	}
+	checkpointEvery := o.CheckpointEvery
+	if checkpointEvery == 0 {
+		checkpointEvery = 1
+	}
+	rs, _ := s.(resumable)
+	if rs == nil && (o.Resume != nil || o.Checkpointer != nil) {
+		return nil, regression.ErrInvalidCheckpoint
+	}
+	t := &tracker{
+		s: s, c: c, curve: o.LearningCurve, observer: o.Observer, every: every, v: v,
+		rs: rs, checkpointer: o.Checkpointer, checkpointEvery: checkpointEvery, start: time.Now(),
+	}
+	if o.Resume != nil {
+		if err := rs.restore(*o.Resume); err != nil {
+			return nil, err
+		}
+		if v != nil {
+			if err := v.restore(*o.Resume, len(s.CurrentCoefficients())); err != nil {
 				return nil, err
 			}
-			if r := 1 - nc/oc; r > 0 && r < t {
-				return s.CurrentCoefficients(), nil
+		}
+		t.r.Iterations = o.Resume.Iteration
+	}
+	return t, nil
+}
+
+// cost calculates the cost function value for the given coefficients.
+func (t *tracker) cost(coeffs []float64) (float64, error) {
+	return t.c(t.s.X(), t.s.Y(), coeffs)
+}
+
+// current calculates the cost function value for the current coefficients.
+func (t *tracker) current() (float64, error) {
+	return t.cost(t.s.CurrentCoefficients())
+}
+
+// iterated records a completed iteration, notifies the observer, evaluates the coefficients on the validation set
+// and saves a checkpoint if they're due. cost returns the cost function value after the iteration, it's called only
+// if the learning curve is recorded or the observer is due.
+// It returns the observer's or checkpointer's error or errEarlyStop if gradient descent should stop early.
+func (t *tracker) iterated(cost func() (float64, error)) error {
+	t.r.Iterations++
+	if err := t.observe(cost); err != nil {
+		return err
+	}
+	if err := t.validate(); err != nil {
+		return err
+	}
+	return t.checkpoint()
+}
+
+// validate evaluates the current coefficients on the validation set if they're due.
+func (t *tracker) validate() error {
+	if t.v == nil || t.r.Iterations%t.v.every != 0 {
+		return nil
+	}
+	stale, err := t.v.evaluate(t.r.Iterations, t.s.CurrentCoefficients())
+	if err != nil {
+		return err
+	}
+	if stale {
+		return errEarlyStop
+	}
+	return nil
+}
+
+// checkpoint passes a checkpoint of the stepper and the validator to the checkpointer if it's due.
+func (t *tracker) checkpoint() error {
+	if t.checkpointer == nil || t.r.Iterations%t.checkpointEvery != 0 {
+		return nil
+	}
+	c := options.Checkpoint{Iteration: t.r.Iterations}
+	t.rs.save(&c)
+	if t.v != nil {
+		t.v.save(&c)
+	}
+	return t.checkpointer.Checkpoint(c)
+}
+
+// observe records the learning curve and notifies the observer.
+func (t *tracker) observe(cost func() (float64, error)) error {
+	observe := t.observer != nil && t.r.Iterations%t.every == 0
+	if !t.curve && !observe {
+		return nil
+	}
+	c, err := cost()
+	if err != nil {
+		return err
+	}
+	if t.curve {
+		t.r.LearningCurve = append(t.r.LearningCurve, regression.Iteration{Cost: c, GradientNorm: t.s.GradientNorm()})
+	}
+	if !observe {
+		return nil
+	}
+	coeffs := make([]float64, len(t.s.CurrentCoefficients()))
+	copy(coeffs, t.s.CurrentCoefficients())
+	p := options.Progress{Iteration: t.r.Iterations, Coefficients: coeffs, Cost: c, LearningRate: t.s.LearningRate()}
+	if t.class != nil {
+		p.Class = t.class.label
+		t.class.mu.Lock()
+		defer t.class.mu.Unlock()
+	}
+	return t.observer.Observe(p)
+}
+
+// stop completes the report with the stop reason and the cost function value of the current coefficients.
+// With early stopping, the best coefficients are returned instead of the current ones.
+func (t *tracker) stop(reason regression.StopReason, cost float64) (Result, error) {
+	coeffs := t.s.CurrentCoefficients()
+	if t.v != nil {
+		// The last coefficients may be the best ones, even if they weren't due to be evaluated.
+		if t.v.last != t.r.Iterations {
+			if _, err := t.v.evaluate(t.r.Iterations, coeffs); err != nil {
+				return Result{}, err
+			}
+		}
+		if t.v.best != t.r.Iterations {
+			coeffs = t.v.coeffs
+			var err error
+			if cost, err = t.cost(coeffs); err != nil {
+				return Result{}, err
 			}
 		}
+		t.r.BestIteration = t.v.best
+		t.r.ValidationCost = t.v.cost
+	}
+	t.r.StopReason = reason
+	t.r.Cost = cost
+	t.r.Duration = time.Since(t.start)
+	return Result{Coefficients: coeffs, Report: t.r}, nil
+}
+
+// A validator evaluates coefficients on a validation set and keeps the best ones for early stopping.
+type validator struct {
+	c        CostFunc
+	x        [][]float64
+	y        []float64
+	every    int
+	patience int
+	// best is the iteration of the best coefficients coeffs with the validation cost cost, last is the iteration
+	// evaluated last and stale is the number of evaluations without improvement.
+	best   int
+	coeffs []float64
+	cost   float64
+	last   int
+	stale  int
+}
+
+// newValidator returns a new validator evaluating the cost function c on a validation set x, y according
+// to early stopping options.
+func newValidator(e options.EarlyStopping, c CostFunc, x [][]float64, y []float64) *validator {
+	every := e.Every
+	if every == 0 {
+		every = 1
+	}
+	return &validator{c: c, x: x, y: y, every: every, patience: e.Patience, best: -1, last: -1}
+}
+
+// evaluate evaluates coefficients after the given iteration. It reports whether the validation cost hasn't improved
+// for the patience of early stopping.
+func (v *validator) evaluate(iteration int, coeffs []float64) (bool, error) {
+	c, err := v.c(v.x, v.y, coeffs)
+	if err != nil {
+		return false, err
+	}
+	v.last = iteration
+	if v.best < 0 || c < v.cost {
+		v.best, v.cost, v.stale = iteration, c, 0
+		v.coeffs = make([]float64, len(coeffs))
+		copy(v.coeffs, coeffs)
+		return false, nil
+	}
+	v.stale++
+	return v.stale >= v.patience, nil
+}
+
+// save saves the best coefficients seen so far to the checkpoint c.
+func (v *validator) save(c *options.Checkpoint) {
+	if v.best < 0 {
+		return
+	}
+	c.BestIteration, c.BestCoefficients, c.ValidationCost, c.Stale = v.best, copyVector(v.coeffs), v.cost, v.stale
+}
+
+// restore restores the best coefficients of n coefficients seen before the checkpoint c was taken.
+func (v *validator) restore(c options.Checkpoint, n int) error {
+	if c.BestCoefficients == nil {
+		return nil
+	}
+	if c.BestIteration < 0 || c.BestIteration > c.Iteration || c.Stale < 0 || !isFinite(c.BestCoefficients, n) {
+		return regression.ErrInvalidCheckpoint
 	}
+	v.best, v.coeffs, v.cost, v.stale = c.BestIteration, copyVector(c.BestCoefficients), c.ValidationCost, c.Stale
+	return nil
 }
@@ -3,6 +3,7 @@ package gd
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
 	"testing"
 
@@ -15,7 +16,7 @@ type stepperMock struct {
 	takeStep func() error
 }
 
-func (s stepperMock) TakeStep() error {
+func (s stepperMock) TakeStep(ctx context.Context) error {
 	return s.takeStep()
 }
 
@@ -32,7 +33,7 @@ func TestNewConverger(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			c, err := NewConverger(tt.ct, 0, nil)
+			c, err := NewConverger(options.Options{ConvergenceType: tt.ct}, nil)
 			if (err != nil) != tt.wantErr {
 				if err != tt.err {
 					t.Fatalf("want error %v, got %v", tt.err, err)
@@ -61,7 +62,7 @@ func TestConverge_Iterative(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			var counter int
 			s := stepperMock{takeStep: func() error { counter++; return nil }}
-			c, err := NewConverger(options.Iterative, float64(tt.i), nil)
+			c, err := NewConverger(options.WithIterativeConvergence(0, 0, uint(tt.i)), func(x [][]float64, y []float64, coeffs []float64) (float64, error) { return 0, nil })
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -96,7 +97,7 @@ func TestConverge_Automatic(t *testing.T) {
 			var counter int
 			s := stepperMock{takeStep: func() error { counter++; return nil }}
 			cost := 10e3
-			c, err := NewConverger(options.Automatic, tt.t, func(x [][]float64, y []float64, coeffs []float64) (float64, error) {
+			c, err := NewConverger(options.WithAutomaticConvergence(0, 0, tt.t), func(x [][]float64, y []float64, coeffs []float64) (float64, error) {
 				cost -= cost * math.Pow(10, -float64(counter))
 				return cost, nil
 			})
@@ -127,7 +128,7 @@ func TestConverge_StepperErr(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := stepperMock{takeStep: func() error { return tt.err }, baseStepper: baseStepper{coeffs: make([]float64, 5)}}
-			c, err := NewConverger(tt.ct, 100, func(x [][]float64, y []float64, coeffs []float64) (float64, error) { return 0, nil })
+			c, err := NewConverger(options.Options{ConvergenceType: tt.ct, ConvergenceIndicator: 100}, func(x [][]float64, y []float64, coeffs []float64) (float64, error) { return 0, nil })
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -138,3 +139,292 @@ func TestConverge_StepperErr(t *testing.T) {
 		})
 	}
 }
+
+type epochStepperMock struct {
+	stepperMock
+	epochDone func() bool
+}
+
+func (s epochStepperMock) EpochDone() bool {
+	return s.epochDone()
+}
+
+func TestConverge_Epochs(t *testing.T) {
+	const steps = 3
+	tests := []struct {
+		name string
+		ct   options.ConvergenceType
+		ci   float64
+		want int
+	}{
+		{name: "iterative i=10", ct: options.Iterative, ci: 10, want: 10 * steps},
+		{name: "automatic t=0.01", ct: options.Automatic, ci: 0.01, want: 2 * steps},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var counter int
+			s := epochStepperMock{
+				stepperMock: stepperMock{takeStep: func() error { counter++; return nil }},
+				epochDone:   func() bool { return counter%steps == 0 },
+			}
+			// The cost function is evaluated twice per epoch, it decreases by 50% and then by 0.5%.
+			costs := []float64{100, 50, 50, 49.75}
+			c, err := NewConverger(options.Options{ConvergenceType: tt.ct, ConvergenceIndicator: tt.ci}, func(x [][]float64, y []float64, coeffs []float64) (float64, error) {
+				if counter%steps != 0 {
+					return 0, fmt.Errorf("cost function evaluated after %d steps", counter)
+				}
+				cost := costs[0]
+				costs = costs[1:]
+				return cost, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.Converge(ctx, s); err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if counter != tt.want {
+				t.Fatalf("want convergence after %d steps, but got convergence after %d", tt.want, counter)
+			}
+		})
+	}
+}
+
+// countingStepper counts its steps in the only coefficient. It reaches a stationary point at the given step.
+type countingStepper struct {
+	baseStepper
+	stationary int
+}
+
+func (s *countingStepper) TakeStep(ctx context.Context) error {
+	k := s.coeffs[0] + 1
+	if int(k) == s.stationary {
+		return errStationary
+	}
+	s.coeffs = []float64{k}
+	s.gnorm = 1 / k
+	s.rate = k / 10
+	return nil
+}
+
+func TestConverge_Report(t *testing.T) {
+	tests := []struct {
+		name       string
+		o          options.Options
+		stationary int
+		iterations int
+		reason     regression.StopReason
+	}{
+		{name: "iterative", o: options.WithIterativeConvergence(0, 0, 10), iterations: 10, reason: regression.IterationLimit},
+		{name: "iterative stationary", o: options.WithIterativeConvergence(0, 0, 10), stationary: 4, iterations: 3, reason: regression.Stationary},
+		{name: "automatic", o: options.WithAutomaticConvergence(0, 0, 0.01), iterations: 2, reason: regression.Converged},
+		{name: "automatic stationary", o: options.WithAutomaticConvergence(0, 0, 1e-9), stationary: 4, iterations: 3, reason: regression.Stationary},
+	}
+	// The cost function halves in the first iteration and then decreases by 0.5%.
+	cost := func(k float64) float64 {
+		if k == 0 {
+			return 100
+		}
+		return 50 - 0.25*(k-1)
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		for _, curve := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s curve=%t", tt.name, curve), func(t *testing.T) {
+				s := &countingStepper{baseStepper: baseStepper{coeffs: []float64{0}}, stationary: tt.stationary}
+				o := tt.o
+				o.LearningCurve = curve
+				c, err := NewConverger(o, func(x [][]float64, y []float64, coeffs []float64) (float64, error) {
+					return cost(coeffs[0]), nil
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := c.Converge(ctx, s)
+				if err != nil {
+					t.Fatalf("want nil, got error %v", err)
+				}
+				r := got.Report
+				if r.Iterations != tt.iterations || r.StopReason != tt.reason {
+					t.Errorf("got %d iterations and stop reason %v, want %d and %v", r.Iterations, r.StopReason, tt.iterations, tt.reason)
+				}
+				if want := cost(float64(tt.iterations)); r.Cost != want {
+					t.Errorf("got cost %v, want %v", r.Cost, want)
+				}
+				if r.Duration <= 0 {
+					t.Errorf("got duration %v, want positive", r.Duration)
+				}
+				if !curve {
+					if r.LearningCurve != nil {
+						t.Errorf("got learning curve %v, want nil", r.LearningCurve)
+					}
+					return
+				}
+				if len(r.LearningCurve) != tt.iterations {
+					t.Fatalf("got learning curve of %d iterations, want %d", len(r.LearningCurve), tt.iterations)
+				}
+				for k, it := range r.LearningCurve {
+					if want := cost(float64(k + 1)); it.Cost != want || it.GradientNorm != 1/float64(k+1) {
+						t.Errorf("got cost %v and gradient norm %v after iteration %d, want %v and %v", it.Cost, it.GradientNorm, k+1, want, 1/float64(k+1))
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestConverge_Observer(t *testing.T) {
+	errObserver := errors.New("observer error")
+	tests := []struct {
+		name       string
+		o          options.Options
+		every      int
+		stopAt     int
+		stopErr    error
+		calls      []int
+		iterations int
+		reason     regression.StopReason
+		err        error
+	}{
+		{name: "iterative every iteration", o: options.WithIterativeConvergence(0, 0, 5), calls: []int{1, 2, 3, 4, 5}, iterations: 5, reason: regression.IterationLimit},
+		{name: "iterative every 3 iterations", o: options.WithIterativeConvergence(0, 0, 10), every: 3, calls: []int{3, 6, 9}, iterations: 10, reason: regression.IterationLimit},
+		{name: "iterative stop", o: options.WithIterativeConvergence(0, 0, 10), every: 2, stopAt: 4, stopErr: regression.ErrStopTraining, calls: []int{2, 4}, iterations: 4, reason: regression.Stopped},
+		{name: "automatic stop", o: options.WithAutomaticConvergence(0, 0, 1e-9), stopAt: 3, stopErr: regression.ErrStopTraining, calls: []int{1, 2, 3}, iterations: 3, reason: regression.Stopped},
+		{name: "wrapped stop", o: options.WithIterativeConvergence(0, 0, 10), stopAt: 2, stopErr: fmt.Errorf("cost too low: %w", regression.ErrStopTraining), calls: []int{1, 2}, iterations: 2, reason: regression.Stopped},
+		{name: "iterative error", o: options.WithIterativeConvergence(0, 0, 10), stopAt: 2, stopErr: errObserver, calls: []int{1, 2}, err: errObserver},
+		{name: "automatic error", o: options.WithAutomaticConvergence(0, 0, 1e-9), stopAt: 2, stopErr: errObserver, calls: []int{1, 2}, err: errObserver},
+	}
+	cost := func(k float64) float64 { return 100 - k }
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &countingStepper{baseStepper: baseStepper{coeffs: []float64{0}}}
+			var calls []int
+			observer := options.ObserverFunc(func(p options.Progress) error {
+				calls = append(calls, p.Iteration)
+				k := float64(p.Iteration)
+				if len(p.Coefficients) != 1 || p.Coefficients[0] != k || p.Cost != cost(k) || p.LearningRate != k/10 {
+					t.Errorf("got progress %+v after iteration %d", p, p.Iteration)
+				}
+				// The coefficients are a copy.
+				p.Coefficients[0] = -1
+				if p.Iteration == tt.stopAt {
+					return tt.stopErr
+				}
+				return nil
+			})
+			c, err := NewConverger(tt.o.WithObserver(observer, tt.every), func(x [][]float64, y []float64, coeffs []float64) (float64, error) {
+				return cost(coeffs[0]), nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := c.Converge(ctx, s)
+			if err != tt.err {
+				t.Fatalf("want %v, got %v", tt.err, err)
+			}
+			if fmt.Sprint(calls) != fmt.Sprint(tt.calls) {
+				t.Errorf("got observer calls after iterations %v, want %v", calls, tt.calls)
+			}
+			if err != nil {
+				return
+			}
+			if r := got.Report; r.Iterations != tt.iterations || r.StopReason != tt.reason || r.Cost != cost(float64(tt.iterations)) {
+				t.Errorf("got %d iterations, stop reason %v and cost %v, want %d, %v and %v", r.Iterations, r.StopReason, r.Cost, tt.iterations, tt.reason, cost(float64(tt.iterations)))
+			}
+			if got.Coefficients[0] != float64(tt.iterations) {
+				t.Errorf("got coefficients %v, want %v", got.Coefficients, []float64{float64(tt.iterations)})
+			}
+		})
+	}
+	if _, err := NewConverger(options.WithIterativeConvergence(0, 0, 10).WithObserver(nil, -1), nil); err != regression.ErrInvalidObserver {
+		t.Errorf("want %v, got %v", regression.ErrInvalidObserver, err)
+	}
+}
+
+func TestConverge_EarlyStopping(t *testing.T) {
+	tests := []struct {
+		name       string
+		o          options.Options
+		every      int
+		validation func(k float64) float64
+		coeffs     float64
+		iterations int
+		reason     regression.StopReason
+	}{
+		{
+			name:       "every iteration",
+			o:          options.WithIterativeConvergence(0, 0, 100),
+			validation: func(k float64) float64 { return math.Abs(k - 5) },
+			coeffs:     5,
+			iterations: 8,
+			reason:     regression.EarlyStopped,
+		},
+		{
+			name:       "every 2 iterations",
+			o:          options.WithIterativeConvergence(0, 0, 100),
+			every:      2,
+			validation: func(k float64) float64 { return math.Abs(k - 5) },
+			coeffs:     4,
+			iterations: 10,
+			reason:     regression.EarlyStopped,
+		},
+		{
+			name:       "automatic",
+			o:          options.WithAutomaticConvergence(0, 0, 1e-9),
+			validation: func(k float64) float64 { return math.Abs(k - 5) },
+			coeffs:     5,
+			iterations: 8,
+			reason:     regression.EarlyStopped,
+		},
+		{
+			name:       "iteration limit",
+			o:          options.WithIterativeConvergence(0, 0, 7),
+			validation: func(k float64) float64 { return math.Abs(k - 5) },
+			coeffs:     5,
+			iterations: 7,
+			reason:     regression.IterationLimit,
+		},
+		{
+			name:       "last coefficients not due",
+			o:          options.WithIterativeConvergence(0, 0, 5),
+			every:      3,
+			validation: func(k float64) float64 { return -k },
+			coeffs:     5,
+			iterations: 5,
+			reason:     regression.IterationLimit,
+		},
+	}
+	cost := func(k float64) float64 { return 100 - k }
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &countingStepper{baseStepper: baseStepper{coeffs: []float64{0}}}
+			e := options.EarlyStopping{Every: tt.every, Patience: 3}
+			v := newValidator(e, func(x [][]float64, y []float64, coeffs []float64) (float64, error) {
+				return tt.validation(coeffs[0]), nil
+			}, nil, nil)
+			c, err := newConverger(tt.o, func(x [][]float64, y []float64, coeffs []float64) (float64, error) {
+				return cost(coeffs[0]), nil
+			}, v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := c.Converge(ctx, s)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if got.Coefficients[0] != tt.coeffs {
+				t.Errorf("got coefficients %v, want %v", got.Coefficients, []float64{tt.coeffs})
+			}
+			r := got.Report
+			if r.Iterations != tt.iterations || r.StopReason != tt.reason {
+				t.Errorf("got %d iterations and stop reason %v, want %d and %v", r.Iterations, r.StopReason, tt.iterations, tt.reason)
+			}
+			if r.BestIteration != int(tt.coeffs) || r.ValidationCost != tt.validation(tt.coeffs) || r.Cost != cost(tt.coeffs) {
+				t.Errorf("got best iteration %d with validation cost %v and cost %v, want %d, %v and %v", r.BestIteration, r.ValidationCost, r.Cost, int(tt.coeffs), tt.validation(tt.coeffs), cost(tt.coeffs))
+			}
+		})
+	}
+}
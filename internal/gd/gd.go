@@ -4,6 +4,8 @@ package gd
 import (
 	"context"
 
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/matrix"
 	"github.com/erni27/regression/options"
 )
 
@@ -13,26 +15,157 @@ type Hyphothesis func(x []float64, coeffs []float64) (float64, error)
 // CostFunc is a function template for cost function used in gradient descent algorithm.
 type CostFunc func(x [][]float64, y []float64, coeffs []float64) (float64, error)
 
-// GradientDescent holds the hyphothesis and cost functions needed by gradient descent algorithm.
+// GradientFunc is a function template for a cost function gradient used in gradient descent algorithm.
+// It returns partial derivatives of a cost function with respect to every coefficient, summed over all passed training examples.
+type GradientFunc func(x [][]float64, y []float64, coeffs []float64) ([]float64, error)
+
+// GradientDescent holds the functions needed by gradient descent algorithm.
+//
+// A gradient descent is driven either by a hyphothesis function (the gradient is derived from it)
+// or by an explicit gradient function.
 type GradientDescent struct {
 	h Hyphothesis
 	c CostFunc
+	g GradientFunc
+	n int
 }
 
 // New creates new gradient descent.
 func New(h Hyphothesis, c CostFunc) GradientDescent {
-	return GradientDescent{h, c}
+	return GradientDescent{h: h, c: c}
+}
+
+// NewWithGradient creates new gradient descent driven by an explicit gradient function.
+// It's used by models whose coefficients don't form a single scalar hyphothesis, n is the number of coefficients.
+//
+// Coefficients must consist of consecutive blocks, one per output, each of them as long as a feature vector
+// and starting with the intercept.
+func NewWithGradient(g GradientFunc, c CostFunc, n int) GradientDescent {
+	return GradientDescent{c: c, g: g, n: n}
 }
 
-// Run runs the gradient descent algorithm.
-func (g GradientDescent) Run(ctx context.Context, o options.Options, x [][]float64, y []float64) ([]float64, error) {
-	gds, err := NewStepper(o.GradientDescentVariant, g.h, x, y, o.LearningRate)
+// Run runs the gradient descent algorithm. It returns the trained coefficients with the training report.
+// Early stopping needs a validation set, so it's run by RunWithValidation. If options enable it,
+// regression.ErrInvalidEarlyStopping is returned.
+func (g GradientDescent) Run(ctx context.Context, o options.Options, x [][]float64, y []float64) (Result, error) {
+	return g.RunWithValidation(ctx, o, x, y, nil, nil)
+}
+
+// RunWithValidation runs the gradient descent algorithm which stops early according to options, evaluating
+// the cost function on a validation set vx, vy (with the dummy feature). Without early stopping in options
+// the validation set is ignored.
+func (g GradientDescent) RunWithValidation(ctx context.Context, o options.Options, x [][]float64, y []float64, vx [][]float64, vy []float64) (Result, error) {
+	if !options.IsValidRegularization(o.Regularization) {
+		return Result{}, regression.ErrInvalidRegularization
+	}
+	var v *validator
+	if o.EarlyStopping != nil {
+		if !o.EarlyStopping.IsValid() || len(vx) == 0 {
+			return Result{}, regression.ErrInvalidEarlyStopping
+		}
+		v = newValidator(*o.EarlyStopping, g.c, vx, vy)
+	}
+	var gds Stepper
+	var err error
+	switch {
+	case o.GradientDescentVariant == options.LBFGS || o.GradientDescentVariant == options.ConjugateGradient:
+		var obj ObjectiveFunc
+		if obj, err = g.objective(o, x, y); err != nil {
+			return Result{}, err
+		}
+		gds, err = newLineSearchStepper(o, obj, x, y, g.size(x))
+	case g.g != nil:
+		gds, err = NewGradientStepper(o, g.g, x, y, g.n)
+	default:
+		gds, err = NewStepper(o, g.h, x, y)
+	}
 	if err != nil {
-		return nil, err
+		return Result{}, err
 	}
-	cv, err := NewConverger(o.ConvergenceType, o.ConvergenceIndicator, g.c)
+	cv, err := newConverger(o, Penalize(g.c, o.Regularization), v)
 	if err != nil {
-		return nil, err
+		return Result{}, err
 	}
 	return cv.Converge(ctx, gds)
 }
+
+// size returns the number of coefficients for a design matrix x.
+func (g GradientDescent) size(x [][]float64) int {
+	if g.g != nil {
+		return g.n
+	}
+	return len(x[0])
+}
+
+// objective returns the cost function with its gradient for a design matrix x and a target vector y,
+// regularized with the L2 regularization strength from options. The gradient is summed over chunks of training examples
+// in parallel.
+//
+// The gradient of a hyphothesis driven gradient descent equals 1/m*sum((h(x)-y)*Xj), which holds for cost functions
+// of linear and logistic regression. The gradient of the regularization term equals l2/m*Oj.
+func (g GradientDescent) objective(o options.Options, x [][]float64, y []float64) (ObjectiveFunc, error) {
+	l2 := o.Regularization
+	c := Penalize(g.c, l2)
+	m := float64(len(x))
+	r, err := newReducer(o, len(x), g.size(x))
+	if err != nil {
+		return nil, err
+	}
+	d, err := matrix.FromRows(x)
+	if err != nil {
+		return nil, regression.ErrInvalidFeatureVector
+	}
+	return func(ctx context.Context, coeffs []float64) (float64, []float64, error) {
+		f, err := c(x, y, coeffs)
+		if err != nil {
+			return 0, nil, err
+		}
+		if len(coeffs) != r.n {
+			return 0, nil, regression.ErrInvalidFeatureVector
+		}
+		var grad []float64
+		if g.g != nil {
+			grad, err = r.sum(ctx, explicitGradient(g.g, x, y, coeffs))
+		} else {
+			grad, err = r.sum(ctx, hyphothesisGradient(g.h, d, y, coeffs))
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		for j := range grad {
+			grad[j] /= m
+			if !isIntercept(j, x) {
+				grad[j] += l2 * coeffs[j] / m
+			}
+		}
+		return f, grad, nil
+	}, nil
+}
+
+// Penalize adds the L2 regularization term to the cost function c.
+//
+// The regularization term equals l2/(2m)*sum(Oj^2), where m is the number of training examples.
+// Intercepts are excluded from the sum.
+func Penalize(c CostFunc, l2 float64) CostFunc {
+	if l2 == 0 {
+		return c
+	}
+	return func(x [][]float64, y []float64, coeffs []float64) (float64, error) {
+		v, err := c(x, y, coeffs)
+		if err != nil {
+			return 0, err
+		}
+		var p float64
+		for j, coeff := range coeffs {
+			if !isIntercept(j, x) {
+				p += coeff * coeff
+			}
+		}
+		return v + l2*p/float64(2*len(x)), nil
+	}
+}
+
+// isIntercept checks if the j-th coefficient is an intercept, i.e. corresponds to the dummy feature of a design matrix x.
+func isIntercept(j int, x [][]float64) bool {
+	return j%len(x[0]) == 0
+}
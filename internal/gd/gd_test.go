@@ -36,6 +36,21 @@ func costStub(x [][]float64, y []float64, coeffs []float64) (float64, error) {
 	return c / float64((2 * m)), nil
 }
 
+// gradStub calculates the gradient of costStub scaled by the number of training examples.
+func gradStub(x [][]float64, y []float64, coeffs []float64) ([]float64, error) {
+	g := make([]float64, len(coeffs))
+	for i := 0; i < len(x); i++ {
+		hr, err := hyphoStub(x[i], coeffs)
+		if err != nil {
+			return nil, err
+		}
+		for j := range g {
+			g[j] += (hr - y[i]) * x[i][j]
+		}
+	}
+	return g, nil
+}
+
 var gd GradientDescent = New(hyphoStub, costStub)
 
 func TestRun(t *testing.T) {
@@ -69,19 +84,68 @@ func TestRun(t *testing.T) {
 			name: "stochastic automatic alpha=0.01 t=0.01",
 			opt:  options.WithAutomaticConvergence(0.01, options.Stochastic, 0.01),
 			want: []float64{0.867, 1.105},
+		}, {
+			name: "mini-batch iterative alpha=0.01 size=2 i=5000",
+			opt:  options.WithIterativeConvergence(0.01, options.Batch, 5000).WithMiniBatch(2, 1),
+			want: []float64{1, 1},
+		},
+		{
+			name: "mini-batch automatic alpha=0.01 size=2 t=0.01",
+			opt:  options.WithAutomaticConvergence(0.01, options.Batch, 0.01).WithMiniBatch(2, 1),
+			want: []float64{0.871, 1.102},
+		},
+		{
+			name: "batch iterative alpha=0.01 i=10 step decay",
+			opt:  options.WithIterativeConvergence(0.01, options.Batch, 10).WithStepDecay(0.5, 1),
+			want: []float64{0.844, 1.068},
+		},
+		// A constant learning rate keeps the stochastic variant oscillating, so it wouldn't converge with the threshold.
+		{
+			name: "stochastic automatic alpha=0.01 t=1e-6 inverse-time decay",
+			opt:  options.WithAutomaticConvergence(0.01, options.Stochastic, 1e-6).WithInverseTimeDecay(1, 300),
+			want: []float64{0.957, 1.034},
+		},
+		{
+			name: "stochastic automatic alpha=0.01 t=1e-6 exponential decay",
+			opt:  options.WithAutomaticConvergence(0.01, options.Stochastic, 1e-6).WithExponentialDecay(0.5, 3000),
+			want: []float64{0.997, 1.002},
+		},
+		{
+			name: "l-bfgs iterative i=10",
+			opt:  options.WithIterativeConvergence(0, options.LBFGS, 10),
+			want: []float64{1, 1},
+		},
+		{
+			name: "l-bfgs automatic t=1e-12 history=1",
+			opt:  options.WithAutomaticConvergence(0, options.LBFGS, 1e-12).WithLBFGS(1),
+			want: []float64{1, 1},
+		},
+		{
+			name: "conjugate gradient iterative i=10",
+			opt:  options.WithIterativeConvergence(0, options.ConjugateGradient, 10),
+			want: []float64{1, 1},
 		},
 	}
+	gds := []struct {
+		name string
+		gd   GradientDescent
+	}{
+		{name: "hyphothesis", gd: gd},
+		{name: "gradient", gd: NewWithGradient(gradStub, costStub, 2)},
+	}
 	ctx := context.Background()
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := gd.Run(ctx, tt.opt, x, y)
-			if err != nil {
-				t.Fatalf("want nil, got error %v", err)
-			}
-			if !regressiontest.AreFloatSlicesEqual(got, tt.want, 3) {
-				t.Fatalf("got %v, want %v", got, tt.want)
-			}
-		})
+		for _, g := range gds {
+			t.Run(tt.name+" "+g.name, func(t *testing.T) {
+				got, err := g.gd.Run(ctx, tt.opt, x, y)
+				if err != nil {
+					t.Fatalf("want nil, got error %v", err)
+				}
+				if !regressiontest.AreFloatSlicesEqual(got.Coefficients, tt.want, 3) {
+					t.Fatalf("got %v, want %v", got.Coefficients, tt.want)
+				}
+			})
+		}
 	}
 }
 
@@ -124,3 +188,280 @@ func TestRun_TooLargeLearningRate(t *testing.T) {
 		})
 	}
 }
+
+func TestRun_Regularization(t *testing.T) {
+	x := [][]float64{
+		{1, 2},
+		{3, 4},
+		{5, 6},
+	}
+	y := []float64{3, 7, 11}
+	tests := []struct {
+		name string
+		opt  options.Options
+		want []float64
+	}{
+		{
+			name: "batch iterative alpha=0.01 i=10000 lambda=1",
+			opt:  options.WithIterativeConvergence(0.01, options.Batch, 10000).WithRegularization(1),
+			want: []float64{1.746, 0.407},
+		},
+		{
+			name: "batch iterative alpha=0.01 i=10000 lambda=10",
+			opt:  options.WithIterativeConvergence(0.01, options.Batch, 10000).WithRegularization(10),
+			want: []float64{2.176, 0.064},
+		},
+		{
+			name: "stochastic iterative alpha=0.001 i=100000 lambda=10",
+			opt:  options.WithIterativeConvergence(0.001, options.Stochastic, 100000).WithRegularization(10),
+			want: []float64{2.175, 0.065},
+		},
+	}
+	gds := []struct {
+		name string
+		gd   GradientDescent
+	}{
+		{name: "hyphothesis", gd: gd},
+		{name: "gradient", gd: NewWithGradient(gradStub, costStub, 2)},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		for _, g := range gds {
+			t.Run(tt.name+" "+g.name, func(t *testing.T) {
+				got, err := g.gd.Run(ctx, tt.opt, x, y)
+				if err != nil {
+					t.Fatalf("want nil, got error %v", err)
+				}
+				if !regressiontest.AreFloatSlicesEqual(got.Coefficients, tt.want, 3) {
+					t.Fatalf("got %v, want %v", got.Coefficients, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestRun_InvalidRegularization(t *testing.T) {
+	opt := options.WithIterativeConvergence(0.01, options.Batch, 10).WithRegularization(-0.5)
+	if _, err := gd.Run(context.Background(), opt, [][]float64{{1, 2}}, []float64{1}); err != regression.ErrInvalidRegularization {
+		t.Fatalf("want %v, got %v", regression.ErrInvalidRegularization, err)
+	}
+}
+
+func TestPenalize(t *testing.T) {
+	x := [][]float64{
+		{1, 2, 3},
+		{1, 4, 5},
+	}
+	y := []float64{1, 2}
+	coeffs := []float64{10, 2, -3}
+	tests := []struct {
+		name string
+		l2   float64
+		want float64
+	}{
+		{name: "lambda=0", l2: 0, want: 7},
+		{name: "lambda=1", l2: 1, want: 10.25},
+		{name: "lambda=4", l2: 4, want: 20},
+	}
+	c := func(x [][]float64, y []float64, coeffs []float64) (float64, error) { return 7, nil }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Penalize(c, tt.l2)(x, y, coeffs)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_Optimizers(t *testing.T) {
+	x := [][]float64{
+		{1, 2},
+		{3, 4},
+		{5, 6},
+	}
+	y := []float64{3, 7, 11}
+	variants := []struct {
+		name string
+		opt  options.Options
+	}{
+		{name: "batch", opt: options.WithIterativeConvergence(0.01, options.Batch, 5000)},
+		{name: "stochastic", opt: options.WithIterativeConvergence(0.01, options.Stochastic, 15000)},
+		{name: "mini-batch", opt: options.WithIterativeConvergence(0.01, options.Batch, 5000).WithMiniBatch(2, 1)},
+	}
+	optimizers := []struct {
+		name string
+		with func(options.Options) options.Options
+	}{
+		{name: "momentum", with: func(o options.Options) options.Options { return o.WithMomentum(0.9) }},
+		{name: "nesterov", with: func(o options.Options) options.Options { return o.WithNesterov(0.9) }},
+		// AdaGrad needs a larger learning rate, since its step sizes keep decreasing.
+		{name: "adagrad", with: func(o options.Options) options.Options { o.LearningRate *= 10; return o.WithAdaGrad(1e-8) }},
+		// RMSProp steps don't shrink with the gradient, so they need a smaller learning rate to settle down.
+		{name: "rmsprop", with: func(o options.Options) options.Options { o.LearningRate /= 20; return o.WithRMSProp(0.9, 1e-8) }},
+		{name: "adam", with: func(o options.Options) options.Options { return o.WithAdam(0.9, 0.999, 1e-8) }},
+	}
+	gds := []struct {
+		name string
+		gd   GradientDescent
+	}{
+		{name: "hyphothesis", gd: gd},
+		{name: "gradient", gd: NewWithGradient(gradStub, costStub, 2)},
+	}
+	want := []float64{1, 1}
+	ctx := context.Background()
+	for _, v := range variants {
+		for _, o := range optimizers {
+			for _, g := range gds {
+				t.Run(v.name+" "+o.name+" "+g.name, func(t *testing.T) {
+					got, err := g.gd.Run(ctx, o.with(v.opt), x, y)
+					if err != nil {
+						t.Fatalf("want nil, got error %v", err)
+					}
+					if !regressiontest.AreFloatSlicesEqual(got.Coefficients, want, 2) {
+						t.Fatalf("got %v, want %v", got.Coefficients, want)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestRun_InvalidOptimizer(t *testing.T) {
+	o := options.WithIterativeConvergence(0.01, options.Batch, 10).WithAdam(0.9, 1, 1e-8)
+	if _, err := gd.Run(context.Background(), o, [][]float64{{1, 2}}, []float64{3}); err != regression.ErrInvalidOptimizer {
+		t.Fatalf("want %v, got %v", regression.ErrInvalidOptimizer, err)
+	}
+}
+
+func TestRun_InvalidHistory(t *testing.T) {
+	o := options.WithIterativeConvergence(0, options.LBFGS, 10).WithLBFGS(-1)
+	if _, err := gd.Run(context.Background(), o, [][]float64{{1, 2}}, []float64{3}); err != regression.ErrInvalidHistory {
+		t.Fatalf("want %v, got %v", regression.ErrInvalidHistory, err)
+	}
+}
+
+func TestRun_Observer(t *testing.T) {
+	x := [][]float64{
+		{1, 2},
+		{3, 4},
+		{5, 6},
+	}
+	y := []float64{3, 7, 11}
+	tests := []struct {
+		name  string
+		opt   options.Options
+		rates []float64
+	}{
+		{
+			name:  "batch step decay",
+			opt:   options.WithIterativeConvergence(0.01, options.Batch, 4).WithStepDecay(0.5, 1),
+			rates: []float64{0.01, 0.005, 0.0025, 0.00125},
+		},
+		{
+			name:  "mini-batch inverse-time decay",
+			opt:   options.WithIterativeConvergence(0.01, options.Batch, 3).WithMiniBatch(2, 1).WithInverseTimeDecay(1, 1),
+			rates: []float64{0.01, 0.005, 0.01 / 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rates []float64
+			o := tt.opt.WithObserver(options.ObserverFunc(func(p options.Progress) error {
+				rates = append(rates, p.LearningRate)
+				return nil
+			}), 0)
+			if _, err := gd.Run(context.Background(), o, x, y); err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if !regressiontest.AreFloatSlicesEqual(rates, tt.rates, 9) {
+				t.Errorf("got learning rates %v, want %v", rates, tt.rates)
+			}
+		})
+	}
+	t.Run("l-bfgs", func(t *testing.T) {
+		prev := math.Inf(1)
+		o := options.WithIterativeConvergence(0, options.LBFGS, 10).WithObserver(options.ObserverFunc(func(p options.Progress) error {
+			if p.LearningRate <= 0 || p.Cost > prev {
+				t.Errorf("got step length %v and cost %v after iteration %d, want positive and at most %v", p.LearningRate, p.Cost, p.Iteration, prev)
+			}
+			prev = p.Cost
+			return nil
+		}), 0)
+		if _, err := gd.Run(context.Background(), o, x, y); err != nil {
+			t.Fatalf("want nil, got error %v", err)
+		}
+	})
+	t.Run("stochastic cadence", func(t *testing.T) {
+		// Observers and checkpointers of the stochastic variant are called after every training example.
+		var observed, checkpointed []int
+		o := options.WithIterativeConvergence(0.01, options.Stochastic, 5).WithObserver(options.ObserverFunc(func(p options.Progress) error {
+			observed = append(observed, p.Iteration)
+			return nil
+		}), 1).WithCheckpointer(options.CheckpointerFunc(func(c options.Checkpoint) error {
+			if c.Cursor != c.Iteration%len(x) {
+				t.Errorf("got cursor %d after iteration %d, want %d", c.Cursor, c.Iteration, c.Iteration%len(x))
+			}
+			checkpointed = append(checkpointed, c.Iteration)
+			return nil
+		}), 2)
+		if _, err := gd.Run(context.Background(), o, x, y); err != nil {
+			t.Fatalf("want nil, got error %v", err)
+		}
+		if fmt.Sprint(observed) != "[1 2 3 4 5]" || fmt.Sprint(checkpointed) != "[2 4]" {
+			t.Errorf("got observer calls after iterations %v and checkpoints after %v, want [1 2 3 4 5] and [2 4]", observed, checkpointed)
+		}
+	})
+}
+
+func TestRun_InvalidEarlyStopping(t *testing.T) {
+	x := [][]float64{{1, 2}, {1, 3}, {1, 4}}
+	y := []float64{2, 3, 4}
+	o := options.WithIterativeConvergence(0.1, options.Batch, 10)
+	tests := []struct {
+		name string
+		o    options.Options
+		vx   [][]float64
+		vy   []float64
+	}{
+		{name: "no validation set", o: o.WithEarlyStopping(options.WithValidationSplit(0.2, 1, 3))},
+		{name: "invalid patience", o: o.WithEarlyStopping(options.WithValidationSplit(0.2, 1, 0)), vx: x, vy: y},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := gd.RunWithValidation(context.Background(), tt.o, x, y, tt.vx, tt.vy); err != regression.ErrInvalidEarlyStopping {
+				t.Fatalf("want %v, got %v", regression.ErrInvalidEarlyStopping, err)
+			}
+		})
+	}
+}
+
+func TestRun_ReportIterations(t *testing.T) {
+	x := [][]float64{
+		{1, 2},
+		{3, 4},
+		{5, 6},
+	}
+	y := []float64{3, 7, 11}
+	// An iteration of the stochastic variant is a single training example, not an epoch.
+	const lr, iterations = 0.01, 5
+	want := []float64{0, 0}
+	for k := 0; k < iterations; k++ {
+		i := k % len(x)
+		hr, _ := hyphoStub(x[i], want)
+		want = []float64{want[0] - lr*(hr-y[i])*x[i][0], want[1] - lr*(hr-y[i])*x[i][1]}
+	}
+	got, err := gd.Run(context.Background(), options.WithIterativeConvergence(lr, options.Stochastic, iterations).WithLearningCurve(), x, y)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if !regressiontest.AreFloatSlicesEqual(got.Coefficients, want, 12) {
+		t.Errorf("got %v, want %v", got.Coefficients, want)
+	}
+	if got.Report.Iterations != iterations || len(got.Report.LearningCurve) != iterations {
+		t.Errorf("got %d iterations and learning curve of %d, want %d", got.Report.Iterations, len(got.Report.LearningCurve), iterations)
+	}
+}
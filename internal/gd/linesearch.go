@@ -0,0 +1,348 @@
+package gd
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/options"
+)
+
+const (
+	// defaultHistory is the number of corrections kept by L-BFGS if options don't specify it.
+	defaultHistory = 10
+	// armijo is the sufficient decrease constant of the Wolfe conditions.
+	armijo = 1e-4
+	// maxLineSearch limits the number of cost function evaluations in a single line search.
+	maxLineSearch = 40
+)
+
+// errStationary is returned by a stepper if it cannot decrease a cost function any further,
+// i.e. it has reached a stationary point within the floating point precision.
+var errStationary = errors.New("stationary point")
+
+// An ObjectiveFunc returns a cost function value and its gradient for given coefficients.
+// It returns the context error if the context is done before the gradient is computed.
+type ObjectiveFunc func(ctx context.Context, coeffs []float64) (float64, []float64, error)
+
+// A direction computes search directions of a line search method.
+type direction interface {
+	// next returns a search direction for the gradient g.
+	next(g []float64) []float64
+	// update records a step of the length a along the direction d and the corresponding gradient change y.
+	update(d []float64, a float64, y []float64)
+	// reset forgets all the previous steps.
+	reset()
+	// initial returns the initial step length of a line search, given the previous step length a and
+	// directional derivatives along the previous and the current direction.
+	initial(a, prev, cur float64) float64
+	// curvature returns the curvature condition constant of the Wolfe conditions.
+	curvature() float64
+	// state returns a copy of the previous steps, saved in checkpoints.
+	state() [][]float64
+	// restore restores the previous steps returned by state for n coefficients.
+	restore(m [][]float64, n int) error
+}
+
+// lineSearchStepper takes steps along search directions with the step length satisfying the strong Wolfe conditions.
+// Its learning rate isn't fixed, so options' learning rate, optimizer and schedule don't apply.
+type lineSearchStepper struct {
+	baseStepper
+	obj ObjectiveFunc
+	dir direction
+	// f and g are the cost function value and gradient at the current coefficients.
+	f float64
+	g []float64
+	// a is the last step length and da is the directional derivative along the last direction.
+	a  float64
+	da float64
+}
+
+// newLineSearchStepper returns a new line search stepper for the gradient descent variant chosen in options.
+// It starts from n zero coefficients.
+func newLineSearchStepper(o options.Options, obj ObjectiveFunc, x [][]float64, y []float64, n int) (Stepper, error) {
+	var dir direction
+	switch o.GradientDescentVariant {
+	case options.LBFGS:
+		h := o.History
+		if h == 0 {
+			h = defaultHistory
+		}
+		if h < 0 {
+			return nil, regression.ErrInvalidHistory
+		}
+		dir = &lbfgs{history: h}
+	case options.ConjugateGradient:
+		dir = &conjugateGradient{}
+	default:
+		return nil, regression.ErrUnsupportedGradientDescentVariant
+	}
+	return &lineSearchStepper{baseStepper: baseStepper{x: x, y: y, l2: o.Regularization, coeffs: make([]float64, n)}, obj: obj, dir: dir}, nil
+}
+
+func (s *lineSearchStepper) TakeStep(ctx context.Context) error {
+	if s.g == nil {
+		f, g, err := s.obj(ctx, s.coeffs)
+		if err != nil {
+			return err
+		}
+		s.f, s.g = f, g
+	}
+	var d []float64
+	var a float64
+	if s.a != 0 {
+		d = s.dir.next(s.g)
+		a = s.dir.initial(s.a, s.da, dot(s.g, d))
+	}
+	if d == nil || dot(s.g, d) >= 0 {
+		// Restart from the steepest descent with the step of the unit length.
+		s.dir.reset()
+		d = make([]float64, len(s.g))
+		for j := range d {
+			d[j] = -s.g[j]
+		}
+		a = 1 / math.Sqrt(dot(s.g, s.g))
+	}
+	if math.IsInf(a, 1) {
+		// The gradient equals zero.
+		return errStationary
+	}
+	da := dot(s.g, d)
+	a, f, g, err := s.search(ctx, d, a)
+	if err != nil {
+		return err
+	}
+	nc := make([]float64, len(s.coeffs))
+	y := make([]float64, len(s.coeffs))
+	for j := range nc {
+		nc[j] = s.coeffs[j] + a*d[j]
+		y[j] = g[j] - s.g[j]
+	}
+	s.dir.update(d, a, y)
+	s.coeffs, s.f, s.g, s.a, s.da = nc, f, g, a, da
+	return nil
+}
+
+// GradientNorm returns the norm of the gradient at the current coefficients.
+func (s *lineSearchStepper) GradientNorm() float64 {
+	return math.Sqrt(dot(s.g, s.g))
+}
+
+// LearningRate returns the step length of the last step.
+func (s *lineSearchStepper) LearningRate() float64 {
+	return s.a
+}
+
+// search finds the step length along the descent direction d, starting from the step length a.
+// The step length satisfies the strong Wolfe conditions:
+//
+//	f(O+a*d) <= f(O)+c1*a*g'd,
+//	|g(O+a*d)'d| <= c2*|g'd|.
+//
+// It returns the step length with the cost function value and gradient at the new coefficients.
+func (s *lineSearchStepper) search(ctx context.Context, d []float64, a float64) (float64, float64, []float64, error) {
+	c2 := s.dir.curvature()
+	f0, d0 := s.f, dot(s.g, d)
+	// eval calculates the cost function value, gradient and directional derivative for the step length.
+	eval := func(a float64) (float64, []float64, float64, error) {
+		c := make([]float64, len(s.coeffs))
+		for j := range c {
+			c[j] = s.coeffs[j] + a*d[j]
+		}
+		f, g, err := s.obj(ctx, c)
+		if err != nil {
+			return 0, nil, 0, err
+		}
+		// A step too long may overflow the cost function, it's treated as the infinite cost.
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return math.Inf(1), nil, math.NaN(), nil
+		}
+		for j := range g {
+			if math.IsNaN(g[j]) || math.IsInf(g[j], 0) {
+				return math.Inf(1), nil, math.NaN(), nil
+			}
+		}
+		return f, g, dot(g, d), nil
+	}
+	// lo is the best step length found so far, it satisfies the sufficient decrease condition.
+	var lo, flo, dlo float64 = 0, f0, d0
+	var glo []float64
+	var hi, fhi, dhi float64
+	bracketed, finite := false, false
+	for k := 0; k < maxLineSearch; k++ {
+		if bracketed {
+			a = interpolate(lo, hi, flo, fhi, dlo, dhi)
+		}
+		f, g, da, err := eval(a)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		if !math.IsInf(f, 1) {
+			finite = true
+		}
+		if f > f0+armijo*a*d0 || (f >= flo && k > 0) {
+			hi, fhi, dhi, bracketed = a, f, da, true
+		} else {
+			if math.Abs(da) <= -c2*d0 {
+				return a, f, g, nil
+			}
+			if bracketed && da*(hi-lo) >= 0 {
+				hi, fhi, dhi = lo, flo, dlo
+			} else if !bracketed && da >= 0 {
+				hi, fhi, dhi, bracketed = lo, flo, dlo, true
+			}
+			lo, flo, dlo, glo = a, f, da, g
+			if !bracketed {
+				a *= 2
+			}
+		}
+		if bracketed && math.Abs(hi-lo) <= 1e-16*math.Max(1, math.Abs(lo)) {
+			break
+		}
+	}
+	if lo == 0 && !finite {
+		return 0, 0, nil, regression.ErrCannotConverge
+	}
+	if lo == 0 {
+		// No step decreases the cost function within the floating point precision.
+		return 0, 0, nil, errStationary
+	}
+	// Accept the step length satisfying only the sufficient decrease condition.
+	return lo, flo, glo, nil
+}
+
+// interpolate returns the minimizer of the cubic interpolating the function values and derivatives
+// at step lengths a and b. It falls back on bisection if the minimizer is too close to the interval ends.
+func interpolate(a, b, fa, fb, da, db float64) float64 {
+	d1 := da + db - 3*(fa-fb)/(a-b)
+	sq := d1*d1 - da*db
+	lo, hi := math.Min(a, b), math.Max(a, b)
+	mid := (a + b) / 2
+	if sq < 0 {
+		return mid
+	}
+	d2 := math.Copysign(math.Sqrt(sq), b-a)
+	c := b - (b-a)*(db+d2-d1)/(db-da+2*d2)
+	if math.IsNaN(c) || c < lo+0.1*(hi-lo) || c > hi-0.1*(hi-lo) {
+		return mid
+	}
+	return c
+}
+
+// lbfgs computes search directions of the limited-memory BFGS quasi-Newton method. It approximates
+// the inverse Hessian with the history of the last steps and gradient changes.
+type lbfgs struct {
+	history int
+	s       [][]float64
+	y       [][]float64
+}
+
+func (l *lbfgs) next(g []float64) []float64 {
+	// The two-loop recursion.
+	q := make([]float64, len(g))
+	for j := range g {
+		q[j] = -g[j]
+	}
+	k := len(l.s)
+	alpha := make([]float64, k)
+	for i := k - 1; i >= 0; i-- {
+		alpha[i] = dot(l.s[i], q) / dot(l.y[i], l.s[i])
+		for j := range q {
+			q[j] -= alpha[i] * l.y[i][j]
+		}
+	}
+	if k > 0 {
+		// Scale the initial inverse Hessian approximation.
+		gamma := dot(l.s[k-1], l.y[k-1]) / dot(l.y[k-1], l.y[k-1])
+		for j := range q {
+			q[j] *= gamma
+		}
+	}
+	for i := 0; i < k; i++ {
+		beta := dot(l.y[i], q) / dot(l.y[i], l.s[i])
+		for j := range q {
+			q[j] += (alpha[i] - beta) * l.s[i][j]
+		}
+	}
+	return q
+}
+
+func (l *lbfgs) update(d []float64, a float64, y []float64) {
+	s := make([]float64, len(d))
+	for j := range d {
+		s[j] = a * d[j]
+	}
+	// Skip the pair if it would break the positive definiteness of the approximation.
+	if dot(s, y) <= 1e-10*math.Sqrt(dot(s, s)*dot(y, y)) {
+		return
+	}
+	if len(l.s) == l.history {
+		l.s, l.y = l.s[1:], l.y[1:]
+	}
+	l.s = append(l.s, s)
+	l.y = append(l.y, y)
+}
+
+func (l *lbfgs) reset() {
+	l.s, l.y = nil, nil
+}
+
+// initial returns the unit step length, since quasi-Newton directions are well scaled.
+func (l *lbfgs) initial(a, prev, cur float64) float64 {
+	return 1
+}
+
+func (l *lbfgs) curvature() float64 {
+	return 0.9
+}
+
+// conjugateGradient computes search directions of the nonlinear conjugate gradient method
+// with the Polak-Ribiere formula, restarted whenever the formula yields a negative value.
+type conjugateGradient struct {
+	d []float64
+	y []float64
+}
+
+func (c *conjugateGradient) next(g []float64) []float64 {
+	if c.d == nil {
+		return nil
+	}
+	// The previous gradient equals g-y.
+	var pg float64
+	for j := range g {
+		pg += (g[j] - c.y[j]) * (g[j] - c.y[j])
+	}
+	beta := math.Max(0, dot(g, c.y)/pg)
+	d := make([]float64, len(g))
+	for j := range d {
+		d[j] = -g[j] + beta*c.d[j]
+	}
+	return d
+}
+
+func (c *conjugateGradient) update(d []float64, a float64, y []float64) {
+	c.d, c.y = d, y
+}
+
+func (c *conjugateGradient) reset() {
+	c.d, c.y = nil, nil
+}
+
+// initial returns the step length for which the first-order change of the cost function is the same
+// as in the previous step.
+func (c *conjugateGradient) initial(a, prev, cur float64) float64 {
+	return a * prev / cur
+}
+
+func (c *conjugateGradient) curvature() float64 {
+	return 0.1
+}
+
+func dot(x, y []float64) float64 {
+	var v float64
+	for j := range x {
+		v += x[j] * y[j]
+	}
+	return v
+}
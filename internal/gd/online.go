@@ -0,0 +1,116 @@
+package gd
+
+import (
+	"context"
+	"sync"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/options"
+)
+
+// Online is an online gradient descent. It learns from training examples as they arrive, taking a step
+// of the stochastic gradient descent variant on each of them.
+//
+// Fit calls are serialized, while Coefficients may be called concurrently with them.
+type Online struct {
+	// fit serializes Fit calls.
+	fit sync.Mutex
+	s   *stochasticStepper
+	// mu guards coeffs, the coefficients published after the last Fit.
+	mu     sync.RWMutex
+	coeffs []float64
+}
+
+// NewOnline returns a new online gradient descent driven by a hyphothesis function for feature vectors of the length
+// width (including the dummy feature). It starts from zero coefficients or the checkpoint in options, whose cursor
+// is ignored.
+//
+// Options must choose the stochastic gradient descent variant, otherwise regression.ErrUnsupportedGradientDescentVariant
+// is returned. Convergence options don't apply, since there is no end of training, and early stopping isn't supported.
+func NewOnline(o options.Options, h Hyphothesis, width int) (*Online, error) {
+	if o.GradientDescentVariant != options.Stochastic {
+		return nil, regression.ErrUnsupportedGradientDescentVariant
+	}
+	if !options.IsValidRegularization(o.Regularization) {
+		return nil, regression.ErrInvalidRegularization
+	}
+	if o.EarlyStopping != nil {
+		return nil, regression.ErrInvalidEarlyStopping
+	}
+	bs, err := newBase(o, nil, 0, width, width)
+	if err != nil {
+		return nil, err
+	}
+	bs.hypho = h
+	s := &stochasticStepper{baseStepper: bs}
+	if o.Resume != nil {
+		if err := s.baseStepper.restore(*o.Resume); err != nil {
+			return nil, err
+		}
+	}
+	return &Online{s: s, coeffs: s.coeffs}, nil
+}
+
+// Fit takes a step on every training example (x[i], y[i]) in order. Feature vectors must include the dummy feature.
+// It returns hyphothesis values of the training examples calculated just before stepping on them, so they measure
+// how well the model generalizes (progressive validation).
+//
+// The regularization term is scaled by the number of training examples seen so far, including the one stepped on,
+// so the coefficients don't depend on how training examples are split between Fit calls. The context is checked
+// between training examples. If Fit fails, training examples before the failing one have been learned
+// and their hyphothesis values are returned along with the error.
+func (g *Online) Fit(ctx context.Context, x [][]float64, y []float64) ([]float64, error) {
+	g.fit.Lock()
+	defer g.fit.Unlock()
+	s := g.s
+	if len(x) == 0 {
+		return nil, nil
+	}
+	if err := s.setX(x); err != nil {
+		return nil, err
+	}
+	s.y, s.i = y, 0
+	defer g.publish()
+	p := make([]float64, len(x))
+	for i := range x {
+		if err := g.step(ctx, i, p); err != nil {
+			return p[:i], err
+		}
+	}
+	return p, nil
+}
+
+// step stores the hyphothesis value of the i-th training example in p and takes a step on it.
+// If it fails, the training example isn't counted as seen.
+func (g *Online) step(ctx context.Context, i int, p []float64) error {
+	s := g.s
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	hr, err := s.hypho(s.d.Row(i), s.coeffs)
+	if err != nil {
+		return err
+	}
+	p[i] = hr
+	s.m++
+	if err := s.TakeStep(ctx); err != nil {
+		s.m--
+		return err
+	}
+	return nil
+}
+
+// publish publishes the current coefficients of the stepper. Steps never modify coefficients in place,
+// so the published ones can be read without holding the lock.
+func (g *Online) publish() {
+	g.mu.Lock()
+	g.coeffs = g.s.coeffs
+	g.mu.Unlock()
+}
+
+// Coefficients returns the coefficients learned by the last Fit. The returned slice must not be modified.
+func (g *Online) Coefficients() []float64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.coeffs
+}
@@ -0,0 +1,96 @@
+package gd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/options"
+)
+
+func TestOnline_Fit(t *testing.T) {
+	x := [][]float64{
+		{1, 2},
+		{3, 4},
+		{5, 6},
+	}
+	y := []float64{3, 7, 11}
+	o := options.WithIterativeConvergence(0.01, options.Stochastic, 30).WithAdam(0.9, 0.999, 1e-8).WithInverseTimeDecay(1, 10)
+	ctx := context.Background()
+	want, err := gd.Run(ctx, o, x, y)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	g, err := NewOnline(o, hyphoStub, 2)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	// Learning a training set in mini-batches takes the same steps as the stochastic gradient descent variant.
+	for k := 0; k < 10; k++ {
+		for i := range x {
+			coeffs := g.Coefficients()
+			p, err := g.Fit(ctx, x[i:i+1], y[i:i+1])
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if hr, _ := hyphoStub(x[i], coeffs); len(p) != 1 || p[0] != hr {
+				t.Fatalf("got predictions %v, want [%v]", p, hr)
+			}
+		}
+	}
+	if got := g.Coefficients(); !areIdentical(got, want.Coefficients) {
+		t.Errorf("got %v, want %v", got, want.Coefficients)
+	}
+}
+
+func TestOnline_Fit_WarmStart(t *testing.T) {
+	o := options.WithIterativeConvergence(0.01, options.Stochastic, 1).WithWarmStart([]float64{1, 1})
+	g, err := NewOnline(o, hyphoStub, 2)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	p, err := g.Fit(context.Background(), [][]float64{{1, 2}}, []float64{3})
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	// The warm started coefficients fit the training example perfectly, so they don't change.
+	if p[0] != 3 || !areIdentical(g.Coefficients(), []float64{1, 1}) {
+		t.Errorf("got prediction %v and coefficients %v, want %v and %v", p[0], g.Coefficients(), 3, []float64{1, 1})
+	}
+}
+
+func TestNewOnline_Invalid(t *testing.T) {
+	o := options.WithIterativeConvergence(0.01, options.Stochastic, 1)
+	tests := []struct {
+		name string
+		o    options.Options
+		want error
+	}{
+		{name: "batch", o: options.WithIterativeConvergence(0.01, options.Batch, 1), want: regression.ErrUnsupportedGradientDescentVariant},
+		{name: "regularization", o: o.WithRegularization(-1), want: regression.ErrInvalidRegularization},
+		{name: "early stopping", o: o.WithEarlyStopping(options.WithValidationSplit(0.2, 1, 3)), want: regression.ErrInvalidEarlyStopping},
+		{name: "warm start", o: o.WithWarmStart([]float64{1}), want: regression.ErrInvalidCheckpoint},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewOnline(tt.o, hyphoStub, 2); err != tt.want {
+				t.Fatalf("want %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestOnline_Fit_ContextCanceled(t *testing.T) {
+	g, err := NewOnline(options.WithIterativeConvergence(0.01, options.Stochastic, 1), hyphoStub, 2)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := g.Fit(ctx, [][]float64{{1, 2}}, []float64{3}); err != context.Canceled {
+		t.Fatalf("want %v, got %v", context.Canceled, err)
+	}
+	if !areIdentical(g.Coefficients(), []float64{0, 0}) {
+		t.Errorf("got %v, want zero coefficients", g.Coefficients())
+	}
+}
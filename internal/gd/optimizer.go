@@ -0,0 +1,147 @@
+package gd
+
+import (
+	"math"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/options"
+)
+
+// An Optimizer calculates next values of the coefficients from a cost function gradient.
+// Steppers compute gradients, while an optimizer decides how to move along them. Optimizers may keep state between steps.
+type Optimizer interface {
+	// Update returns new coefficients for the current coefficients, a cost function gradient g and a learning rate lr.
+	Update(coeffs []float64, g []float64, lr float64) []float64
+}
+
+// NewOptimizer returns a new optimizer chosen in options for n coefficients.
+// If unsupported Optimizer is passed or its hyperparameters are invalid, an error is returned.
+func NewOptimizer(o options.Options, n int) (Optimizer, error) {
+	switch o.Optimizer {
+	case options.Vanilla:
+		return vanilla{}, nil
+	case options.Momentum, options.Nesterov:
+		if !isValidDecay(o.Beta1) {
+			return nil, regression.ErrInvalidOptimizer
+		}
+		return &momentum{beta: o.Beta1, nesterov: o.Optimizer == options.Nesterov, v: make([]float64, n)}, nil
+	case options.AdaGrad:
+		if !isValidEpsilon(o.Epsilon) {
+			return nil, regression.ErrInvalidOptimizer
+		}
+		return &adaGrad{eps: o.Epsilon, s: make([]float64, n)}, nil
+	case options.RMSProp:
+		if !isValidDecay(o.Beta2) || !isValidEpsilon(o.Epsilon) {
+			return nil, regression.ErrInvalidOptimizer
+		}
+		return &rmsProp{beta: o.Beta2, eps: o.Epsilon, s: make([]float64, n)}, nil
+	case options.Adam:
+		if !isValidDecay(o.Beta1) || !isValidDecay(o.Beta2) || !isValidEpsilon(o.Epsilon) {
+			return nil, regression.ErrInvalidOptimizer
+		}
+		return &adam{beta1: o.Beta1, beta2: o.Beta2, eps: o.Epsilon, m: make([]float64, n), v: make([]float64, n)}, nil
+	default:
+		return nil, regression.ErrUnsupportedOptimizer
+	}
+}
+
+// isValidDecay checks if a decay rate is within [0, 1).
+func isValidDecay(b float64) bool {
+	return b >= 0 && b < 1
+}
+
+// isValidEpsilon checks if a smoothing term is a positive number.
+func isValidEpsilon(e float64) bool {
+	return e > 0 && !math.IsInf(e, 1)
+}
+
+// vanilla takes steps proportional to the gradient, O=O-lr*g.
+type vanilla struct{}
+
+func (o vanilla) Update(coeffs []float64, g []float64, lr float64) []float64 {
+	nc := make([]float64, len(coeffs))
+	for j := range coeffs {
+		nc[j] = coeffs[j] - lr*g[j]
+	}
+	return nc
+}
+
+// momentum accumulates a velocity of past gradients, v=beta*v-lr*g and O=O+v.
+//
+// The Nesterov variant corrects the step with the velocity it's about to take, O=O+beta*v-lr*g.
+type momentum struct {
+	beta     float64
+	nesterov bool
+	v        []float64
+}
+
+func (o *momentum) Update(coeffs []float64, g []float64, lr float64) []float64 {
+	nc := make([]float64, len(coeffs))
+	for j := range coeffs {
+		o.v[j] = o.beta*o.v[j] - lr*g[j]
+		if o.nesterov {
+			nc[j] = coeffs[j] + o.beta*o.v[j] - lr*g[j]
+		} else {
+			nc[j] = coeffs[j] + o.v[j]
+		}
+	}
+	return nc
+}
+
+// adaGrad scales the learning rate of every coefficient by the accumulated squared gradients,
+// s=s+g^2 and O=O-lr*g/(sqrt(s)+eps).
+type adaGrad struct {
+	eps float64
+	s   []float64
+}
+
+func (o *adaGrad) Update(coeffs []float64, g []float64, lr float64) []float64 {
+	nc := make([]float64, len(coeffs))
+	for j := range coeffs {
+		o.s[j] += g[j] * g[j]
+		nc[j] = coeffs[j] - lr*g[j]/(math.Sqrt(o.s[j])+o.eps)
+	}
+	return nc
+}
+
+// rmsProp scales the learning rate of every coefficient by the moving average of squared gradients,
+// s=beta*s+(1-beta)*g^2 and O=O-lr*g/(sqrt(s)+eps).
+type rmsProp struct {
+	beta float64
+	eps  float64
+	s    []float64
+}
+
+func (o *rmsProp) Update(coeffs []float64, g []float64, lr float64) []float64 {
+	nc := make([]float64, len(coeffs))
+	for j := range coeffs {
+		o.s[j] = o.beta*o.s[j] + (1-o.beta)*g[j]*g[j]
+		nc[j] = coeffs[j] - lr*g[j]/(math.Sqrt(o.s[j])+o.eps)
+	}
+	return nc
+}
+
+// adam combines momentum with RMSProp. It keeps moving averages of gradients m=beta1*m+(1-beta1)*g and
+// squared gradients v=beta2*v+(1-beta2)*g^2, corrects their bias towards zero and takes steps
+// O=O-lr*m'/(sqrt(v')+eps), where m'=m/(1-beta1^t) and v'=v/(1-beta2^t).
+type adam struct {
+	beta1 float64
+	beta2 float64
+	eps   float64
+	m     []float64
+	v     []float64
+	t     int
+}
+
+func (o *adam) Update(coeffs []float64, g []float64, lr float64) []float64 {
+	o.t++
+	c1 := 1 - math.Pow(o.beta1, float64(o.t))
+	c2 := 1 - math.Pow(o.beta2, float64(o.t))
+	nc := make([]float64, len(coeffs))
+	for j := range coeffs {
+		o.m[j] = o.beta1*o.m[j] + (1-o.beta1)*g[j]
+		o.v[j] = o.beta2*o.v[j] + (1-o.beta2)*g[j]*g[j]
+		nc[j] = coeffs[j] - lr*(o.m[j]/c1)/(math.Sqrt(o.v[j]/c2)+o.eps)
+	}
+	return nc
+}
@@ -0,0 +1,83 @@
+package gd
+
+import (
+	"math"
+	"testing"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
+	"github.com/erni27/regression/options"
+)
+
+func TestNewOptimizer(t *testing.T) {
+	o := options.Options{LearningRate: 0.1}
+	tests := []struct {
+		name string
+		o    options.Options
+		err  error
+	}{
+		{name: "vanilla", o: o},
+		{name: "momentum", o: o.WithMomentum(0.9)},
+		{name: "nesterov", o: o.WithNesterov(0)},
+		{name: "adagrad", o: o.WithAdaGrad(1e-8)},
+		{name: "rmsprop", o: o.WithRMSProp(0.9, 1e-8)},
+		{name: "adam", o: o.WithAdam(0.9, 0.999, 1e-8)},
+		{name: "momentum equals one", o: o.WithMomentum(1), err: regression.ErrInvalidOptimizer},
+		{name: "negative nesterov momentum", o: o.WithNesterov(-0.5), err: regression.ErrInvalidOptimizer},
+		{name: "adagrad zero epsilon", o: o.WithAdaGrad(0), err: regression.ErrInvalidOptimizer},
+		{name: "rmsprop invalid decay", o: o.WithRMSProp(1.5, 1e-8), err: regression.ErrInvalidOptimizer},
+		{name: "adam invalid beta1", o: o.WithAdam(1, 0.999, 1e-8), err: regression.ErrInvalidOptimizer},
+		{name: "adam nan beta2", o: o.WithAdam(0.9, math.NaN(), 1e-8), err: regression.ErrInvalidOptimizer},
+		{name: "unsupported", o: options.Options{Optimizer: -1}, err: regression.ErrUnsupportedOptimizer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewOptimizer(tt.o, 2)
+			if err != tt.err {
+				t.Fatalf("want error %v, got %v", tt.err, err)
+			}
+			if tt.err == nil && got == nil {
+				t.Fatalf("want not nil optimizer")
+			}
+		})
+	}
+}
+
+func TestOptimizer_Update(t *testing.T) {
+	o := options.Options{LearningRate: 0.1}
+	g := []float64{0.5, -1}
+	tests := []struct {
+		name  string
+		o     options.Options
+		steps int
+		want  []float64
+	}{
+		{name: "vanilla one step", o: o, steps: 1, want: []float64{0.95, 2.1}},
+		{name: "vanilla two steps", o: o, steps: 2, want: []float64{0.9, 2.2}},
+		{name: "momentum one step", o: o.WithMomentum(0.9), steps: 1, want: []float64{0.95, 2.1}},
+		{name: "momentum two steps", o: o.WithMomentum(0.9), steps: 2, want: []float64{0.855, 2.29}},
+		{name: "nesterov one step", o: o.WithNesterov(0.9), steps: 1, want: []float64{0.905, 2.19}},
+		{name: "nesterov two steps", o: o.WithNesterov(0.9), steps: 2, want: []float64{0.7695, 2.461}},
+		// Adaptive optimizers take the first step of the learning rate size regardless of the gradient scale.
+		{name: "adagrad one step", o: o.WithAdaGrad(1e-8), steps: 1, want: []float64{0.9, 2.1}},
+		{name: "adagrad two steps", o: o.WithAdaGrad(1e-8), steps: 2, want: []float64{0.829289, 2.170711}},
+		{name: "rmsprop one step", o: o.WithRMSProp(0.9, 1e-8), steps: 1, want: []float64{0.683772, 2.316228}},
+		{name: "adam one step", o: o.WithAdam(0.9, 0.999, 1e-8), steps: 1, want: []float64{0.9, 2.1}},
+		{name: "adam two steps", o: o.WithAdam(0.9, 0.999, 1e-8), steps: 2, want: []float64{0.8, 2.2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt, err := NewOptimizer(tt.o, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []float64{1, 2}
+			for k := 0; k < tt.steps; k++ {
+				got = opt.Update(got, g, tt.o.LearningRate)
+			}
+			if !regressiontest.AreFloatSlicesEqual(got, tt.want, 6) {
+				t.Fatalf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package gd
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/matrix"
+	"github.com/erni27/regression/options"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// minChunkSize is the smallest number of training examples per chunk. Smaller chunks
+	// don't pay off the cost of scheduling them.
+	minChunkSize = 256
+	// maxChunks bounds the number of chunks, and so the memory taken by partial gradients.
+	maxChunks = 64
+)
+
+// A reducer sums gradients over training examples in parallel.
+//
+// Training examples are split into contiguous chunks which depend only on the training set size.
+// Every chunk accumulates its own partial gradient and partial gradients are added up in the chunk order,
+// so the result doesn't depend on the number of workers nor on the scheduling.
+type reducer struct {
+	workers  int
+	examples int
+	n        int
+	size     int
+	partials [][]float64
+}
+
+// newReducer returns a new reducer of gradients of n coefficients over m training examples.
+// It uses at most o.Workers goroutines, zero means GOMAXPROCS. If the number of workers is negative, an error is returned.
+func newReducer(o options.Options, m, n int) (*reducer, error) {
+	if o.Workers < 0 {
+		return nil, regression.ErrInvalidWorkers
+	}
+	workers := o.Workers
+	if workers == 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	size := (m + maxChunks - 1) / maxChunks
+	if size < minChunkSize {
+		size = minChunkSize
+	}
+	chunks := (m + size - 1) / size
+	if workers > chunks {
+		workers = chunks
+	}
+	partials := make([][]float64, chunks)
+	for c := range partials {
+		partials[c] = make([]float64, n)
+	}
+	return &reducer{workers: workers, examples: m, n: n, size: size, partials: partials}, nil
+}
+
+// sum calls f for every chunk [lo, hi) of training examples. f adds the gradient of the chunk to grad, which is zeroed
+// beforehand. It returns the sum of all partial gradients or the first error returned by f.
+//
+// A single worker (or a single chunk) processes chunks in the calling goroutine. The context is checked before every
+// chunk and all the workers are done once sum returns.
+func (r *reducer) sum(ctx context.Context, f func(lo, hi int, grad []float64) error) ([]float64, error) {
+	chunk := func(c int) error {
+		p := r.partials[c]
+		for j := range p {
+			p[j] = 0
+		}
+		lo := c * r.size
+		hi := lo + r.size
+		if hi > r.examples {
+			hi = r.examples
+		}
+		return f(lo, hi, p)
+	}
+	if r.workers <= 1 {
+		for c := range r.partials {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if err := chunk(c); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		// Workers stop taking chunks after the first error or once the context is done.
+		g, gctx := errgroup.WithContext(ctx)
+		var next int64
+		for w := 0; w < r.workers; w++ {
+			g.Go(func() error {
+				for {
+					c := int(atomic.AddInt64(&next, 1) - 1)
+					if c >= len(r.partials) || gctx.Err() != nil {
+						return nil
+					}
+					if err := chunk(c); err != nil {
+						return err
+					}
+				}
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	grad := make([]float64, r.n)
+	for _, p := range r.partials {
+		for j, v := range p {
+			grad[j] += v
+		}
+	}
+	return grad, nil
+}
+
+// hyphothesisGradient returns a function adding the gradient of a hyphothesis driven cost function, sum((h(x)-y)*Xj),
+// over training examples [lo, hi) to grad. The residual of every training example is calculated once.
+func hyphothesisGradient(h Hyphothesis, x *matrix.Dense, y []float64, coeffs []float64) func(lo, hi int, grad []float64) error {
+	return func(lo, hi int, grad []float64) error {
+		for i := lo; i < hi; i++ {
+			xi := x.Row(i)
+			hr, err := h(xi, coeffs)
+			if err != nil {
+				return err
+			}
+			r := hr - y[i]
+			for j, xj := range xi {
+				grad[j] += r * xj
+			}
+		}
+		return nil
+	}
+}
+
+// explicitGradient returns a function adding the gradient g over training examples [lo, hi) to grad.
+func explicitGradient(g GradientFunc, x [][]float64, y []float64, coeffs []float64) func(lo, hi int, grad []float64) error {
+	return func(lo, hi int, grad []float64) error {
+		pg, err := g(x[lo:hi], y[lo:hi], coeffs)
+		if err != nil {
+			return err
+		}
+		if len(pg) != len(grad) {
+			return regression.ErrInvalidFeatureVector
+		}
+		for j, v := range pg {
+			grad[j] += v
+		}
+		return nil
+	}
+}
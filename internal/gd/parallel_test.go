@@ -0,0 +1,231 @@
+package gd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
+	"github.com/erni27/regression/options"
+	"golang.org/x/sync/errgroup"
+)
+
+// randomTrainingSet returns m training examples with n features (and the dummy one) drawn from [-1, 1)
+// and noisy targets of a linear function.
+func randomTrainingSet(rnd *rand.Rand, m, n int) ([][]float64, []float64) {
+	data := make([]float64, m*(n+1))
+	x := make([][]float64, m)
+	y := make([]float64, m)
+	for i := range x {
+		x[i] = data[i*(n+1) : (i+1)*(n+1)]
+		x[i][0] = 1
+		for j := 1; j <= n; j++ {
+			x[i][j] = 2*rnd.Float64() - 1
+			y[i] += float64(j) * x[i][j]
+		}
+		y[i] += rnd.NormFloat64()
+	}
+	return x, y
+}
+
+func TestNewReducer(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+		m       int
+		chunks  int
+		size    int
+	}{
+		{name: "small training set", workers: 4, m: 97, chunks: 1, size: minChunkSize},
+		{name: "min chunk size", workers: 4, m: 1000, chunks: 4, size: minChunkSize},
+		{name: "max chunks", workers: 4, m: 100000, chunks: maxChunks, size: 1563},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newReducer(options.Options{Workers: tt.workers}, tt.m, 3)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if len(r.partials) != tt.chunks || r.size != tt.size {
+				t.Errorf("got %d chunks of size %d, want %d chunks of size %d", len(r.partials), r.size, tt.chunks, tt.size)
+			}
+			want := tt.workers
+			if tt.chunks < want {
+				want = tt.chunks
+			}
+			if r.workers != want {
+				t.Errorf("got %d workers, want %d", r.workers, want)
+			}
+		})
+	}
+	if _, err := newReducer(options.Options{Workers: -1}, 10, 3); err != regression.ErrInvalidWorkers {
+		t.Errorf("want %v, got %v", regression.ErrInvalidWorkers, err)
+	}
+}
+
+func TestTakeStep_Workers(t *testing.T) {
+	// Exercise the parallel reduction even on a single CPU.
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	x, y := randomTrainingSet(rand.New(rand.NewSource(1)), 20000, 10)
+	var want []float64
+	for _, w := range []int{1, 2, 3, 8, 0} {
+		s, err := NewStepper(options.WithIterativeConvergence(0.1, options.Batch, 0).WithWorkers(w), hyphoStub, x, y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 5; i++ {
+			if err := s.TakeStep(context.Background()); err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+		}
+		got := s.CurrentCoefficients()
+		if want == nil {
+			want = got
+			continue
+		}
+		// The reduction is deterministic, so coefficients must be bitwise equal.
+		if !regressiontest.AreFloatSlicesEqual(got, want, -1) {
+			t.Fatalf("got %v with %d workers, want %v", got, w, want)
+		}
+	}
+}
+
+func TestTakeStep_WorkersHyphothesisError(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	x, y := randomTrainingSet(rand.New(rand.NewSource(1)), 5000, 2)
+	errHypho := errors.New("hyphothesis error")
+	h := func(x []float64, coeffs []float64) (float64, error) {
+		if x[1] > 0.99 {
+			return 0, errHypho
+		}
+		return hyphoStub(x, coeffs)
+	}
+	s, err := NewStepper(options.WithIterativeConvergence(0.1, options.Batch, 0), h, x, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.TakeStep(context.Background()); err != errHypho {
+		t.Fatalf("want %v, got %v", errHypho, err)
+	}
+}
+
+func TestRun_InvalidWorkers(t *testing.T) {
+	x := [][]float64{{1, 1}, {1, 2}}
+	y := []float64{1, 2}
+	for _, gdv := range []options.GradientDescentVariant{options.Batch, options.LBFGS} {
+		o := options.WithIterativeConvergence(0.1, gdv, 10).WithWorkers(-1)
+		if _, err := gd.Run(context.Background(), o, x, y); err != regression.ErrInvalidWorkers {
+			t.Errorf("%v: want %v, got %v", gdv, regression.ErrInvalidWorkers, err)
+		}
+	}
+}
+
+// legacyBatchStep takes a step of the former batch stepper, which computed every partial derivative in its own goroutine
+// and evaluated the hyphothesis for every pair of a coefficient and a training example. It's a benchmark baseline.
+func legacyBatchStep(h Hyphothesis, x [][]float64, y []float64, coeffs []float64, lr float64) ([]float64, error) {
+	grad := make([]float64, len(coeffs))
+	var g errgroup.Group
+	for j := 0; j < len(coeffs); j++ {
+		j := j
+		g.Go(func() error {
+			for i := 0; i < len(x); i++ {
+				hr, err := h(x[i], coeffs)
+				if err != nil {
+					return err
+				}
+				grad[j] += (hr - y[i]) * x[i][j]
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	nc := make([]float64, len(coeffs))
+	for j := range nc {
+		nc[j] = coeffs[j] - lr*grad[j]/float64(len(x))
+	}
+	return nc, nil
+}
+
+func benchmarkBatchStep(b *testing.B, m, n int) {
+	x, y := randomTrainingSet(rand.New(rand.NewSource(1)), m, n)
+	b.Run("legacy", func(b *testing.B) {
+		coeffs := make([]float64, n+1)
+		for i := 0; i < b.N; i++ {
+			var err error
+			if coeffs, err = legacyBatchStep(hyphoStub, x, y, coeffs, 0.01); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	// Zero workers means GOMAXPROCS.
+	for _, w := range []int{1, 0} {
+		b.Run(fmt.Sprintf("workers=%d", w), func(b *testing.B) {
+			s, err := NewStepper(options.WithIterativeConvergence(0.01, options.Batch, 0).WithWorkers(w), hyphoStub, x, y)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := s.TakeStep(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkBatchStep_Small(b *testing.B) {
+	benchmarkBatchStep(b, 1000, 10)
+}
+
+func BenchmarkBatchStep_Large(b *testing.B) {
+	benchmarkBatchStep(b, 100000, 50)
+}
+
+func TestRun_ContextCanceledDuringStep(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	x, y := randomTrainingSet(rand.New(rand.NewSource(1)), 20000, 5)
+	tests := []struct {
+		name string
+		gdv  options.GradientDescentVariant
+	}{
+		{name: "batch", gdv: options.Batch},
+		{name: "l-bfgs", gdv: options.LBFGS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var calls int64
+			// The context is canceled in the middle of the first step.
+			h := func(x []float64, coeffs []float64) (float64, error) {
+				if atomic.AddInt64(&calls, 1) == 5000 {
+					cancel()
+				}
+				return hyphoStub(x, coeffs)
+			}
+			g := New(h, costStub)
+			o := options.WithIterativeConvergence(0.1, tt.gdv, 1000).WithWorkers(4)
+			if _, err := g.Run(ctx, o, x, y); err != context.Canceled {
+				t.Fatalf("want %v, got %v", context.Canceled, err)
+			}
+			done := atomic.LoadInt64(&calls)
+			// The step is abandoned within a chunk of training examples per worker.
+			if done >= int64(len(x)) {
+				t.Errorf("got %d hyphothesis calls, want less than a step", done)
+			}
+			time.Sleep(10 * time.Millisecond)
+			if got := atomic.LoadInt64(&calls); got != done {
+				t.Errorf("got %d hyphothesis calls after Run returned", got-done)
+			}
+		})
+	}
+}
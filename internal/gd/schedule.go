@@ -0,0 +1,62 @@
+package gd
+
+import (
+	"math"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/options"
+)
+
+// A Schedule returns the learning rate of the t-th iteration, counted from zero.
+// An iteration is a single step, or a whole epoch for an EpochStepper.
+type Schedule func(t int) float64
+
+// NewSchedule returns a new learning rate schedule chosen in options.
+// If unsupported LearningRateSchedule is passed or its parameters are invalid, an error is returned.
+func NewSchedule(o options.Options) (Schedule, error) {
+	lr, r, k := o.LearningRate, o.DecayRate, float64(o.DecaySteps)
+	var s Schedule
+	switch o.LearningRateSchedule {
+	case options.Constant:
+		s = func(int) float64 { return lr }
+	case options.StepDecay:
+		if k < 1 || !(r > 0 && r <= 1) {
+			return nil, regression.ErrInvalidLearningRateSchedule
+		}
+		s = func(t int) float64 { return lr * math.Pow(r, math.Floor(float64(t)/k)) }
+	case options.ExponentialDecay:
+		if k < 1 || !(r > 0 && r <= 1) {
+			return nil, regression.ErrInvalidLearningRateSchedule
+		}
+		s = func(t int) float64 { return lr * math.Pow(r, float64(t)/k) }
+	case options.InverseTimeDecay:
+		if k < 1 || !(r >= 0 && !math.IsInf(r, 1)) {
+			return nil, regression.ErrInvalidLearningRateSchedule
+		}
+		s = func(t int) float64 { return lr / (1 + r*float64(t)/k) }
+	case options.CosineAnnealing:
+		if k < 1 || !(r >= 0 && r <= 1) {
+			return nil, regression.ErrInvalidLearningRateSchedule
+		}
+		s = func(t int) float64 {
+			p := math.Min(float64(t), k) / k
+			return lr * (r + (1-r)*(1+math.Cos(math.Pi*p))/2)
+		}
+	default:
+		return nil, regression.ErrUnsupportedLearningRateSchedule
+	}
+	if o.WarmupSteps < 0 {
+		return nil, regression.ErrInvalidLearningRateSchedule
+	}
+	if w := o.WarmupSteps; w > 0 {
+		decay := s
+		// The warmup increases the learning rate linearly, the decay starts after it.
+		s = func(t int) float64 {
+			if t < w {
+				return decay(0) * float64(t+1) / float64(w)
+			}
+			return decay(t - w)
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,64 @@
+package gd
+
+import (
+	"math"
+	"testing"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
+	"github.com/erni27/regression/options"
+)
+
+func TestNewSchedule(t *testing.T) {
+	o := options.Options{LearningRate: 0.1}
+	tests := []struct {
+		name string
+		o    options.Options
+		want map[int]float64
+	}{
+		{name: "constant", o: o, want: map[int]float64{0: 0.1, 1000: 0.1}},
+		{name: "step decay", o: o.WithStepDecay(0.5, 10), want: map[int]float64{0: 0.1, 9: 0.1, 10: 0.05, 25: 0.025}},
+		{name: "exponential decay", o: o.WithExponentialDecay(0.5, 10), want: map[int]float64{0: 0.1, 5: 0.0707107, 10: 0.05, 20: 0.025}},
+		{name: "inverse-time decay", o: o.WithInverseTimeDecay(0.5, 10), want: map[int]float64{0: 0.1, 10: 0.0666667, 20: 0.05}},
+		{name: "cosine annealing", o: o.WithCosineAnnealing(10, 0.1), want: map[int]float64{0: 0.1, 5: 0.055, 10: 0.01, 20: 0.01}},
+		{name: "warmup", o: o.WithWarmup(4), want: map[int]float64{0: 0.025, 1: 0.05, 3: 0.1, 100: 0.1}},
+		{name: "warmup with step decay", o: o.WithStepDecay(0.5, 10).WithWarmup(4), want: map[int]float64{0: 0.025, 4: 0.1, 13: 0.1, 14: 0.05}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSchedule(tt.o)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			for it, want := range tt.want {
+				if got := s(it); !regressiontest.AreFloatEqual(got, want, 6) {
+					t.Errorf("want learning rate %v for iteration %d, got %v", want, it, got)
+				}
+			}
+		})
+	}
+}
+
+func TestNewSchedule_Invalid(t *testing.T) {
+	o := options.Options{LearningRate: 0.1}
+	tests := []struct {
+		name string
+		o    options.Options
+		err  error
+	}{
+		{name: "step decay zero steps", o: o.WithStepDecay(0.5, 0), err: regression.ErrInvalidLearningRateSchedule},
+		{name: "step decay zero rate", o: o.WithStepDecay(0, 10), err: regression.ErrInvalidLearningRateSchedule},
+		{name: "exponential decay rate greater than one", o: o.WithExponentialDecay(1.5, 10), err: regression.ErrInvalidLearningRateSchedule},
+		{name: "inverse-time decay negative rate", o: o.WithInverseTimeDecay(-1, 10), err: regression.ErrInvalidLearningRateSchedule},
+		{name: "cosine annealing nan ratio", o: o.WithCosineAnnealing(10, math.NaN()), err: regression.ErrInvalidLearningRateSchedule},
+		{name: "negative warmup", o: o.WithWarmup(-1), err: regression.ErrInvalidLearningRateSchedule},
+		{name: "unsupported", o: options.Options{LearningRateSchedule: -1}, err: regression.ErrUnsupportedLearningRateSchedule},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSchedule(tt.o); err != tt.err {
+				t.Fatalf("want error %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
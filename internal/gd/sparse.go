@@ -0,0 +1,392 @@
+package gd
+
+import (
+	"context"
+	"math"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/options"
+)
+
+// SparseHyphothesis is a function template for a hyphothesis function of sparse feature vectors.
+// Feature vectors don't include the dummy feature, the first coefficient is the intercept.
+type SparseHyphothesis func(x regression.SparseVector, coeffs []float64) (float64, error)
+
+// SparseCostFunc is a function template for a cost function of a sparse design matrix.
+// The design matrix doesn't include the dummy feature, the first coefficient is the intercept.
+type SparseCostFunc func(x regression.SparseMatrix, y []float64, coeffs []float64) (float64, error)
+
+// SparseGradientDescent holds the functions needed by gradient descent algorithm on a sparse design matrix.
+//
+// The gradient is derived from the hyphothesis function, like in a hyphothesis driven GradientDescent.
+// Computing it takes time proportional to the number of non-zero features, so the design matrix is never materialized.
+type SparseGradientDescent struct {
+	h SparseHyphothesis
+	c SparseCostFunc
+}
+
+// NewSparse creates new gradient descent for a sparse design matrix.
+func NewSparse(h SparseHyphothesis, c SparseCostFunc) SparseGradientDescent {
+	return SparseGradientDescent{h: h, c: c}
+}
+
+// Run runs the gradient descent algorithm. It returns x.Cols+1 coefficients, starting with the intercept,
+// with the training report. Early stopping isn't supported, if options enable it, regression.ErrInvalidEarlyStopping
+// is returned.
+func (g SparseGradientDescent) Run(ctx context.Context, o options.Options, x regression.SparseMatrix, y []float64) (Result, error) {
+	if !options.IsValidRegularization(o.Regularization) {
+		return Result{}, regression.ErrInvalidRegularization
+	}
+	if o.EarlyStopping != nil {
+		return Result{}, regression.ErrInvalidEarlyStopping
+	}
+	n := x.Cols + 1
+	var gds Stepper
+	var err error
+	switch o.GradientDescentVariant {
+	case options.LBFGS, options.ConjugateGradient:
+		var obj ObjectiveFunc
+		if obj, err = g.objective(o, x, y); err != nil {
+			return Result{}, err
+		}
+		gds, err = newLineSearchStepper(o, obj, nil, y, n)
+	default:
+		gds, err = newSparseStepper(o, g.h, x, y)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	cv, err := NewConverger(o, g.penalize(x, o.Regularization))
+	if err != nil {
+		return Result{}, err
+	}
+	return cv.Converge(ctx, gds)
+}
+
+// penalize returns the cost function with the L2 regularization term for a sparse design matrix x.
+// The returned function ignores its dense design matrix argument.
+//
+// The regularization term equals l2/(2m)*sum(Oj^2), where m is the number of training examples.
+// The intercept is excluded from the sum.
+func (g SparseGradientDescent) penalize(x regression.SparseMatrix, l2 float64) CostFunc {
+	return func(_ [][]float64, y []float64, coeffs []float64) (float64, error) {
+		v, err := g.c(x, y, coeffs)
+		if err != nil || l2 == 0 {
+			return v, err
+		}
+		var p float64
+		for _, coeff := range coeffs[1:] {
+			p += coeff * coeff
+		}
+		return v + l2*p/float64(2*x.Rows), nil
+	}
+}
+
+// objective returns the L2 regularized cost function with its gradient for a sparse design matrix x and a target vector y.
+// See GradientDescent.objective.
+func (g SparseGradientDescent) objective(o options.Options, x regression.SparseMatrix, y []float64) (ObjectiveFunc, error) {
+	l2 := o.Regularization
+	c := g.penalize(x, l2)
+	m := float64(x.Rows)
+	r, err := newReducer(o, x.Rows, x.Cols+1)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, coeffs []float64) (float64, []float64, error) {
+		f, err := c(nil, y, coeffs)
+		if err != nil {
+			return 0, nil, err
+		}
+		if len(coeffs) != r.n {
+			return 0, nil, regression.ErrInvalidFeatureVector
+		}
+		grad, err := r.sum(ctx, sparseGradient(g.h, x, y, coeffs))
+		if err != nil {
+			return 0, nil, err
+		}
+		for j := range grad {
+			grad[j] /= m
+			if j != 0 {
+				grad[j] += l2 * coeffs[j] / m
+			}
+		}
+		return f, grad, nil
+	}, nil
+}
+
+// sparseGradient returns a function adding the gradient over training examples [lo, hi) of a sparse design matrix x to grad.
+func sparseGradient(h SparseHyphothesis, x regression.SparseMatrix, y []float64, coeffs []float64) func(lo, hi int, grad []float64) error {
+	return func(lo, hi int, grad []float64) error {
+		for i := lo; i < hi; i++ {
+			if err := accumulate(h, grad, x.Row(i), y[i], coeffs); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// accumulate adds the gradient of a single training example (x, y), (h(x)-y)*X, to grad.
+// Only the intercept and coefficients of non-zero features change.
+func accumulate(h SparseHyphothesis, grad []float64, x regression.SparseVector, y float64, coeffs []float64) error {
+	hr, err := h(x, coeffs)
+	if err != nil {
+		return err
+	}
+	r := hr - y
+	grad[0] += r
+	for k, j := range x.Indices {
+		grad[j+1] += r * x.Values[k]
+	}
+	return nil
+}
+
+// newSparseStepper returns a new stepper on a sparse design matrix for the gradient descent variant chosen in options.
+// If unsupported GradientDescentVariant is passed, an error is returned.
+func newSparseStepper(o options.Options, h SparseHyphothesis, x regression.SparseMatrix, y []float64) (Stepper, error) {
+	n := x.Cols + 1
+	bs, err := newBase(o, y, x.Rows, n, n)
+	if err != nil {
+		return nil, err
+	}
+	sb := sparseBaseStepper{baseStepper: bs, h: h, sx: x}
+	var gds Stepper
+	switch o.GradientDescentVariant {
+	case options.Batch:
+		r, err := newReducer(o, x.Rows, n)
+		if err != nil {
+			return nil, err
+		}
+		gds = &sparseBatchStepper{sb, r}
+	case options.Stochastic:
+		sb.lazy = newLazy(bs.opt, bs.coeffs)
+		gds = &sparseStochasticStepper{sb, 0}
+	case options.MiniBatch:
+		b, err := newBatcher(o, x.Rows)
+		if err != nil {
+			return nil, err
+		}
+		sb.lazy = newLazy(bs.opt, bs.coeffs)
+		gds = &sparseMiniBatchStepper{sb, b}
+	default:
+		return nil, regression.ErrUnsupportedGradientDescentVariant
+	}
+	return gds, nil
+}
+
+// sparseBaseStepper is a prototype for concrete steppers on a sparse design matrix. It should be embedded.
+// Its X method returns nil, since there is no dense design matrix.
+type sparseBaseStepper struct {
+	baseStepper
+	h  SparseHyphothesis
+	sx regression.SparseMatrix
+	// lazy is the state of lazy updates, nil if steps update every coefficient.
+	lazy *lazy
+}
+
+// lazy holds the state of steps on a few training examples with the vanilla optimizer, which update only
+// the intercept and coefficients of their non-zero features, so that a step takes time proportional to the number
+// of non-zero features rather than to the number of coefficients.
+//
+// The L2 regularization shrinks every coefficient by the factor 1-lr*l2*examples/m in each step. Shrinking is deferred:
+// scale is the sum of logarithms of factors of all steps and seen[j] is its value when the j-th coefficient was last
+// brought up to date, so the coefficient misses the factor e^(scale-seen[j]).
+type lazy struct {
+	scale float64
+	seen  []float64
+	// sq is the sum of squares of up to date coefficients except the intercept, needed by the gradient norm.
+	sq float64
+	// g is the gradient of the current step and touched are indices of coefficients it may change, marked in mark.
+	g       []float64
+	mark    []bool
+	touched []int
+}
+
+// newLazy returns the state of lazy updates of coefficients coeffs if the optimizer o supports them, nil otherwise.
+// Optimizers other than vanilla keep state of every coefficient, so they update all of them in each step.
+func newLazy(o Optimizer, coeffs []float64) *lazy {
+	if _, ok := o.(vanilla); !ok {
+		return nil
+	}
+	l := &lazy{seen: make([]float64, len(coeffs)), g: make([]float64, len(coeffs)), mark: make([]bool, len(coeffs))}
+	l.reset(coeffs)
+	return l
+}
+
+// reset resets the state for up to date coefficients coeffs.
+func (l *lazy) reset(coeffs []float64) {
+	l.scale = 0
+	for j := range l.seen {
+		l.seen[j] = 0
+	}
+	l.sq = 0
+	for _, c := range coeffs[1:] {
+		l.sq += c * c
+	}
+}
+
+// clear clears the gradient and touched coefficients of the current step.
+func (l *lazy) clear() {
+	for _, j := range l.touched {
+		l.g[j], l.mark[j] = 0, false
+	}
+	l.g[0] = 0
+	l.touched = l.touched[:0]
+}
+
+// CurrentCoefficients returns a copy of coefficients brought up to date, with lazy updates.
+func (s *sparseBaseStepper) CurrentCoefficients() []float64 {
+	if s.lazy == nil {
+		return s.coeffs
+	}
+	coeffs := make([]float64, len(s.coeffs))
+	coeffs[0] = s.coeffs[0]
+	for j := 1; j < len(coeffs); j++ {
+		coeffs[j] = s.coeffs[j] * math.Exp(s.lazy.scale-s.lazy.seen[j])
+	}
+	return coeffs
+}
+
+// take takes a step on training examples rows.
+func (s *sparseBaseStepper) take(rows ...int) error {
+	if s.lazy != nil {
+		return s.update(rows)
+	}
+	grad := make([]float64, len(s.coeffs))
+	for _, i := range rows {
+		if err := accumulate(s.h, grad, s.sx.Row(i), s.y[i], s.coeffs); err != nil {
+			return err
+		}
+	}
+	nc, err := s.descend(grad, len(rows))
+	if err != nil {
+		return err
+	}
+	s.coeffs = nc
+	return nil
+}
+
+// update takes a step on training examples rows with lazy updates. It brings touched coefficients up to date,
+// then changes them like descend does. If the regularization shrinks coefficients by a non-positive factor,
+// all of them are brought up to date and the step is taken by descend.
+func (s *sparseBaseStepper) update(rows []int) error {
+	l := s.lazy
+	defer l.clear()
+	for _, i := range rows {
+		for _, j := range s.sx.Row(i).Indices {
+			if col := j + 1; !l.mark[col] {
+				l.mark[col] = true
+				l.touched = append(l.touched, col)
+				s.coeffs[col] *= math.Exp(l.scale - l.seen[col])
+				l.seen[col] = l.scale
+			}
+		}
+	}
+	for _, i := range rows {
+		if err := accumulate(s.h, l.g, s.sx.Row(i), s.y[i], s.coeffs); err != nil {
+			return err
+		}
+	}
+	examples := len(rows)
+	k := s.l2 * float64(examples) / float64(s.m)
+	s.rate = s.lr(s.t)
+	shrink := 1 - s.rate*k
+	if shrink <= 0 {
+		s.coeffs = s.CurrentCoefficients()
+		nc, err := s.descend(l.g, examples)
+		if err != nil {
+			return err
+		}
+		s.coeffs = nc
+		l.reset(nc)
+		return nil
+	}
+	// New values of touched coefficients replace their gradient until all of them are known to be finite.
+	ss := l.g[0] * l.g[0]
+	rest := l.sq
+	for _, j := range l.touched {
+		c := s.coeffs[j]
+		pg := l.g[j] + k*c
+		ss += pg * pg
+		rest -= c * c
+		l.g[j] = shrink*c - s.rate*l.g[j]
+		if math.IsNaN(l.g[j]) || math.IsInf(l.g[j], 0) {
+			return regression.ErrCannotConverge
+		}
+	}
+	intercept := s.coeffs[0] - s.rate*l.g[0]
+	if math.IsNaN(intercept) || math.IsInf(intercept, 0) {
+		return regression.ErrCannotConverge
+	}
+	// Rounding errors may make the sum of squares of untouched coefficients slightly negative.
+	if rest < 0 {
+		rest = 0
+	}
+	s.gnorm = math.Sqrt(ss+k*k*rest) / float64(examples)
+	s.coeffs[0] = intercept
+	l.scale += math.Log(shrink)
+	l.sq = shrink * shrink * rest
+	for _, j := range l.touched {
+		s.coeffs[j] = l.g[j]
+		l.seen[j] = l.scale
+		l.sq += s.coeffs[j] * s.coeffs[j]
+	}
+	return nil
+}
+
+// sparseBatchStepper takes steps according to the batch gradient descent variant on a sparse design matrix.
+// The gradient is summed over chunks of training examples in parallel.
+type sparseBatchStepper struct {
+	sparseBaseStepper
+	*reducer
+}
+
+func (s *sparseBatchStepper) TakeStep(ctx context.Context) error {
+	grad, err := s.sum(ctx, sparseGradient(s.h, s.sx, s.y, s.coeffs))
+	if err != nil {
+		return err
+	}
+	nc, err := s.descend(grad, s.sx.Rows)
+	if err != nil {
+		return err
+	}
+	s.coeffs = nc
+	s.t++
+	return nil
+}
+
+// sparseStochasticStepper takes steps according to the stochastic gradient descent variant on a sparse design matrix.
+// With the vanilla optimizer, it updates coefficients lazily.
+type sparseStochasticStepper struct {
+	sparseBaseStepper
+	i int
+}
+
+func (s *sparseStochasticStepper) TakeStep(ctx context.Context) error {
+	if err := s.take(s.i); err != nil {
+		return err
+	}
+	s.i++
+	if s.i == s.sx.Rows {
+		s.i = 0
+	}
+	s.t++
+	return nil
+}
+
+// sparseMiniBatchStepper takes steps according to the mini-batch gradient descent variant on a sparse design matrix.
+// With the vanilla optimizer, it updates coefficients lazily.
+type sparseMiniBatchStepper struct {
+	sparseBaseStepper
+	*batcher
+}
+
+func (s *sparseMiniBatchStepper) TakeStep(ctx context.Context) error {
+	if err := s.take(s.next()...); err != nil {
+		return err
+	}
+	if s.EpochDone() {
+		s.t++
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package gd
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
+	"github.com/erni27/regression/internal/ts"
+	"github.com/erni27/regression/options"
+)
+
+func sparseCostStub(x regression.SparseMatrix, y []float64, coeffs []float64) (float64, error) {
+	var c float64
+	for i := 0; i < x.Rows; i++ {
+		hr, err := ts.Dot(x.Row(i), coeffs)
+		if err != nil {
+			return 0, err
+		}
+		c += math.Pow(hr-y[i], 2)
+	}
+	return c / float64((2 * x.Rows)), nil
+}
+
+func TestSparseRun(t *testing.T) {
+	x := [][]float64{
+		{0, 2, 0},
+		{3, 0, 4},
+		{5, 6, 0},
+		{0, 0, 1},
+	}
+	y := []float64{3, 7, 11, 2}
+	s := regressiontest.ToSparse(regression.TrainingSet{X: x, Y: y})
+	tests := []struct {
+		name string
+		opt  options.Options
+	}{
+		{name: "batch iterative", opt: options.WithIterativeConvergence(0.01, options.Batch, 100)},
+		{name: "batch automatic", opt: options.WithAutomaticConvergence(0.01, options.Batch, 0.01)},
+		{name: "stochastic iterative", opt: options.WithIterativeConvergence(0.01, options.Stochastic, 100)},
+		{name: "mini-batch iterative", opt: options.WithIterativeConvergence(0.01, options.Batch, 50).WithMiniBatch(3, 1)},
+		{name: "stochastic regularized", opt: options.WithIterativeConvergence(0.01, options.Stochastic, 100).WithRegularization(1)},
+		{name: "stochastic automatic regularized", opt: options.WithAutomaticConvergence(0.01, options.Stochastic, 0.0001).WithRegularization(0.5)},
+		{name: "mini-batch regularized", opt: options.WithIterativeConvergence(0.01, options.Batch, 50).WithMiniBatch(3, 1).WithRegularization(1)},
+		{name: "stochastic momentum regularized", opt: options.WithIterativeConvergence(0.01, options.Stochastic, 100).WithMomentum(0.5).WithRegularization(1)},
+		{name: "batch adam regularized", opt: options.WithIterativeConvergence(0.01, options.Batch, 100).WithAdam(0.9, 0.999, 1e-8).WithRegularization(1)},
+		{name: "l-bfgs iterative", opt: options.WithIterativeConvergence(0, options.LBFGS, 10)},
+		{name: "conjugate gradient regularized", opt: options.WithIterativeConvergence(0, options.ConjugateGradient, 10).WithRegularization(1)},
+	}
+	ctx := context.Background()
+	sgd := NewSparse(ts.Dot, sparseCostStub)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The sparse gradient descent takes the same steps as the dense one with the dummy feature.
+			want, err := gd.Run(ctx, tt.opt, ts.AddDummies(x), y)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			got, err := sgd.Run(ctx, tt.opt, s.X, s.Y)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if !regressiontest.AreFloatSlicesEqual(got.Coefficients, want.Coefficients, 9) {
+				t.Fatalf("got %v, want %v", got.Coefficients, want.Coefficients)
+			}
+		})
+	}
+}
+
+func TestSparseRun_InvalidFeatureVector(t *testing.T) {
+	s := regressiontest.ToSparse(regression.TrainingSet{X: [][]float64{{1, 2}, {3, 4}}, Y: []float64{1, 2}})
+	h := func(x regression.SparseVector, coeffs []float64) (float64, error) {
+		return 0, regression.ErrInvalidFeatureVector
+	}
+	o := options.WithIterativeConvergence(0.01, options.Batch, 10)
+	if _, err := NewSparse(h, sparseCostStub).Run(context.Background(), o, s.X, s.Y); !errors.Is(err, regression.ErrInvalidFeatureVector) {
+		t.Fatalf("want %v, got %v", regression.ErrInvalidFeatureVector, err)
+	}
+}
+
+func TestSparseRun_EarlyStopping(t *testing.T) {
+	s := regressiontest.ToSparse(regression.TrainingSet{X: [][]float64{{2}, {3}, {4}}, Y: []float64{2, 3, 4}})
+	o := options.WithIterativeConvergence(0.1, options.Batch, 10).WithEarlyStopping(options.WithValidationSplit(0.2, 1, 3))
+	if _, err := NewSparse(ts.Dot, sparseCostStub).Run(context.Background(), o, s.X, s.Y); err != regression.ErrInvalidEarlyStopping {
+		t.Fatalf("want %v, got %v", regression.ErrInvalidEarlyStopping, err)
+	}
+}
+
+func TestSparseStochasticStepper_Lazy(t *testing.T) {
+	// A wide training set, every example has a single non-zero feature.
+	x := make([][]float64, 4)
+	for i := range x {
+		x[i] = make([]float64, 1000)
+		x[i][i] = 1
+	}
+	s := regressiontest.ToSparse(regression.TrainingSet{X: x, Y: []float64{1, 2, 3, 4}})
+	o := options.WithIterativeConvergence(0.1, options.Stochastic, 10).WithRegularization(1)
+	gds, err := newSparseStepper(o, ts.Dot, s.X, s.Y)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	st := gds.(*sparseStochasticStepper)
+	for j := range st.coeffs {
+		st.coeffs[j] = 1
+	}
+	st.lazy.reset(st.coeffs)
+	for k := 0; k < 2; k++ {
+		if err := st.TakeStep(context.Background()); err != nil {
+			t.Fatalf("want nil, got error %v", err)
+		}
+	}
+	// Untouched coefficients are stored as they were, but they're shrunk by 1-0.1*1/4 in each step.
+	if st.coeffs[3] != 1 || st.coeffs[1000] != 1 {
+		t.Errorf("got stored untouched coefficients %v and %v, want 1", st.coeffs[3], st.coeffs[1000])
+	}
+	coeffs := st.CurrentCoefficients()
+	if want := 0.975 * 0.975; !regressiontest.AreFloatEqual(coeffs[1000], want, 12) {
+		t.Errorf("got untouched coefficient %v, want %v", coeffs[1000], want)
+	}
+	// The first feature was touched by the first step only: h=2, so O1=0.975*1-0.1*(2-1).
+	if want := (0.975 - 0.1) * 0.975; !regressiontest.AreFloatEqual(coeffs[1], want, 12) {
+		t.Errorf("got coefficient %v, want %v", coeffs[1], want)
+	}
+	// The gradient norm covers the penalty of every coefficient, touched or not. In the second step h=0.9+0.975,
+	// so the residual is -0.125.
+	want := math.Sqrt(2*0.125*0.125 - 2*0.125*0.25*0.975 + 0.25*0.25*(0.875*0.875+999*0.975*0.975))
+	if got := st.GradientNorm(); !regressiontest.AreFloatEqual(got, want, 9) {
+		t.Errorf("got gradient norm %v, want %v", got, want)
+	}
+}
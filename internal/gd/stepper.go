@@ -1,32 +1,65 @@
 package gd
 
 import (
+	"context"
 	"math"
+	"math/rand"
 
 	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/matrix"
 	"github.com/erni27/regression/options"
-	"golang.org/x/sync/errgroup"
 )
 
 // A Stepper wraps logic around taking steps (calculating new coefficients' values).
 type Stepper interface {
-	// TakeStep takes single step towards cost function minimum.
-	TakeStep() error
+	// TakeStep takes single step towards cost function minimum. A step over the whole training set
+	// checks the context between chunks of training examples.
+	TakeStep(context.Context) error
 	// CurrentCoefficients returns current coefficients calculated by stepper.
 	CurrentCoefficients() []float64
+	// GradientNorm returns the Euclidean norm of the cost function gradient of the last step,
+	// averaged over training examples the step was taken on.
+	GradientNorm() float64
+	// LearningRate returns the learning rate of the last step.
+	LearningRate() float64
 	// X returns design matrix used in calculations.
 	X() [][]float64
 	// Y returns target vector used in calculations.
 	Y() []float64
 }
 
-func NewStepper(gdv options.GradientDescentVariant, h Hyphothesis, x [][]float64, y []float64, lr float64) (Stepper, error) {
+// An EpochStepper is a stepper which takes many steps per epoch (a full pass over a training set).
+// Convergers account for its progress in epochs rather than in single steps.
+type EpochStepper interface {
+	Stepper
+	// EpochDone reports whether the last step completed an epoch.
+	EpochDone() bool
+}
+
+// NewStepper returns a new stepper for the gradient descent variant chosen in options.
+// If unsupported GradientDescentVariant is passed, an error is returned.
+func NewStepper(o options.Options, h Hyphothesis, x [][]float64, y []float64) (Stepper, error) {
+	bs, err := newBaseStepper(o, x, y, len(x[0]))
+	if err != nil {
+		return nil, err
+	}
+	bs.hypho = h
 	var gds Stepper
-	switch gdv {
+	switch o.GradientDescentVariant {
 	case options.Batch:
-		gds = &batchStepper{baseStepper{hypho: h, x: x, y: y, lr: lr, coeffs: make([]float64, len(x[0]))}}
+		r, err := newReducer(o, len(x), len(x[0]))
+		if err != nil {
+			return nil, err
+		}
+		gds = &batchStepper{bs, r}
 	case options.Stochastic:
-		gds = &stochasticStepper{baseStepper{hypho: h, x: x, y: y, lr: lr, coeffs: make([]float64, len(x[0]))}, 0}
+		gds = &stochasticStepper{bs, 0}
+	case options.MiniBatch:
+		b, err := newBatcher(o, len(x))
+		if err != nil {
+			return nil, err
+		}
+		gds = &miniBatchStepper{bs, b}
 	default:
 		return nil, regression.ErrUnsupportedGradientDescentVariant
 	}
@@ -35,17 +68,74 @@ func NewStepper(gdv options.GradientDescentVariant, h Hyphothesis, x [][]float64
 
 // baseStepper is a prototype for concrete steppers. It should be embedded.
 type baseStepper struct {
-	hypho  Hyphothesis
-	x      [][]float64
-	y      []float64
-	lr     float64
+	hypho Hyphothesis
+	// x is the design matrix and d is its dense form, which shares storage with x if rows of x are laid out
+	// contiguously (like rows returned by ts.AddDummies). Hyphothesis driven steppers iterate over d.
+	x   [][]float64
+	d   *matrix.Dense
+	y   []float64
+	opt Optimizer
+	lr  Schedule
+	// t is the current iteration, either a step or an epoch.
+	t  int
+	l2 float64
+	// m is the number of training examples and width is the length of a feature vector
+	// (including the dummy feature), every width-th coefficient is an intercept.
+	m      int
+	width  int
 	coeffs []float64
+	// gnorm is the norm of the averaged gradient of the last step and rate is its learning rate.
+	gnorm float64
+	rate  float64
+}
+
+func newBaseStepper(o options.Options, x [][]float64, y []float64, n int) (baseStepper, error) {
+	bs, err := newBase(o, y, len(x), len(x[0]), n)
+	if err != nil {
+		return baseStepper{}, err
+	}
+	if err := bs.setX(x); err != nil {
+		return baseStepper{}, err
+	}
+	return bs, nil
+}
+
+// setX sets the design matrix of the stepper.
+func (s *baseStepper) setX(x [][]float64) error {
+	d, err := matrix.FromRows(x)
+	if err != nil {
+		return regression.ErrInvalidFeatureVector
+	}
+	s.x, s.d = x, d
+	return nil
+}
+
+// newBase returns a new base stepper without a design matrix for m training examples
+// with feature vectors of the length width (including the dummy feature). It starts from n zero coefficients.
+func newBase(o options.Options, y []float64, m, width, n int) (baseStepper, error) {
+	opt, err := NewOptimizer(o, n)
+	if err != nil {
+		return baseStepper{}, err
+	}
+	lr, err := NewSchedule(o)
+	if err != nil {
+		return baseStepper{}, err
+	}
+	return baseStepper{y: y, opt: opt, lr: lr, l2: o.Regularization, m: m, width: width, coeffs: make([]float64, n)}, nil
 }
 
 func (s baseStepper) CurrentCoefficients() []float64 {
 	return s.coeffs
 }
 
+func (s baseStepper) GradientNorm() float64 {
+	return s.gnorm
+}
+
+func (s baseStepper) LearningRate() float64 {
+	return s.rate
+}
+
 func (s baseStepper) X() [][]float64 {
 	return s.x
 }
@@ -54,38 +144,34 @@ func (s baseStepper) Y() []float64 {
 	return s.y
 }
 
+// penalty calculates the partial derivative of the L2 regularization term with respect to the j-th coefficient.
+// The penalty of a single training example is scaled down by the training set size, so that it adds up to the full
+// penalty over an epoch. Intercepts are never penalized.
+func (s baseStepper) penalty(j int, examples int) float64 {
+	if s.l2 == 0 || j%s.width == 0 {
+		return 0
+	}
+	return s.l2 * s.coeffs[j] * float64(examples) / float64(s.m)
+}
+
 // batchStepper takes steps (calculates next values of the coefficients) according to the batch gradient descent variant.
+// The gradient is summed over chunks of training examples in parallel.
 type batchStepper struct {
 	baseStepper
+	*reducer
 }
 
-func (s *batchStepper) TakeStep() error {
-	nc := make([]float64, len(s.coeffs))
-	var g errgroup.Group
-	for j := 0; j < len(s.coeffs); j++ {
-		j := j
-		g.Go(func() error {
-			// Calculate partial derivative.
-			var pd float64
-			for i := 0; i < len(s.x); i++ {
-				hr, err := s.hypho(s.x[i], s.coeffs)
-				if err != nil {
-					return err
-				}
-				pd += (s.y[i] - hr) * s.x[i][j]
-			}
-			// Assign new value to the new coefficients vector.
-			nc[j] = s.coeffs[j] + s.lr*pd
-			if math.IsNaN(nc[j]) || math.IsInf(nc[j], 0) {
-				return regression.ErrCannotConverge
-			}
-			return nil
-		})
-	}
-	if err := g.Wait(); err != nil {
+func (s *batchStepper) TakeStep(ctx context.Context) error {
+	grad, err := s.sum(ctx, hyphothesisGradient(s.hypho, s.d, s.y, s.coeffs))
+	if err != nil {
+		return err
+	}
+	nc, err := s.descend(grad, len(s.x))
+	if err != nil {
 		return err
 	}
 	s.coeffs = nc
+	s.t++
 	return nil
 }
 
@@ -95,24 +181,18 @@ type stochasticStepper struct {
 	i int
 }
 
-func (s *stochasticStepper) TakeStep() error {
-	nc := make([]float64, len(s.coeffs))
-	var g errgroup.Group
-	for j := 0; j < len(s.coeffs); j++ {
-		j := j
-		g.Go(func() error {
-			hr, err := s.hypho(s.x[s.i], s.coeffs)
-			if err != nil {
-				return err
-			}
-			nc[j] = s.coeffs[j] + s.lr*(s.y[s.i]-hr)*s.x[s.i][j]
-			if math.IsNaN(nc[j]) || math.IsInf(nc[j], 0) {
-				return regression.ErrCannotConverge
-			}
-			return nil
-		})
-	}
-	if err := g.Wait(); err != nil {
+func (s *stochasticStepper) TakeStep(ctx context.Context) error {
+	x := s.d.Row(s.i)
+	hr, err := s.hypho(x, s.coeffs)
+	if err != nil {
+		return err
+	}
+	grad := make([]float64, len(s.coeffs))
+	for j := range grad {
+		grad[j] = (hr - s.y[s.i]) * x[j]
+	}
+	nc, err := s.descend(grad, 1)
+	if err != nil {
 		return err
 	}
 	s.i++
@@ -120,5 +200,211 @@ func (s *stochasticStepper) TakeStep() error {
 		s.i = 0
 	}
 	s.coeffs = nc
+	s.t++
+	return nil
+}
+
+// miniBatchStepper takes steps (calculates next values of the coefficients) according to the mini-batch gradient descent variant.
+type miniBatchStepper struct {
+	baseStepper
+	*batcher
+}
+
+func (s *miniBatchStepper) TakeStep(ctx context.Context) error {
+	batch := s.next()
+	// Calculate residuals once, they're shared by all partial derivatives.
+	r := make([]float64, len(batch))
+	for k, i := range batch {
+		hr, err := s.hypho(s.d.Row(i), s.coeffs)
+		if err != nil {
+			return err
+		}
+		r[k] = s.y[i] - hr
+	}
+	grad := make([]float64, len(s.coeffs))
+	for j := range grad {
+		for k, i := range batch {
+			grad[j] -= r[k] * s.d.At(i, j)
+		}
+	}
+	nc, err := s.descend(grad, len(batch))
+	if err != nil {
+		return err
+	}
+	s.coeffs = nc
+	if s.EpochDone() {
+		s.t++
+	}
+	return nil
+}
+
+// A batcher splits a training set into mini-batches. Training examples are shuffled at the beginning of every epoch.
+type batcher struct {
+	rnd  *rand.Rand
+	perm []int
+	size int
+	pos  int
+}
+
+// newBatcher returns a new batcher for a training set with m training examples.
+// If the batch size in options isn't positive, an error is returned.
+func newBatcher(o options.Options, m int) (*batcher, error) {
+	if o.BatchSize < 1 {
+		return nil, regression.ErrInvalidBatchSize
+	}
+	size := o.BatchSize
+	if size > m {
+		size = m
+	}
+	return &batcher{rnd: rand.New(rand.NewSource(o.Seed)), perm: make([]int, m), size: size, pos: m}, nil
+}
+
+// next returns indices of training examples forming the next mini-batch.
+// The last mini-batch of an epoch is smaller if the batch size doesn't divide the training set size.
+func (b *batcher) next() []int {
+	if b.pos == len(b.perm) {
+		// Start a new epoch.
+		b.perm = b.rnd.Perm(len(b.perm))
+		b.pos = 0
+	}
+	end := b.pos + b.size
+	if end > len(b.perm) {
+		end = len(b.perm)
+	}
+	batch := b.perm[b.pos:end]
+	b.pos = end
+	return batch
+}
+
+// EpochDone reports whether the last mini-batch completed an epoch.
+func (b *batcher) EpochDone() bool {
+	return b.pos == len(b.perm)
+}
+
+// NewGradientStepper returns a new stepper driven by an explicit gradient function.
+// It starts from n zero coefficients.
+func NewGradientStepper(o options.Options, g GradientFunc, x [][]float64, y []float64, n int) (Stepper, error) {
+	bs, err := newBaseStepper(o, x, y, n)
+	if err != nil {
+		return nil, err
+	}
+	var gds Stepper
+	switch o.GradientDescentVariant {
+	case options.Batch:
+		r, err := newReducer(o, len(x), n)
+		if err != nil {
+			return nil, err
+		}
+		gds = &batchGradientStepper{bs, g, r}
+	case options.Stochastic:
+		gds = &stochasticGradientStepper{bs, g, 0}
+	case options.MiniBatch:
+		b, err := newBatcher(o, len(x))
+		if err != nil {
+			return nil, err
+		}
+		gds = &miniBatchGradientStepper{bs, g, b}
+	default:
+		return nil, regression.ErrUnsupportedGradientDescentVariant
+	}
+	return gds, nil
+}
+
+// batchGradientStepper takes steps according to the batch gradient descent variant using an explicit gradient function.
+// The gradient is summed over chunks of training examples in parallel.
+type batchGradientStepper struct {
+	baseStepper
+	grad GradientFunc
+	*reducer
+}
+
+func (s *batchGradientStepper) TakeStep(ctx context.Context) error {
+	g, err := s.sum(ctx, explicitGradient(s.grad, s.x, s.y, s.coeffs))
+	if err != nil {
+		return err
+	}
+	nc, err := s.descend(g, len(s.x))
+	if err != nil {
+		return err
+	}
+	s.coeffs = nc
+	s.t++
+	return nil
+}
+
+// stochasticGradientStepper takes steps according to the stochastic gradient descent variant using an explicit gradient function.
+type stochasticGradientStepper struct {
+	baseStepper
+	grad GradientFunc
+	i    int
+}
+
+func (s *stochasticGradientStepper) TakeStep(ctx context.Context) error {
+	g, err := s.grad(s.x[s.i:s.i+1], s.y[s.i:s.i+1], s.coeffs)
+	if err != nil {
+		return err
+	}
+	nc, err := s.descend(g, 1)
+	if err != nil {
+		return err
+	}
+	s.i++
+	if s.i == len(s.y) {
+		s.i = 0
+	}
+	s.coeffs = nc
+	s.t++
+	return nil
+}
+
+// descend takes a step in the direction opposite to the gradient g computed over the given number of training examples.
+// The regularization term is added to the gradient and the optimizer calculates new coefficients.
+func (s *baseStepper) descend(g []float64, examples int) ([]float64, error) {
+	if len(g) != len(s.coeffs) {
+		return nil, regression.ErrInvalidFeatureVector
+	}
+	pg := make([]float64, len(g))
+	var ss float64
+	for j := range g {
+		pg[j] = g[j] + s.penalty(j, examples)
+		ss += pg[j] * pg[j]
+	}
+	s.gnorm = math.Sqrt(ss) / float64(examples)
+	s.rate = s.lr(s.t)
+	nc := s.opt.Update(s.coeffs, pg, s.rate)
+	for j := range nc {
+		if math.IsNaN(nc[j]) || math.IsInf(nc[j], 0) {
+			return nil, regression.ErrCannotConverge
+		}
+	}
+	return nc, nil
+}
+
+// miniBatchGradientStepper takes steps according to the mini-batch gradient descent variant using an explicit gradient function.
+type miniBatchGradientStepper struct {
+	baseStepper
+	grad GradientFunc
+	*batcher
+}
+
+func (s *miniBatchGradientStepper) TakeStep(ctx context.Context) error {
+	batch := s.next()
+	x := make([][]float64, len(batch))
+	y := make([]float64, len(batch))
+	for k, i := range batch {
+		x[k], y[k] = s.x[i], s.y[i]
+	}
+	g, err := s.grad(x, y, s.coeffs)
+	if err != nil {
+		return err
+	}
+	nc, err := s.descend(g, len(batch))
+	if err != nil {
+		return err
+	}
+	s.coeffs = nc
+	if s.EpochDone() {
+		s.t++
+	}
 	return nil
 }
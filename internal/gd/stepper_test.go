@@ -1,10 +1,13 @@
 package gd
 
 import (
+	"context"
+	"reflect"
 	"testing"
 
 	"github.com/erni27/regression"
 	"github.com/erni27/regression/internal/regressiontest"
+	"github.com/erni27/regression/internal/ts"
 	"github.com/erni27/regression/options"
 )
 
@@ -12,20 +15,21 @@ func TestNewStepper(t *testing.T) {
 	tests := []struct {
 		name    string
 		gdv     options.GradientDescentVariant
+		size    int
 		wantErr bool
 		err     error
 	}{
 		{name: "batch", gdv: options.Batch, wantErr: false},
 		{name: "stochastic", gdv: options.Stochastic, wantErr: false},
+		{name: "mini-batch", gdv: options.MiniBatch, size: 10, wantErr: false},
+		{name: "mini-batch zero batch size", gdv: options.MiniBatch, wantErr: true, err: regression.ErrInvalidBatchSize},
 		{name: "unsupported", gdv: 0, wantErr: true, err: regression.ErrUnsupportedGradientDescentVariant},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := NewStepper(tt.gdv, nil, [][]float64{{1}}, nil, 0)
-			if (err != nil) != tt.wantErr {
-				if err != tt.err {
-					t.Fatalf("want error %v, got %v", tt.err, err)
-				}
+			got, err := NewStepper(options.Options{GradientDescentVariant: tt.gdv, BatchSize: tt.size}, nil, [][]float64{{1}}, nil)
+			if (err != nil) != tt.wantErr || err != tt.err {
+				t.Fatalf("want error %v, got %v", tt.err, err)
 			}
 			if !tt.wantErr && got == nil {
 				t.Fatalf("want not nil stepper")
@@ -34,87 +38,122 @@ func TestNewStepper(t *testing.T) {
 	}
 }
 
-func TestTakeStep(t *testing.T) {
-	tests := []struct {
-		name string
-		gdv  options.GradientDescentVariant
-		x    [][]float64
-		y    []float64
-		lr   float64
-		want []float64
-	}{
-		{
-			name: "batch n=1 m=10 alpha=0.1",
-			gdv:  options.Batch,
-			x: [][]float64{
-				{1, 4},
-				{1, 10},
-				{1, 21},
-				{1, 22},
-				{1, 29},
-				{1, 35},
-				{1, 39},
-				{1, 44},
-				{1, 50},
-				{1, 51},
-			},
-			y:    []float64{50, 33, 14, 13.5, 10, 8, 7.6, 7, 5, 4},
-			lr:   0.1,
-			want: []float64{15.21, 274.94},
+func TestNewStepper_DenseDesignMatrix(t *testing.T) {
+	o := options.Options{GradientDescentVariant: options.Batch}
+	x := ts.AddDummies([][]float64{{1, 2}, {3, 4}, {5, 6}})
+	s, err := NewStepper(o, nil, x, []float64{1, 2, 3})
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	// Rows with dummies are laid out contiguously, so the dense design matrix shares storage with them.
+	if d := s.(*batchStepper).d; &d.Row(2)[1] != &x[2][1] {
+		t.Errorf("want the dense design matrix to share storage with the training set")
+	}
+	if _, err := NewStepper(o, nil, [][]float64{{1, 2}, {3}}, []float64{1, 2}); err != regression.ErrInvalidFeatureVector {
+		t.Errorf("want %v, got %v", regression.ErrInvalidFeatureVector, err)
+	}
+}
+
+var takeStepTests = []struct {
+	name string
+	gdv  options.GradientDescentVariant
+	x    [][]float64
+	y    []float64
+	lr   float64
+	want []float64
+}{
+	{
+		name: "batch n=1 m=10 alpha=0.1",
+		gdv:  options.Batch,
+		x: [][]float64{
+			{1, 4},
+			{1, 10},
+			{1, 21},
+			{1, 22},
+			{1, 29},
+			{1, 35},
+			{1, 39},
+			{1, 44},
+			{1, 50},
+			{1, 51},
 		},
-		{
-			name: "batch n=2 m=5 alpha=0.01",
-			gdv:  options.Batch,
-			x: [][]float64{
-				{1, 5, 7},
-				{1, 13, 3},
-				{1, 21, 1},
-				{1, 6, 6},
-				{1, 1, 14},
-			},
-			y:    []float64{12.5, 15, 25, 10, 13},
-			lr:   0.01,
-			want: []float64{0.755, 8.555, 3.995},
+		y:    []float64{50, 33, 14, 13.5, 10, 8, 7.6, 7, 5, 4},
+		lr:   0.1,
+		want: []float64{15.21, 274.94},
+	},
+	{
+		name: "batch n=2 m=5 alpha=0.01",
+		gdv:  options.Batch,
+		x: [][]float64{
+			{1, 5, 7},
+			{1, 13, 3},
+			{1, 21, 1},
+			{1, 6, 6},
+			{1, 1, 14},
 		},
-		{
-			name: "stochastic n=1 m=7 alpha=0.01",
-			gdv:  options.Stochastic,
-			x: [][]float64{
-				{1, 9},
-				{1, 21},
-				{1, 22},
-				{1, 39},
-				{1, 44},
-				{1, 50},
-				{1, 51},
-			},
-			y:    []float64{33, 14, 13.5, 7.6, 7, 5, 4},
-			lr:   0.01,
-			want: []float64{0.33, 2.97},
+		y:    []float64{12.5, 15, 25, 10, 13},
+		lr:   0.01,
+		want: []float64{0.755, 8.555, 3.995},
+	},
+	{
+		name: "stochastic n=1 m=7 alpha=0.01",
+		gdv:  options.Stochastic,
+		x: [][]float64{
+			{1, 9},
+			{1, 21},
+			{1, 22},
+			{1, 39},
+			{1, 44},
+			{1, 50},
+			{1, 51},
 		},
-		{
-			name: "stochastic n=2 m=6 alpha=0.1",
-			gdv:  options.Stochastic,
-			x: [][]float64{
-				{1, 5, 7},
-				{1, 13, 3},
-				{1, 21, 1},
-				{1, 6, 6},
-				{1, 1, 14},
-				{1, 3, 9},
-			},
-			y:    []float64{12.5, 15, 25, 10, 13, 9},
-			lr:   0.1,
-			want: []float64{1.25, 6.25, 8.75},
+		y:    []float64{33, 14, 13.5, 7.6, 7, 5, 4},
+		lr:   0.01,
+		want: []float64{0.33, 2.97},
+	},
+	{
+		name: "stochastic n=2 m=6 alpha=0.1",
+		gdv:  options.Stochastic,
+		x: [][]float64{
+			{1, 5, 7},
+			{1, 13, 3},
+			{1, 21, 1},
+			{1, 6, 6},
+			{1, 1, 14},
+			{1, 3, 9},
 		},
+		y:    []float64{12.5, 15, 25, 10, 13, 9},
+		lr:   0.1,
+		want: []float64{1.25, 6.25, 8.75},
+	},
+}
+
+func TestTakeStep(t *testing.T) {
+	for _, tt := range takeStepTests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewStepper(options.Options{LearningRate: tt.lr, GradientDescentVariant: tt.gdv}, hyphoStub, tt.x, tt.y)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.TakeStep(context.Background())
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if got := s.CurrentCoefficients(); !regressiontest.AreFloatSlicesEqual(got, tt.want, 3) {
+				t.Fatalf("want %v, got %v", tt.want, got)
+			}
+		})
 	}
-	for _, tt := range tests {
+}
+
+func TestTakeStep_Gradient(t *testing.T) {
+	for _, tt := range takeStepTests {
 		t.Run(tt.name, func(t *testing.T) {
-			s, err := NewStepper(tt.gdv, hyphoStub, tt.x, tt.y, tt.lr)
+			s, err := NewGradientStepper(options.Options{LearningRate: tt.lr, GradientDescentVariant: tt.gdv}, gradStub, tt.x, tt.y, len(tt.x[0]))
 			if err != nil {
 				t.Fatal(err)
 			}
-			err = s.TakeStep()
+			err = s.TakeStep(context.Background())
 			if err != nil {
 				t.Fatalf("want nil, got error %v", err)
 			}
@@ -124,3 +163,113 @@ func TestTakeStep(t *testing.T) {
 		})
 	}
 }
+
+func TestTakeStep_MiniBatchWholeTrainingSet(t *testing.T) {
+	// A mini-batch covering the whole training set takes the same step as the batch variant.
+	for _, tt := range takeStepTests[:2] {
+		t.Run(tt.name, func(t *testing.T) {
+			o := options.Options{LearningRate: tt.lr}.WithMiniBatch(len(tt.x)+1, 7)
+			steppers := []struct {
+				name string
+				new  func() (Stepper, error)
+			}{
+				{name: "hyphothesis", new: func() (Stepper, error) { return NewStepper(o, hyphoStub, tt.x, tt.y) }},
+				{name: "gradient", new: func() (Stepper, error) { return NewGradientStepper(o, gradStub, tt.x, tt.y, len(tt.x[0])) }},
+			}
+			for _, st := range steppers {
+				s, err := st.new()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := s.TakeStep(context.Background()); err != nil {
+					t.Fatalf("want nil, got error %v", err)
+				}
+				if got := s.CurrentCoefficients(); !regressiontest.AreFloatSlicesEqual(got, tt.want, 3) {
+					t.Fatalf("%s: want %v, got %v", st.name, tt.want, got)
+				}
+				if es, ok := s.(EpochStepper); !ok || !es.EpochDone() {
+					t.Fatalf("%s: want completed epoch after a single step", st.name)
+				}
+			}
+		})
+	}
+}
+
+func TestTakeStep_MiniBatchDeterministic(t *testing.T) {
+	tt := takeStepTests[1]
+	run := func(seed int64) []float64 {
+		s, err := NewStepper(options.Options{LearningRate: tt.lr}.WithMiniBatch(2, seed), hyphoStub, tt.x, tt.y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k := 0; k < 3; k++ {
+			if err := s.TakeStep(context.Background()); err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+		}
+		return s.CurrentCoefficients()
+	}
+	if a, b := run(42), run(42); !regressiontest.AreFloatSlicesEqual(a, b, 9) {
+		t.Fatalf("want the same coefficients for the same seed, got %v and %v", a, b)
+	}
+}
+
+func TestBatcher(t *testing.T) {
+	const m = 10
+	b, err := newBatcher(options.Options{}.WithMiniBatch(4, 1), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var epochs [][]int
+	for e := 0; e < 3; e++ {
+		var epoch []int
+		for _, size := range []int{4, 4, 2} {
+			batch := b.next()
+			if len(batch) != size {
+				t.Fatalf("want batch size %d, got %d", size, len(batch))
+			}
+			epoch = append(epoch, batch...)
+			if done := len(epoch) == m; b.EpochDone() != done {
+				t.Fatalf("want epoch done %t, got %t", done, b.EpochDone())
+			}
+		}
+		seen := make(map[int]bool)
+		for _, i := range epoch {
+			if i < 0 || i >= m || seen[i] {
+				t.Fatalf("want every training example exactly once per epoch, got %v", epoch)
+			}
+			seen[i] = true
+		}
+		epochs = append(epochs, epoch)
+	}
+	if reflect.DeepEqual(epochs[0], epochs[1]) && reflect.DeepEqual(epochs[1], epochs[2]) {
+		t.Fatalf("want training examples shuffled every epoch, got %v", epochs)
+	}
+}
+
+func TestTakeStep_MiniBatchSchedule(t *testing.T) {
+	// The training set consists of 5 examples, so an epoch takes 3 steps with the batch size equals 2.
+	tt := takeStepTests[1]
+	o := options.Options{LearningRate: tt.lr}.WithMiniBatch(2, 1)
+	constant, err := NewStepper(o, hyphoStub, tt.x, tt.y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decayed, err := NewStepper(o.WithStepDecay(0.5, 1), hyphoStub, tt.x, tt.y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := 1; k <= 4; k++ {
+		if err := constant.TakeStep(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if err := decayed.TakeStep(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		// The learning rate is decayed per epoch, not per step.
+		equal := regressiontest.AreFloatSlicesEqual(constant.CurrentCoefficients(), decayed.CurrentCoefficients(), 9)
+		if want := k <= 3; equal != want {
+			t.Fatalf("want equal coefficients %t after %d steps, got %v and %v", want, k, constant.CurrentCoefficients(), decayed.CurrentCoefficients())
+		}
+	}
+}
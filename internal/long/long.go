@@ -3,19 +3,21 @@ package long
 
 import "context"
 
-// Run wraps a long running operation and handles context accordingly.
+// Run runs a long running operation and handles context accordingly.
+//
+// f runs in the calling goroutine and must check the context cooperatively, so no work continues after Run returns.
+// If the context is done before f starts or f fails after the context is done, the context error is returned.
 func Run[T any](ctx context.Context, f func() (T, error)) (T, error) {
-	var v T
-	var err error
-	done := make(chan struct{})
-	go func() {
-		v, err = f()
-		done <- struct{}{}
-	}()
-	select {
-	case <-done:
-		return v, err
-	case <-ctx.Done():
-		return v, ctx.Err()
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
 	}
+	v, err := f()
+	if err != nil {
+		if cerr := ctx.Err(); cerr != nil {
+			return zero, cerr
+		}
+		return zero, err
+	}
+	return v, nil
 }
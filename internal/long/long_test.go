@@ -1,10 +1,8 @@
-//go:build !race
-// +build !race
-
 package long
 
 import (
 	"context"
+	"errors"
 	"testing"
 )
 
@@ -20,12 +18,57 @@ func TestRun(t *testing.T) {
 	}
 }
 
-// TestRun_ContextCanceled fails under the data race detector due to immediate return after the ctx.Done().
+func TestRun_Error(t *testing.T) {
+	want := errors.New("long operation error")
+	if _, err := Run(context.Background(), func() (int, error) { return 0, want }); err != want {
+		t.Fatalf("want %v, got error %v", want, err)
+	}
+}
+
 func TestRun_ContextCanceled(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
-	_, err := Run(ctx, func() (int, error) { return 997, nil })
+	var called bool
+	_, err := Run(ctx, func() (int, error) { called = true; return 997, nil })
 	if err != context.Canceled {
 		t.Fatalf("want %v, got error %v", context.Canceled, err)
 	}
+	if called {
+		t.Fatal("want the operation not started")
+	}
+}
+
+func TestRun_ContextCanceledWhileRunning(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var units int
+	_, err := Run(ctx, func() (int, error) {
+		// A cooperative operation checking the context between units of work, canceled during the third one.
+		for {
+			if ctx.Err() != nil {
+				// The operation may fail with an error wrapping the context one.
+				return 0, errors.New("stopped")
+			}
+			if units++; units == 3 {
+				cancel()
+			}
+		}
+	})
+	if err != context.Canceled {
+		t.Fatalf("want %v, got error %v", context.Canceled, err)
+	}
+	if units != 3 {
+		t.Fatalf("got %d units of work, want %d", units, 3)
+	}
+}
+
+func TestRun_CompletedDespiteCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	got, err := Run(ctx, func() (int, error) { cancel(); return 997, nil })
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if got != 997 {
+		t.Fatalf("want %d, got %d", 997, got)
+	}
 }
@@ -0,0 +1,383 @@
+package matrix
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// blockSize is the side of square blocks processed by the dense matrix kernels.
+// A few blocks of float64 fit into the L1 and L2 caches together.
+const blockSize = 64
+
+// Dense is a dense matrix stored contiguously in row-major order.
+//
+// The element (i, j) is kept at data[i*stride+j]. The stride equals the number of columns,
+// unless the matrix is a view of a larger one.
+type Dense struct {
+	rows   int
+	cols   int
+	stride int
+	data   []float64
+}
+
+// NewDense creates a new r x c dense matrix backed by data, which is used without copying.
+// If data is nil, a zero matrix is allocated. Otherwise, its length must equal r*c.
+func NewDense(r, c int, data []float64) (*Dense, error) {
+	if r < 0 || c < 0 {
+		return nil, ErrIrregularMatrix
+	}
+	if data == nil {
+		data = make([]float64, r*c)
+	}
+	if len(data) != r*c {
+		return nil, ErrIrregularMatrix
+	}
+	return &Dense{rows: r, cols: c, stride: c, data: data}, nil
+}
+
+// FromRows creates a new dense matrix from a 2D slice.
+//
+// If rows of x are laid out in a single slice with a constant stride (like rows returned by Rows),
+// the dense matrix shares its storage with x. Otherwise, x is copied into contiguous storage.
+func FromRows(x [][]float64) (*Dense, error) {
+	if !IsRegular(x) {
+		return nil, ErrIrregularMatrix
+	}
+	if d, ok := view(x); ok {
+		return d, nil
+	}
+	r, c := len(x), len(x[0])
+	d := &Dense{rows: r, cols: c, stride: c, data: make([]float64, r*c)}
+	for i := 0; i < r; i++ {
+		copy(d.data[i*c:(i+1)*c], x[i])
+	}
+	return d, nil
+}
+
+// view returns a dense matrix sharing storage with a regular 2D slice x, if rows of x are laid out
+// in a single slice with a constant stride.
+func view(x [][]float64) (*Dense, bool) {
+	r, c := len(x), len(x[0])
+	if c == 0 {
+		return nil, false
+	}
+	base := x[0][:cap(x[0])]
+	stride := c
+	if r > 1 {
+		// Find the offset of the second row in the backing array of the first one.
+		stride = -1
+		for k := c; k < len(base); k++ {
+			if &base[k] == &x[1][0] {
+				stride = k
+				break
+			}
+		}
+		if stride < 0 || (r-1)*stride+c > len(base) {
+			return nil, false
+		}
+		for i := 2; i < r; i++ {
+			if &base[i*stride] != &x[i][0] {
+				return nil, false
+			}
+		}
+	}
+	return &Dense{rows: r, cols: c, stride: stride, data: base[:(r-1)*stride+c]}, true
+}
+
+// Dims returns the number of rows and columns.
+func (d *Dense) Dims() (int, int) {
+	return d.rows, d.cols
+}
+
+// At returns the element (i, j).
+func (d *Dense) At(i, j int) float64 {
+	return d.data[i*d.stride+j]
+}
+
+// Set sets the element (i, j) to v.
+func (d *Dense) Set(i, j int, v float64) {
+	d.data[i*d.stride+j] = v
+}
+
+// Row returns the i-th row. It shares storage with the matrix.
+func (d *Dense) Row(i int) []float64 {
+	s := i * d.stride
+	return d.data[s : s+d.cols]
+}
+
+// Rows returns rows of the matrix as a 2D slice sharing storage with the matrix. Only the slice
+// of row headers is allocated. Rows keep the capacity of the backing array, so FromRows can view them
+// without copying. Appending to a row overwrites the next one.
+func (d *Dense) Rows() [][]float64 {
+	rows := make([][]float64, d.rows)
+	for i := range rows {
+		rows[i] = d.Row(i)
+	}
+	return rows
+}
+
+// View returns the r x c submatrix starting at the element (i, j). It shares storage with the matrix.
+func (d *Dense) View(i, j, r, c int) *Dense {
+	if r == 0 || c == 0 {
+		return &Dense{rows: r, cols: c, stride: d.stride}
+	}
+	s := i*d.stride + j
+	return &Dense{rows: r, cols: c, stride: d.stride, data: d.data[s : s+(r-1)*d.stride+c]}
+}
+
+// Clone returns a deep copy of the matrix in contiguous storage.
+func (d *Dense) Clone() *Dense {
+	c := &Dense{rows: d.rows, cols: d.cols, stride: d.cols, data: make([]float64, d.rows*d.cols)}
+	for i := 0; i < d.rows; i++ {
+		copy(c.Row(i), d.Row(i))
+	}
+	return c
+}
+
+// MultiplyDense produces matrix product c=ab.
+//
+// The product is computed in blocks of rows distributed among GOMAXPROCS goroutines. Each block
+// is multiplied tile by tile, so the tiles of a and b stay in the cache.
+func MultiplyDense(ctx context.Context, a, b *Dense) (*Dense, error) {
+	if a.cols != b.rows {
+		return nil, ErrOperationNotAllowed
+	}
+	m, n, p := a.rows, a.cols, b.cols
+	c := &Dense{rows: m, cols: p, stride: p, data: make([]float64, m*p)}
+	err := parallelFor(ctx, m, blockSize, func(lo, hi int) error {
+		for kb := 0; kb < n; kb += blockSize {
+			ke := minInt(kb+blockSize, n)
+			for jb := 0; jb < p; jb += blockSize {
+				je := minInt(jb+blockSize, p)
+				for i := lo; i < hi; i++ {
+					ci := c.Row(i)[jb:je]
+					ai := a.Row(i)
+					for k := kb; k < ke; k++ {
+						aik := ai[k]
+						if aik == 0 {
+							continue
+						}
+						bk := b.Row(k)[jb:je]
+						for j := range ci {
+							ci[j] += aik * bk[j]
+						}
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// MultiplyDenseByVector multiplies a given matrix by a given vector. Blocks of rows are distributed
+// among GOMAXPROCS goroutines.
+func MultiplyDenseByVector(ctx context.Context, a *Dense, v []float64) ([]float64, error) {
+	if a.cols != len(v) {
+		return nil, ErrOperationNotAllowed
+	}
+	p := make([]float64, a.rows)
+	err := parallelFor(ctx, a.rows, blockSize*blockSize/maxInt(a.cols, 1), func(lo, hi int) error {
+		for i := lo; i < hi; i++ {
+			var s float64
+			for k, x := range a.Row(i) {
+				s += x * v[k]
+			}
+			p[i] = s
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// TransposeDense performs a matrix transposition. It copies the matrix tile by tile, so both
+// the reads and the writes stay in the cache. Blocks of rows are distributed among GOMAXPROCS goroutines.
+func TransposeDense(ctx context.Context, a *Dense) (*Dense, error) {
+	m, n := a.rows, a.cols
+	t := &Dense{rows: n, cols: m, stride: m, data: make([]float64, m*n)}
+	err := parallelFor(ctx, m, blockSize, func(lo, hi int) error {
+		for jb := 0; jb < n; jb += blockSize {
+			je := minInt(jb+blockSize, n)
+			for i := lo; i < hi; i++ {
+				ai := a.Row(i)
+				for j := jb; j < je; j++ {
+					t.data[j*m+i] = ai[j]
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// InverseDense performs matrix inversion.
+//
+// It uses the LU decomposition with partial pivoting. If any pivot is negligible relative to the largest
+// element of the matrix, the matrix is numerically singular and ErrNonInvertibleMatrix is returned.
+// Updates of the remaining rows and the solutions for columns of the inverse are computed in parallel.
+func InverseDense(ctx context.Context, d *Dense) (*Dense, error) {
+	n := d.rows
+	if n != d.cols || n == 0 {
+		return nil, ErrNonInvertibleMatrix
+	}
+
+	lu := d.Clone()
+	var amax float64
+	for _, v := range lu.data {
+		amax = math.Max(amax, math.Abs(v))
+	}
+	tol := float64(n) * eps * amax
+
+	// Rows are swapped by their headers, a holds the rows of pa.
+	a := lu.Rows()
+	// p is a permutation vector, p[i] is the row of the original matrix placed at i.
+	p := make([]int, n)
+	for i := 0; i < n; i++ {
+		p[i] = i
+	}
+
+	// Decomposition pa=lu.
+	// l under the main diagonal without the main diagonal.
+	// u above the main diagonal with the main diagonal.
+	for k := 0; k < n; k++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// Partial pivoting, choose the row with the largest absolute pivot.
+		piv := k
+		for i := k + 1; i < n; i++ {
+			if math.Abs(a[i][k]) > math.Abs(a[piv][k]) {
+				piv = i
+			}
+		}
+		if !(math.Abs(a[piv][k]) > tol) {
+			return nil, ErrNonInvertibleMatrix
+		}
+		a[k], a[piv] = a[piv], a[k]
+		p[k], p[piv] = p[piv], p[k]
+		// Decomposition.
+		ak := a[k]
+		err := parallelFor(ctx, n-k-1, blockSize*blockSize/maxInt(n-k, 1), func(lo, hi int) error {
+			for i := k + 1 + lo; i < k+1+hi; i++ {
+				ai := a[i]
+				ai[k] /= ak[k]
+				f := ai[k]
+				for j := k + 1; j < n; j++ {
+					ai[j] -= f * ak[j]
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The inverse is transposed, so every column is written as a contiguous row.
+	rt := &Dense{rows: n, cols: n, stride: n, data: make([]float64, n*n)}
+	// Solving n sets of the equation ax=b, where b is a column vector of the permuted identity matrix.
+	err := parallelFor(ctx, n, maxInt(blockSize*blockSize/n, 1), func(lo, hi int) error {
+		for i := lo; i < hi; i++ {
+			// x is a column vector of the inversed matrix.
+			x := rt.Row(i)
+			// Forward substition.
+			for k := 0; k < n; k++ {
+				var b float64
+				if p[k] == i {
+					b = 1
+				}
+				var s float64
+				for j, l := range a[k][:k] {
+					s += l * x[j]
+				}
+				// Dividing by l[k][k] omitted since l[k][k] is one for every k.
+				x[k] = b - s
+			}
+			// Back substition.
+			for k := n - 1; k >= 0; k-- {
+				var s float64
+				for j := k + 1; j < n; j++ {
+					s += a[k][j] * x[j]
+				}
+				x[k] = (x[k] - s) / a[k][k]
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return TransposeDense(ctx, rt)
+}
+
+// parallelFor splits [0, n) into chunks of the size chunk and calls f for every chunk. Chunks are distributed
+// among at most GOMAXPROCS goroutines, a single chunk is processed by the calling goroutine.
+// It stops after the first error or once the context is done.
+func parallelFor(ctx context.Context, n, chunk int, f func(lo, hi int) error) error {
+	if chunk < 1 {
+		chunk = 1
+	}
+	chunks := (n + chunk - 1) / chunk
+	workers := minInt(runtime.GOMAXPROCS(0), chunks)
+	if workers <= 1 {
+		for lo := 0; lo < n; lo += chunk {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := f(lo, minInt(lo+chunk, n)); err != nil {
+				return err
+			}
+		}
+		return ctx.Err()
+	}
+	g, gctx := errgroup.WithContext(ctx)
+	var next int64
+	for w := 0; w < workers; w++ {
+		g.Go(func() error {
+			for {
+				c := int(atomic.AddInt64(&next, 1) - 1)
+				if c >= chunks {
+					return nil
+				}
+				if err := gctx.Err(); err != nil {
+					return err
+				}
+				lo := c * chunk
+				if err := f(lo, minInt(lo+chunk, n)); err != nil {
+					return err
+				}
+			}
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
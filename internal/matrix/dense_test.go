@@ -0,0 +1,230 @@
+package matrix
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"github.com/erni27/regression/internal/regressiontest"
+)
+
+// randomDense returns an r x c dense matrix with elements drawn from [-1, 1).
+func randomDense(rnd *rand.Rand, r, c int) *Dense {
+	d, _ := NewDense(r, c, nil)
+	for i := range d.data {
+		d.data[i] = 2*rnd.Float64() - 1
+	}
+	return d
+}
+
+// naiveMultiply is the textbook matrix product, a reference for the blocked kernel.
+func naiveMultiply(a, b *Dense) [][]float64 {
+	c := make([][]float64, a.rows)
+	for i := range c {
+		c[i] = make([]float64, b.cols)
+		for j := range c[i] {
+			for k := 0; k < a.cols; k++ {
+				c[i][j] += a.At(i, k) * b.At(k, j)
+			}
+		}
+	}
+	return c
+}
+
+func TestNewDense(t *testing.T) {
+	d, err := NewDense(2, 3, []float64{1, 2, 3, 4, 5, 6})
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if r, c := d.Dims(); r != 2 || c != 3 {
+		t.Fatalf("got dims %dx%d, want 2x3", r, c)
+	}
+	if got := d.At(1, 0); got != 4 {
+		t.Fatalf("got %v, want %v", got, 4)
+	}
+	want := [][]float64{{1, 2, 3}, {4, 5, 6}}
+	if !regressiontest.Are2DFloatSlicesEqual(d.Rows(), want, 9) {
+		t.Fatalf("got %v, want %v", d.Rows(), want)
+	}
+	if _, err := NewDense(2, 3, []float64{1, 2, 3}); err != ErrIrregularMatrix {
+		t.Fatalf("want %v, got %v", ErrIrregularMatrix, err)
+	}
+}
+
+func TestFromRows(t *testing.T) {
+	t.Run("separate rows are copied", func(t *testing.T) {
+		x := [][]float64{{1, 2}, {3, 4}}
+		d, err := FromRows(x)
+		if err != nil {
+			t.Fatalf("want nil, got error %v", err)
+		}
+		d.Set(0, 0, 42)
+		if x[0][0] != 1 {
+			t.Fatalf("got %v, want %v", x[0][0], 1)
+		}
+	})
+	t.Run("contiguous rows are viewed", func(t *testing.T) {
+		data := []float64{1, 2, 0, 3, 4, 0, 5, 6}
+		// Rows with the stride 3.
+		x := [][]float64{data[0:2], data[3:5], data[6:8]}
+		d, err := FromRows(x)
+		if err != nil {
+			t.Fatalf("want nil, got error %v", err)
+		}
+		x[2][1] = 42
+		if got := d.At(2, 1); got != 42 {
+			t.Fatalf("got %v, want %v", got, 42)
+		}
+		want := [][]float64{{1, 2}, {3, 4}, {5, 42}}
+		if !regressiontest.Are2DFloatSlicesEqual(d.Rows(), want, 9) {
+			t.Fatalf("got %v, want %v", d.Rows(), want)
+		}
+	})
+	t.Run("irregular", func(t *testing.T) {
+		if _, err := FromRows([][]float64{{1, 2}, {3}}); err != ErrIrregularMatrix {
+			t.Fatalf("want %v, got %v", ErrIrregularMatrix, err)
+		}
+	})
+}
+
+func TestView(t *testing.T) {
+	d, _ := NewDense(3, 3, []float64{1, 2, 3, 4, 5, 6, 7, 8, 9})
+	v := d.View(1, 1, 2, 2)
+	want := [][]float64{{5, 6}, {8, 9}}
+	if !regressiontest.Are2DFloatSlicesEqual(v.Rows(), want, 9) {
+		t.Fatalf("got %v, want %v", v.Rows(), want)
+	}
+	tr, err := TransposeDense(context.Background(), v)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	want = [][]float64{{5, 8}, {6, 9}}
+	if !regressiontest.Are2DFloatSlicesEqual(tr.Rows(), want, 9) {
+		t.Fatalf("got %v, want %v", tr.Rows(), want)
+	}
+}
+
+func TestMultiplyDense(t *testing.T) {
+	// Exercise the parallel kernels even on a single CPU.
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	rnd := rand.New(rand.NewSource(1))
+	// Sizes crossing block boundaries.
+	sizes := [][3]int{{1, 1, 1}, {3, 5, 2}, {130, 70, 90}, {200, 129, 65}}
+	ctx := context.Background()
+	for _, s := range sizes {
+		a, b := randomDense(rnd, s[0], s[1]), randomDense(rnd, s[1], s[2])
+		got, err := MultiplyDense(ctx, a, b)
+		if err != nil {
+			t.Fatalf("want nil, got error %v", err)
+		}
+		if want := naiveMultiply(a, b); !regressiontest.Are2DFloatSlicesEqual(got.Rows(), want, 9) {
+			t.Fatalf("%v: product differs from the naive one", s)
+		}
+	}
+	if _, err := MultiplyDense(ctx, randomDense(rnd, 2, 3), randomDense(rnd, 2, 3)); err != ErrOperationNotAllowed {
+		t.Fatalf("want %v, got %v", ErrOperationNotAllowed, err)
+	}
+}
+
+func TestTransposeDense(t *testing.T) {
+	// Exercise the parallel kernels even on a single CPU.
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	rnd := rand.New(rand.NewSource(1))
+	a := randomDense(rnd, 150, 70)
+	got, err := TransposeDense(context.Background(), a)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	for i := 0; i < 150; i++ {
+		for j := 0; j < 70; j++ {
+			if got.At(j, i) != a.At(i, j) {
+				t.Fatalf("got %v at (%d, %d), want %v", got.At(j, i), j, i, a.At(i, j))
+			}
+		}
+	}
+}
+
+func TestMultiplyDenseByVector(t *testing.T) {
+	// Exercise the parallel kernels even on a single CPU.
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	rnd := rand.New(rand.NewSource(1))
+	a := randomDense(rnd, 300, 40)
+	v := randomDense(rnd, 40, 1)
+	got, err := MultiplyDenseByVector(context.Background(), a, v.data)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	want := naiveMultiply(a, v)
+	for i := range got {
+		if !regressiontest.AreFloatEqual(got[i], want[i][0], 9) {
+			t.Fatalf("got %v at %d, want %v", got[i], i, want[i][0])
+		}
+	}
+}
+
+func TestInverseDense(t *testing.T) {
+	// Exercise the parallel kernels even on a single CPU.
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	rnd := rand.New(rand.NewSource(1))
+	n := 150
+	a := randomDense(rnd, n, n)
+	ctx := context.Background()
+	inv, err := InverseDense(ctx, a)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	p, err := MultiplyDense(ctx, a, inv)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			want := 0.0
+			if i == j {
+				want = 1
+			}
+			if !regressiontest.AreFloatEqual(p.At(i, j), want, 6) {
+				t.Fatalf("got %v at (%d, %d) of aa^-1, want %v", p.At(i, j), i, j, want)
+			}
+		}
+	}
+}
+
+func TestDenseKernels_CanceledContext(t *testing.T) {
+	// Exercise the parallel kernels even on a single CPU.
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	rnd := rand.New(rand.NewSource(1))
+	a := randomDense(rnd, 200, 200)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{name: "multiply", run: func() error { _, err := MultiplyDense(ctx, a, a); return err }},
+		{name: "multiply by vector", run: func() error { _, err := MultiplyDenseByVector(ctx, a, a.Row(0)); return err }},
+		{name: "transpose", run: func() error { _, err := TransposeDense(ctx, a); return err }},
+		{name: "inverse", run: func() error { _, err := InverseDense(ctx, a); return err }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); !errors.Is(err, context.Canceled) {
+				t.Fatalf("want %v, got %v", context.Canceled, err)
+			}
+		})
+	}
+}
+
+func BenchmarkMultiplyDense(b *testing.B) {
+	rnd := rand.New(rand.NewSource(1))
+	x, y := randomDense(rnd, 512, 512), randomDense(rnd, 512, 512)
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := MultiplyDense(ctx, x, y); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
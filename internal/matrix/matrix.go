@@ -4,186 +4,101 @@ package matrix
 import (
 	"context"
 	"errors"
+	"math"
 )
 
 var (
 	ErrNonInvertibleMatrix = errors.New("matrix is not invertible")
 	ErrOperationNotAllowed = errors.New("operation not allowed")
 	ErrIrregularMatrix     = errors.New("irregular matrix")
+	// ErrNotConverged is returned if an iterative decomposition doesn't converge within its iteration limit.
+	ErrNotConverged = errors.New("decomposition did not converge")
 )
 
-// Inverse performs matrix inversion.
+// Inverse performs matrix inversion. See InverseDense.
 func Inverse(ctx context.Context, m [][]float64) ([][]float64, error) {
-	if !IsRegular(m) {
-		return nil, ErrIrregularMatrix
+	a, err := FromRows(m)
+	if err != nil {
+		return nil, err
 	}
-	n := len(m)
-	if n != len(m[0]) {
-		return nil, ErrNonInvertibleMatrix
-	}
-
-	a := make([][]float64, n)
-	copy(a, m)
-
-	// p is a permuation matrix.
-	p := make([][]float64, n)
-	for i := 0; i < n; i++ {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-			p[i] = make([]float64, n)
-			p[i][i] = 1
-		}
+	r, err := InverseDense(ctx, a)
+	if err != nil {
+		return nil, err
 	}
+	return r.Rows(), nil
+}
 
-	// Decomposition a=plu.
-	// l under the main diagonal without the main diagonal.
-	// u above the main diagonal with the main diagonal.
-	for k := 0; k < n-1; k++ {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-			// Pivoting.
-			for i := k; i < n-1; i++ {
-				if a[k][k] != 0 {
-					break
-				}
-				a[k], a[k+1] = a[k+1], a[k]
-				p[k], p[k+1] = p[k+1], p[k]
-			}
-			// Decomposition.
-			for i := k + 1; i < n; i++ {
-				a[i][k] /= a[k][k]
-				for j := k + 1; j < n; j++ {
-					a[i][j] -= a[i][k] * a[k][j]
-				}
-			}
-		}
-	}
+// Condition returns the condition number of a square matrix in the 1-norm, |a|*|a^-1|.
+//
+// The condition number estimates how much the solution of ax=b can change relative to a change of b.
+// Roughly log10 of it decimal digits of precision are lost when solving the equation. If the matrix is
+// numerically singular, ErrNonInvertibleMatrix is returned.
+func Condition(ctx context.Context, a [][]float64) (float64, error) {
+	_, c, err := InverseCondition(ctx, a)
+	return c, err
+}
 
-	r := make([][]float64, n)
-	for i := 0; i < n; i++ {
-		r[i] = make([]float64, n)
-	}
+// InverseCondition returns the inverse of a square matrix together with its condition number in the 1-norm
+// (see Condition), so that a caller needing both inverts the matrix only once.
+func InverseCondition(ctx context.Context, a [][]float64) ([][]float64, float64, error) {
+	inv, err := Inverse(ctx, a)
+	if err != nil {
+		return nil, 0, err
+	}
+	return inv, norm1(a) * norm1(inv), nil
+}
 
-	// b is a column vector of the permuted identity matrix.
-	b := make([]float64, n)
-	// x is a column vector of the inversed matrix.
-	x := make([]float64, n)
-	// Solving n sets of the equation ax=b.
-	for i := 0; i < n; i++ {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-			// Assign the column vector.
-			for j := 0; j < n; j++ {
-				if i == j {
-					b[j] = p[i][j]
-				} else {
-					b[j] = p[i][j]
-				}
-			}
-			// Forward substition.
-			x[0] = b[0]
-			for k := 1; k < n; k++ {
-				var s float64
-				for j := 0; j < k; j++ {
-					s += a[k][j] * x[j]
-				}
-				// Dividing by l[i][i] omitted since l[i][i] is zero for every i.
-				x[k] = b[k] - s
-			}
-			// Back substition.
-			x[n-1] /= a[n-1][n-1]
-			for k := n - 2; k >= 0; k-- {
-				var s float64
-				for j := k + 1; j < n; j++ {
-					s += a[k][j] * x[j]
-				}
-				x[k] = (x[k] - s) / a[k][k]
-			}
-			// Write a column vector into the result.
-			for j := 0; j < n; j++ {
-				r[j][i] = x[j]
-			}
+// norm1 returns the 1-norm of a matrix, the largest absolute column sum.
+func norm1(a [][]float64) float64 {
+	var norm float64
+	for j := range a[0] {
+		var s float64
+		for i := range a {
+			s += math.Abs(a[i][j])
 		}
+		norm = math.Max(norm, s)
 	}
-	return r, nil
+	return norm
 }
 
-// Multiply produces matrix product z=xy.
+// Multiply produces matrix product z=xy. See MultiplyDense.
 func Multiply(ctx context.Context, x [][]float64, y [][]float64) ([][]float64, error) {
-	if !IsRegular(x) || !IsRegular(y) {
-		return nil, ErrIrregularMatrix
+	a, err := FromRows(x)
+	if err != nil {
+		return nil, err
 	}
-	m, n, p := len(x), len(y), len(y[0])
-	// The numbers of rows in x matrix must be equal to the number of columns in y matrix.
-	if len(x[0]) != n {
-		return nil, ErrOperationNotAllowed
+	b, err := FromRows(y)
+	if err != nil {
+		return nil, err
 	}
-	z := make([][]float64, m)
-	// Calculate a matrix-matrix product.
-	for i := 0; i < m; i++ {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-			z[i] = make([]float64, p)
-			for j := 0; j < p; j++ {
-				for k := 0; k < n; k++ {
-					z[i][j] += x[i][k] * y[k][j]
-				}
-			}
-		}
+	// The numbers of rows in x matrix must be equal to the number of columns in y matrix.
+	z, err := MultiplyDense(ctx, a, b)
+	if err != nil {
+		return nil, err
 	}
-	return z, nil
+	return z.Rows(), nil
 }
 
-// MultiplyByVector multiples a given matrix by a given vector.
+// MultiplyByVector multiples a given matrix by a given vector. See MultiplyDenseByVector.
 func MultiplyByVector(ctx context.Context, x [][]float64, y []float64) ([]float64, error) {
-	if !IsRegular(x) {
-		return nil, ErrIrregularMatrix
+	a, err := FromRows(x)
+	if err != nil {
+		return nil, err
 	}
-	m, n := len(x), len(y)
-	if n != len(x[0]) {
-		return nil, ErrOperationNotAllowed
-	}
-	p := make([]float64, m)
-	for i := 0; i < m; i++ {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-			for k := 0; k < n; k++ {
-				p[i] += x[i][k] * y[k]
-			}
-		}
-	}
-	return p, nil
+	return MultiplyDenseByVector(ctx, a, y)
 }
 
-// Transpose performs a matrix transposition.
+// Transpose performs a matrix transposition. See TransposeDense.
 func Transpose(ctx context.Context, x [][]float64) ([][]float64, error) {
-	if !IsRegular(x) {
-		return nil, ErrIrregularMatrix
+	a, err := FromRows(x)
+	if err != nil {
+		return nil, err
 	}
-	n, m := len(x), len(x[0])
-	t := make([][]float64, m)
-	for j := 0; j < m; j++ {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-			t[j] = make([]float64, n)
-			for i := 0; i < n; i++ {
-				t[j][i] = x[i][j]
-			}
-		}
+	t, err := TransposeDense(ctx, a)
+	if err != nil {
+		return nil, err
 	}
-	return t, nil
+	return t.Rows(), nil
 }
 
 // IsRegular checks if a 2D slice is a non-nil, regular matrix.
@@ -59,6 +59,103 @@ func TestInverse(t *testing.T) {
 	}
 }
 
+func TestInverse_Pivoting(t *testing.T) {
+	// Without pivoting, the tiny pivot makes the elimination lose all the precision.
+	m := [][]float64{
+		{1e-20, 1},
+		{1, 1},
+	}
+	orig := [][]float64{
+		{1e-20, 1},
+		{1, 1},
+	}
+	got, err := Inverse(context.Background(), m)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	want := [][]float64{
+		{-1, 1},
+		{1, 0},
+	}
+	if !regressiontest.Are2DFloatSlicesEqual(got, want, 9) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(m, orig) {
+		t.Fatalf("input matrix modified, got %v, want %v", m, orig)
+	}
+}
+
+func TestInverse_Singular(t *testing.T) {
+	tests := []struct {
+		name   string
+		matrix [][]float64
+	}{
+		{name: "zero column", matrix: [][]float64{{1, 0}, {2, 0}}},
+		{name: "linearly dependent rows", matrix: [][]float64{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}},
+		{name: "numerically singular", matrix: [][]float64{{1, 1}, {1, 1 + 1e-17}}},
+		{name: "not square", matrix: [][]float64{{1, 2, 3}, {4, 5, 6}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Inverse(context.Background(), tt.matrix); err != ErrNonInvertibleMatrix {
+				t.Fatalf("want %v, got %v", ErrNonInvertibleMatrix, err)
+			}
+		})
+	}
+}
+
+func TestCondition(t *testing.T) {
+	tests := []struct {
+		name   string
+		matrix [][]float64
+		want   float64
+	}{
+		{name: "identity", matrix: [][]float64{{1, 0}, {0, 1}}, want: 1},
+		{name: "diagonal", matrix: [][]float64{{100, 0}, {0, 0.01}}, want: 1e4},
+		{name: "nearly singular", matrix: [][]float64{{1, 1}, {1, 1.0001}}, want: 40004.0001},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Condition(context.Background(), tt.matrix)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if !regressiontest.AreFloatEqual(got, tt.want, 3) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCholeskyCondition(t *testing.T) {
+	tests := []struct {
+		name   string
+		matrix [][]float64
+	}{
+		{name: "identity", matrix: [][]float64{{1, 0}, {0, 1}}},
+		{name: "diagonal", matrix: [][]float64{{100, 0}, {0, 0.01}}},
+		{name: "nearly singular", matrix: [][]float64{{1, 1}, {1, 1.0001}}},
+		{name: "hilbert", matrix: [][]float64{{1, 1. / 2, 1. / 3, 1. / 4}, {1. / 2, 1. / 3, 1. / 4, 1. / 5}, {1. / 3, 1. / 4, 1. / 5, 1. / 6}, {1. / 4, 1. / 5, 1. / 6, 1. / 7}}},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := Condition(ctx, tt.matrix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l, err := Cholesky(ctx, tt.matrix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The estimate is a lower bound, exact for these matrices.
+			if got := CholeskyCondition(tt.matrix, l); got > want*(1+1e-9) || got < want*(1-1e-6) {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
 func TestMultiply(t *testing.T) {
 	tests := []struct {
 		name string
@@ -0,0 +1,406 @@
+package matrix
+
+import (
+	"context"
+	"math"
+)
+
+const (
+	// eps is the machine epsilon for float64.
+	eps = 0x1p-52
+	// maxSweeps limits the number of Jacobi sweeps of the singular value decomposition.
+	maxSweeps = 60
+)
+
+// SolveQR finds the least squares solution of ax=b with the Householder QR decomposition of a.
+//
+// Unlike solving the normal equation a'ax=a'b, it doesn't square the condition number of a.
+// The matrix a must have at least as many rows as columns and full column rank,
+// otherwise ErrNonInvertibleMatrix is returned.
+func SolveQR(ctx context.Context, a [][]float64, b []float64) ([]float64, error) {
+	if !IsRegular(a) {
+		return nil, ErrIrregularMatrix
+	}
+	m, n := len(a), len(a[0])
+	if len(b) != m {
+		return nil, ErrOperationNotAllowed
+	}
+	if m < n {
+		return nil, ErrNonInvertibleMatrix
+	}
+	r, qb, err := householder(ctx, a, b)
+	if err != nil {
+		return nil, err
+	}
+	var rmax float64
+	for k := 0; k < n; k++ {
+		rmax = math.Max(rmax, math.Abs(r[k][k]))
+	}
+	// The columns are linearly dependent if any diagonal element of r is negligible.
+	tol := float64(m) * eps * rmax
+	for k := 0; k < n; k++ {
+		if math.Abs(r[k][k]) <= tol {
+			return nil, ErrNonInvertibleMatrix
+		}
+	}
+	// Back substitution rx=q'b.
+	x := make([]float64, n)
+	for k := n - 1; k >= 0; k-- {
+		s := qb[k]
+		for j := k + 1; j < n; j++ {
+			s -= r[k][j] * x[j]
+		}
+		x[k] = s / r[k][k]
+	}
+	return x, nil
+}
+
+// householder reduces an m x n matrix a with at least as many rows as columns to the upper triangular form r=q'a
+// with Householder reflections, and applies them to the vector b. The first n rows of r hold the triangular factor,
+// the rest is zero. A column which is already zero below the main diagonal isn't reflected, so linearly dependent
+// columns leave zeros on the main diagonal.
+func householder(ctx context.Context, a [][]float64, b []float64) ([][]float64, []float64, error) {
+	m, n := len(a), len(a[0])
+	r := clone(a)
+	qb := make([]float64, m)
+	copy(qb, b)
+	// v is a Householder vector, w is v'r.
+	v := make([]float64, m)
+	w := make([]float64, n)
+	for k := 0; k < n; k++ {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		var norm float64
+		for i := k; i < m; i++ {
+			norm = math.Hypot(norm, r[i][k])
+		}
+		if norm == 0 {
+			continue
+		}
+		// Choose the sign which avoids cancellation.
+		alpha := -math.Copysign(norm, r[k][k])
+		var vv float64
+		for i := k; i < m; i++ {
+			v[i] = r[i][k]
+			if i == k {
+				v[i] -= alpha
+			}
+			vv += v[i] * v[i]
+		}
+		// Reflect the remaining columns and the right-hand side, H=I-2vv'/v'v. Rows are traversed in order,
+		// first to calculate w=v'r and then to update r-=2vw'/v'v, which keeps the memory access sequential.
+		for j := k; j < n; j++ {
+			w[j] = 0
+		}
+		for i := k; i < m; i++ {
+			ri := r[i]
+			for j := k; j < n; j++ {
+				w[j] += v[i] * ri[j]
+			}
+		}
+		for i := k; i < m; i++ {
+			ri, f := r[i], 2*v[i]/vv
+			for j := k; j < n; j++ {
+				ri[j] -= f * w[j]
+			}
+		}
+		var s float64
+		for i := k; i < m; i++ {
+			s += v[i] * qb[i]
+		}
+		s = 2 * s / vv
+		for i := k; i < m; i++ {
+			qb[i] -= s * v[i]
+		}
+	}
+	return r, qb, nil
+}
+
+// Cholesky performs the Cholesky decomposition a=ll' of a symmetric positive definite matrix a.
+// It returns the lower triangular matrix l. Only the lower triangle of a is read.
+//
+// If a isn't positive definite, ErrNonInvertibleMatrix is returned.
+func Cholesky(ctx context.Context, a [][]float64) ([][]float64, error) {
+	if !IsRegular(a) {
+		return nil, ErrIrregularMatrix
+	}
+	n := len(a)
+	if n != len(a[0]) {
+		return nil, ErrNonInvertibleMatrix
+	}
+	l := make([][]float64, n)
+	for i := 0; i < n; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		l[i] = make([]float64, n)
+		for j := 0; j <= i; j++ {
+			s := a[i][j]
+			for k := 0; k < j; k++ {
+				s -= l[i][k] * l[j][k]
+			}
+			if i != j {
+				l[i][j] = s / l[j][j]
+				continue
+			}
+			// Pivots negligible relative to the diagonal element mean a (numerically) singular matrix.
+			if !(s > float64(n)*eps*math.Abs(a[i][i])) {
+				return nil, ErrNonInvertibleMatrix
+			}
+			l[i][i] = math.Sqrt(s)
+		}
+	}
+	return l, nil
+}
+
+// SolveCholesky solves ax=b for a symmetric positive definite matrix a with the Cholesky decomposition.
+//
+// If a isn't positive definite, ErrNonInvertibleMatrix is returned.
+func SolveCholesky(ctx context.Context, a [][]float64, b []float64) ([]float64, error) {
+	l, err := Cholesky(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	return SolveCholeskyFactor(l, b)
+}
+
+// SolveCholeskyFactor solves ax=b with the Cholesky factor l of a returned by Cholesky. It takes O(n²) time.
+func SolveCholeskyFactor(l [][]float64, b []float64) ([]float64, error) {
+	n := len(l)
+	if len(b) != n {
+		return nil, ErrOperationNotAllowed
+	}
+	// Forward substitution lz=b.
+	z := make([]float64, n)
+	for i := 0; i < n; i++ {
+		s := b[i]
+		for k := 0; k < i; k++ {
+			s -= l[i][k] * z[k]
+		}
+		z[i] = s / l[i][i]
+	}
+	// Back substitution l'x=z.
+	x := make([]float64, n)
+	for i := n - 1; i >= 0; i-- {
+		s := z[i]
+		for k := i + 1; k < n; k++ {
+			s -= l[k][i] * x[k]
+		}
+		x[i] = s / l[i][i]
+	}
+	return x, nil
+}
+
+// CholeskyCondition estimates the condition number in the 1-norm (see Condition) of a symmetric positive definite
+// matrix a from its Cholesky factor l, without inverting a.
+//
+// The 1-norm of the inverse is estimated with Hager's method, which takes a few O(n²) solves with l. The estimate
+// never exceeds the condition number and is usually equal to it or within a small factor of it.
+func CholeskyCondition(a, l [][]float64) float64 {
+	n := len(l)
+	// Start from the uniform vector of the unit 1-norm.
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = 1 / float64(n)
+	}
+	var est float64
+	// The inverse is symmetric, so solving with a gives both the inverse and its transpose times a vector.
+	for k := 0; k < 5; k++ {
+		y, _ := SolveCholeskyFactor(l, x)
+		var norm float64
+		for i, v := range y {
+			norm += math.Abs(v)
+			// y is reused as the sign vector of itself.
+			y[i] = math.Copysign(1, v)
+		}
+		if k > 0 && norm <= est {
+			break
+		}
+		est = norm
+		z, _ := SolveCholeskyFactor(l, y)
+		j, zx := 0, 0.0
+		for i, v := range z {
+			zx += v * x[i]
+			if math.Abs(v) > math.Abs(z[j]) {
+				j = i
+			}
+		}
+		// No unit vector increases the estimate, it's a local maximum.
+		if math.Abs(z[j]) <= zx {
+			break
+		}
+		for i := range x {
+			x[i] = 0
+		}
+		x[j] = 1
+	}
+	return norm1(a) * est
+}
+
+// SVD performs the thin singular value decomposition a=usv' with the one-sided Jacobi method.
+//
+// For an m x n matrix a, it returns an m x n matrix u, n singular values s in descending order
+// and an n x n orthogonal matrix v. Columns of u corresponding to zero singular values are zero.
+// If columns aren't orthogonal after maxSweeps sweeps, ErrNotConverged is returned.
+func SVD(ctx context.Context, a [][]float64) ([][]float64, []float64, [][]float64, error) {
+	return svd(ctx, a, maxSweeps)
+}
+
+// svd performs the singular value decomposition like SVD, with at most the given number of sweeps.
+func svd(ctx context.Context, a [][]float64, sweeps int) ([][]float64, []float64, [][]float64, error) {
+	if !IsRegular(a) {
+		return nil, nil, nil, ErrIrregularMatrix
+	}
+	m, n := len(a), len(a[0])
+	// Work on columns, so keep the transposed matrices.
+	u, err := Transpose(ctx, a)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	v := make([][]float64, n)
+	for j := range v {
+		v[j] = make([]float64, n)
+		v[j][j] = 1
+	}
+	// Columns shorter than tiny are numerically zero, rotating them wouldn't ever converge.
+	var fro float64
+	for j := 0; j < n; j++ {
+		for i := 0; i < m; i++ {
+			fro += u[j][i] * u[j][i]
+		}
+	}
+	tiny := eps * eps * fro
+	// Rotate pairs of columns until all of them are orthogonal.
+	for sweep := 0; ; sweep++ {
+		if sweep == sweeps {
+			return nil, nil, nil, ErrNotConverged
+		}
+		rotated := false
+		for p := 0; p < n-1; p++ {
+			// A sweep takes time proportional to mn², so check the context for every column.
+			if err := ctx.Err(); err != nil {
+				return nil, nil, nil, err
+			}
+			for q := p + 1; q < n; q++ {
+				var alpha, beta, gamma float64
+				for i := 0; i < m; i++ {
+					alpha += u[p][i] * u[p][i]
+					beta += u[q][i] * u[q][i]
+					gamma += u[p][i] * u[q][i]
+				}
+				if alpha <= tiny || beta <= tiny || math.Abs(gamma) <= eps*math.Sqrt(alpha*beta) {
+					continue
+				}
+				rotated = true
+				zeta := (beta - alpha) / (2 * gamma)
+				t := math.Copysign(1, zeta) / (math.Abs(zeta) + math.Sqrt(1+zeta*zeta))
+				c := 1 / math.Sqrt(1+t*t)
+				s := c * t
+				for i := 0; i < m; i++ {
+					up, uq := u[p][i], u[q][i]
+					u[p][i], u[q][i] = c*up-s*uq, s*up+c*uq
+				}
+				for i := 0; i < n; i++ {
+					vp, vq := v[p][i], v[q][i]
+					v[p][i], v[q][i] = c*vp-s*vq, s*vp+c*vq
+				}
+			}
+		}
+		if !rotated {
+			break
+		}
+	}
+	// Singular values are the norms of the orthogonalized columns.
+	s := make([]float64, n)
+	for j := 0; j < n; j++ {
+		var norm float64
+		for i := 0; i < m; i++ {
+			norm = math.Hypot(norm, u[j][i])
+		}
+		s[j] = norm
+		for i := 0; i < m; i++ {
+			if norm == 0 {
+				u[j][i] = 0
+			} else {
+				u[j][i] /= norm
+			}
+		}
+	}
+	// Sort singular values in descending order.
+	for j := 1; j < n; j++ {
+		for k := j; k > 0 && s[k] > s[k-1]; k-- {
+			s[k], s[k-1] = s[k-1], s[k]
+			u[k], u[k-1] = u[k-1], u[k]
+			v[k], v[k-1] = v[k-1], v[k]
+		}
+	}
+	if u, err = Transpose(ctx, u); err != nil {
+		return nil, nil, nil, err
+	}
+	if v, err = Transpose(ctx, v); err != nil {
+		return nil, nil, nil, err
+	}
+	return u, s, v, nil
+}
+
+// SolveSVD finds the minimum norm least squares solution of ax=b with the pseudo-inverse of a, x=vs⁺u'b.
+//
+// Singular values negligible relative to the largest one are treated as zeros, so it handles
+// rank-deficient matrices (e.g. linearly dependent columns).
+//
+// A matrix with more rows than columns is first reduced to the square triangular factor r of its QR decomposition,
+// which has the same singular values. Then the Jacobi sweeps take time proportional to n³ rather than mn²,
+// so the whole solution costs little more than SolveQR.
+func SolveSVD(ctx context.Context, a [][]float64, b []float64) ([]float64, error) {
+	if !IsRegular(a) {
+		return nil, ErrIrregularMatrix
+	}
+	m, n := len(a), len(a[0])
+	if len(b) != m {
+		return nil, ErrOperationNotAllowed
+	}
+	tol := float64(m) * eps
+	if n > m {
+		tol = float64(n) * eps
+	}
+	if m > n {
+		// The least squares solution of ax=b equals the one of rx=q'b.
+		r, qb, err := householder(ctx, a, b)
+		if err != nil {
+			return nil, err
+		}
+		a, b = r[:n], qb[:n]
+		m = n
+	}
+	u, s, v, err := SVD(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	tol *= s[0]
+	x := make([]float64, n)
+	for k := 0; k < n; k++ {
+		if s[k] <= tol {
+			// Singular values are sorted.
+			break
+		}
+		var c float64
+		for i := 0; i < m; i++ {
+			c += u[i][k] * b[i]
+		}
+		c /= s[k]
+		for j := 0; j < n; j++ {
+			x[j] += c * v[j][k]
+		}
+	}
+	return x, nil
+}
+
+// clone returns a deep copy of a regular matrix in contiguous storage.
+func clone(a [][]float64) [][]float64 {
+	d, err := FromRows(a)
+	if err != nil {
+		return nil
+	}
+	return d.Clone().Rows()
+}
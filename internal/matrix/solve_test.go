@@ -0,0 +1,179 @@
+package matrix
+
+import (
+	"context"
+	"testing"
+
+	"github.com/erni27/regression/internal/regressiontest"
+)
+
+var (
+	// fullRank is a design matrix of a simple linear regression.
+	fullRank  = [][]float64{{1, 1}, {1, 2}, {1, 3}, {1, 4}}
+	fullRankB = []float64{6, 5, 7, 10}
+	// rankDeficient is a design matrix with an intercept and a one-hot encoded feature, its columns are linearly dependent.
+	rankDeficient  = [][]float64{{1, 1, 0}, {1, 1, 0}, {1, 0, 1}, {1, 0, 1}}
+	rankDeficientB = []float64{1, 3, 5, 7}
+)
+
+func TestSolveQR(t *testing.T) {
+	got, err := SolveQR(context.Background(), fullRank, fullRankB)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	want := []float64{3.5, 1.4}
+	if !regressiontest.AreFloatSlicesEqual(got, want, 9) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestSolveQR_RankDeficient(t *testing.T) {
+	if _, err := SolveQR(context.Background(), rankDeficient, rankDeficientB); err != ErrNonInvertibleMatrix {
+		t.Fatalf("want %v, got %v", ErrNonInvertibleMatrix, err)
+	}
+}
+
+func TestCholesky(t *testing.T) {
+	a := [][]float64{
+		{4, 12, -16},
+		{12, 37, -43},
+		{-16, -43, 98},
+	}
+	got, err := Cholesky(context.Background(), a)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	want := [][]float64{
+		{2, 0, 0},
+		{6, 1, 0},
+		{-8, 5, 3},
+	}
+	if !regressiontest.Are2DFloatSlicesEqual(got, want, 9) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestCholesky_NotPositiveDefinite(t *testing.T) {
+	tests := []struct {
+		name string
+		a    [][]float64
+	}{
+		{name: "indefinite", a: [][]float64{{1, 2}, {2, 1}}},
+		{name: "singular", a: [][]float64{{1, 1}, {1, 1}}},
+		{name: "not square", a: [][]float64{{1, 1, 1}, {1, 1, 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Cholesky(context.Background(), tt.a); err != ErrNonInvertibleMatrix {
+				t.Fatalf("want %v, got %v", ErrNonInvertibleMatrix, err)
+			}
+		})
+	}
+}
+
+func TestSolveCholesky(t *testing.T) {
+	// The normal equation of fullRank.
+	a := [][]float64{{4, 10}, {10, 30}}
+	b := []float64{28, 77}
+	got, err := SolveCholesky(context.Background(), a, b)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	want := []float64{3.5, 1.4}
+	if !regressiontest.AreFloatSlicesEqual(got, want, 9) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestSVD(t *testing.T) {
+	tests := []struct {
+		name string
+		a    [][]float64
+		want []float64
+	}{
+		{name: "wide", a: [][]float64{{3, 2, 2}, {2, 3, -2}}, want: []float64{5, 3, 0}},
+		{name: "tall", a: [][]float64{{3, 2}, {2, 3}, {2, -2}}, want: []float64{5, 3}},
+		{name: "rank deficient", a: rankDeficient, want: []float64{2.449489743, 1.414213562, 0}},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, s, v, err := SVD(ctx, tt.a)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if !regressiontest.AreFloatSlicesEqual(s, tt.want, 9) {
+				t.Fatalf("got singular values %v, want %v", s, tt.want)
+			}
+			// Reconstruct a=usv'.
+			m, n := len(tt.a), len(tt.a[0])
+			got := make([][]float64, m)
+			for i := 0; i < m; i++ {
+				got[i] = make([]float64, n)
+				for j := 0; j < n; j++ {
+					for k := 0; k < n; k++ {
+						got[i][j] += u[i][k] * s[k] * v[j][k]
+					}
+				}
+			}
+			if !regressiontest.Are2DFloatSlicesEqual(got, tt.a, 9) {
+				t.Fatalf("got usv' %v, want %v", got, tt.a)
+			}
+			// v is orthogonal.
+			for j := 0; j < n; j++ {
+				for k := 0; k < n; k++ {
+					var p float64
+					for i := 0; i < n; i++ {
+						p += v[i][j] * v[i][k]
+					}
+					want := 0.0
+					if j == k {
+						want = 1
+					}
+					if !regressiontest.AreFloatEqual(p, want, 9) {
+						t.Fatalf("got v'v[%d][%d] %v, want %v", j, k, p, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSVD_NotConverged(t *testing.T) {
+	// Three columns aren't orthogonalized by a single sweep of rotations.
+	a := [][]float64{{4, 1, 2}, {1, 3, 1}, {2, 1, 5}}
+	if _, _, _, err := svd(context.Background(), a, 1); err != ErrNotConverged {
+		t.Fatalf("want %v, got %v", ErrNotConverged, err)
+	}
+	if _, _, _, err := svd(context.Background(), a, maxSweeps); err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+}
+
+func TestSolveSVD(t *testing.T) {
+	tests := []struct {
+		name string
+		a    [][]float64
+		b    []float64
+		want []float64
+	}{
+		{name: "full rank", a: fullRank, b: fullRankB, want: []float64{3.5, 1.4}},
+		// The minimum norm solution among all the solutions θ0+θ1=2, θ0+θ2=6.
+		{name: "rank deficient", a: rankDeficient, b: rankDeficientB, want: []float64{2.666666667, -0.666666667, 3.333333333}},
+		{name: "wide", a: [][]float64{{1, 1, 0}, {1, 0, 1}}, b: []float64{2, 6}, want: []float64{2.666666667, -0.666666667, 3.333333333}},
+		// A zero column isn't reflected by the QR reduction.
+		{name: "zero column", a: [][]float64{{1, 0}, {2, 0}, {3, 0}}, b: []float64{1, 2, 3}, want: []float64{1, 0}},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SolveSVD(ctx, tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if !regressiontest.AreFloatSlicesEqual(got, tt.want, 9) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
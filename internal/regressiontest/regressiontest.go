@@ -1,10 +1,13 @@
 package regressiontest
 
 import (
+	"context"
 	"encoding/csv"
 	"math"
+	"math/rand"
 	"os"
 	"strconv"
+	"sync/atomic"
 
 	"github.com/erni27/regression"
 )
@@ -78,3 +81,67 @@ func LoadTrainingSet(path string) (regression.TrainingSet, error) {
 	}
 	return regression.TrainingSet{X: x, Y: y}, nil
 }
+
+// ToSparse converts a training set into a sparse one, keeping only non-zero features.
+func ToSparse(s regression.TrainingSet) regression.SparseTrainingSet {
+	x := regression.SparseMatrix{Rows: len(s.X), Cols: len(s.X[0]), RowOffsets: []int{0}}
+	for _, row := range s.X {
+		for j, v := range row {
+			if v != 0 {
+				x.ColIndices = append(x.ColIndices, j)
+				x.Values = append(x.Values, v)
+			}
+		}
+		x.RowOffsets = append(x.RowOffsets, len(x.Values))
+	}
+	return regression.SparseTrainingSet{X: x, Y: s.Y}
+}
+
+// RandomTrainingSet returns m training examples with n features drawn from [-1, 1) and noisy targets
+// of a linear function. Targets are rounded to 0 or 1 if binary is true.
+func RandomTrainingSet(seed int64, m, n int, binary bool) regression.TrainingSet {
+	rnd := rand.New(rand.NewSource(seed))
+	x := make([][]float64, m)
+	y := make([]float64, m)
+	for i := range x {
+		x[i] = make([]float64, n)
+		for j := range x[i] {
+			x[i][j] = 2*rnd.Float64() - 1
+			y[i] += float64(j+1) * x[i][j]
+		}
+		y[i] += rnd.NormFloat64()
+		if binary {
+			y[i] = math.Max(0, math.Copysign(1, y[i]))
+		}
+	}
+	return regression.TrainingSet{X: x, Y: y}
+}
+
+// CountdownContext returns a context canceled once it's been checked, by calling either its Err or Done method,
+// the given number of times. It cancels a long operation at a deterministic point of its progress, regardless of timing.
+func CountdownContext(ctx context.Context, calls int) context.Context {
+	cctx, cancel := context.WithCancel(ctx)
+	return &countdownContext{Context: cctx, cancel: cancel, calls: int64(calls)}
+}
+
+type countdownContext struct {
+	context.Context
+	cancel context.CancelFunc
+	calls  int64
+}
+
+func (c *countdownContext) Done() <-chan struct{} {
+	c.countdown()
+	return c.Context.Done()
+}
+
+func (c *countdownContext) Err() error {
+	c.countdown()
+	return c.Context.Err()
+}
+
+func (c *countdownContext) countdown() {
+	if atomic.AddInt64(&c.calls, -1) < 0 {
+		c.cancel()
+	}
+}
@@ -0,0 +1,258 @@
+// Package serial contains implementation of the versioned serialization of trained models.
+//
+// A model can be encoded either as JSON or in a compact binary form. Both forms carry
+// a format version and a model kind, so a model saved by one package cannot be restored by another one.
+package serial
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/erni27/regression"
+)
+
+// Version is the current version of the serialization format.
+//
+// Version 2 adds class labels of multiclass models. Models saved in version 1 can still be decoded.
+const Version = 2
+
+// magic prefixes every model encoded in the binary form.
+var magic = []byte{'R', 'G', 'M', 0}
+
+// A Model is a serializable representation of a trained model.
+type Model struct {
+	// Version is the serialization format version.
+	Version int `json:"version"`
+	// Kind identifies the package that trained the model.
+	Kind string `json:"kind"`
+	// Coefficients are the trained model's coefficients.
+	Coefficients []float64 `json:"coefficients"`
+	// Accuracy is the trained model's accuracy.
+	Accuracy float64 `json:"accuracy"`
+	// Params holds the model specific parameters.
+	Params map[string]float64 `json:"params,omitempty"`
+	// Classes holds the class labels of a multiclass model.
+	Classes []int `json:"classes,omitempty"`
+}
+
+// MarshalJSON encodes a model as JSON. A non-finite accuracy, e.g. the coefficient of determination
+// of a constant target vector, cannot be a JSON number, so it's encoded as a string: "NaN", "+Inf" or "-Inf".
+func MarshalJSON(m Model) ([]byte, error) {
+	m.Version = Version
+	return json.Marshal(jsonModel{jsonFields: jsonFields(m), Accuracy: accuracy(m.Accuracy)})
+}
+
+// jsonFields are the fields of a model, whose accuracy is shadowed in jsonModel.
+type jsonFields Model
+
+// jsonModel is the JSON form of a model, whose accuracy may be non-finite.
+type jsonModel struct {
+	jsonFields
+	Accuracy accuracy `json:"accuracy"`
+}
+
+// An accuracy is encoded as a JSON number if it's finite, otherwise as a string.
+type accuracy float64
+
+func (a accuracy) MarshalJSON() ([]byte, error) {
+	f := float64(a)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return json.Marshal(strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	return json.Marshal(f)
+}
+
+func (a *accuracy) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return json.Unmarshal(data, (*float64)(a))
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(math.IsNaN(f) || math.IsInf(f, 0)) {
+		return regression.ErrInvalidModel
+	}
+	*a = accuracy(f)
+	return nil
+}
+
+// MarshalBinary encodes a model in the binary form.
+//
+// The binary form consists of the magic number, the format version, the model kind,
+// the accuracy, the coefficients, the parameters sorted by name and the class labels.
+// Numbers are written in the little endian byte order.
+func MarshalBinary(m Model) ([]byte, error) {
+	if len(m.Kind) > math.MaxUint8 || len(m.Params) > math.MaxUint16 {
+		return nil, regression.ErrInvalidModel
+	}
+	var b bytes.Buffer
+	b.Write(magic)
+	write(&b, uint16(Version))
+	writeString(&b, m.Kind)
+	write(&b, m.Accuracy)
+	write(&b, uint32(len(m.Coefficients)))
+	write(&b, m.Coefficients)
+	keys := make([]string, 0, len(m.Params))
+	for k := range m.Params {
+		if len(k) > math.MaxUint8 {
+			return nil, regression.ErrInvalidModel
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	write(&b, uint16(len(keys)))
+	for _, k := range keys {
+		writeString(&b, k)
+		write(&b, m.Params[k])
+	}
+	classes := make([]int64, len(m.Classes))
+	for i, c := range m.Classes {
+		classes[i] = int64(c)
+	}
+	write(&b, uint32(len(classes)))
+	write(&b, classes)
+	return b.Bytes(), nil
+}
+
+// Unmarshal decodes a model of one of the given kinds. The encoding form (JSON or binary) is detected automatically.
+func Unmarshal(data []byte, kinds ...string) (Model, error) {
+	var m Model
+	var err error
+	if bytes.HasPrefix(data, magic) {
+		m, err = unmarshalBinary(data[len(magic):])
+	} else {
+		m, err = unmarshalJSON(data)
+	}
+	if err != nil {
+		return Model{}, err
+	}
+	if len(m.Coefficients) == 0 {
+		return Model{}, regression.ErrInvalidModel
+	}
+	for _, k := range kinds {
+		if m.Kind == k {
+			return m, nil
+		}
+	}
+	return Model{}, regression.ErrModelKindMismatch
+}
+
+// isSupported checks if a model saved in the format version v can be decoded.
+func isSupported(v int) bool {
+	return v >= 1 && v <= Version
+}
+
+func unmarshalJSON(data []byte) (Model, error) {
+	var jm jsonModel
+	if err := json.Unmarshal(data, &jm); err != nil {
+		return Model{}, regression.ErrInvalidModel
+	}
+	m := Model(jm.jsonFields)
+	m.Accuracy = float64(jm.Accuracy)
+	if !isSupported(m.Version) {
+		return Model{}, regression.ErrUnsupportedModelVersion
+	}
+	return m, nil
+}
+
+func unmarshalBinary(data []byte) (Model, error) {
+	r := bytes.NewReader(data)
+	var v uint16
+	if err := read(r, &v); err != nil {
+		return Model{}, err
+	}
+	if !isSupported(int(v)) {
+		return Model{}, regression.ErrUnsupportedModelVersion
+	}
+	m := Model{Version: int(v)}
+	var err error
+	if m.Kind, err = readString(r); err != nil {
+		return Model{}, err
+	}
+	if err := read(r, &m.Accuracy); err != nil {
+		return Model{}, err
+	}
+	var n uint32
+	if err := read(r, &n); err != nil {
+		return Model{}, err
+	}
+	// Each coefficient takes 8 bytes, so a larger length means corrupted data.
+	if int64(n)*8 > int64(r.Len()) {
+		return Model{}, regression.ErrInvalidModel
+	}
+	m.Coefficients = make([]float64, n)
+	if err := read(r, m.Coefficients); err != nil {
+		return Model{}, err
+	}
+	var p uint16
+	if err := read(r, &p); err != nil {
+		return Model{}, err
+	}
+	if p > 0 {
+		m.Params = make(map[string]float64, p)
+	}
+	for i := 0; i < int(p); i++ {
+		k, err := readString(r)
+		if err != nil {
+			return Model{}, err
+		}
+		var pv float64
+		if err := read(r, &pv); err != nil {
+			return Model{}, err
+		}
+		m.Params[k] = pv
+	}
+	if v >= 2 {
+		var c uint32
+		if err := read(r, &c); err != nil {
+			return Model{}, err
+		}
+		if int64(c)*8 > int64(r.Len()) {
+			return Model{}, regression.ErrInvalidModel
+		}
+		classes := make([]int64, c)
+		if err := read(r, classes); err != nil {
+			return Model{}, err
+		}
+		for _, c := range classes {
+			m.Classes = append(m.Classes, int(c))
+		}
+	}
+	if r.Len() != 0 {
+		return Model{}, regression.ErrInvalidModel
+	}
+	return m, nil
+}
+
+func write(w io.Writer, v any) {
+	// Writing to bytes.Buffer never fails.
+	_ = binary.Write(w, binary.LittleEndian, v)
+}
+
+func writeString(w io.Writer, s string) {
+	write(w, uint8(len(s)))
+	write(w, []byte(s))
+}
+
+func read(r io.Reader, v any) error {
+	if err := binary.Read(r, binary.LittleEndian, v); err != nil {
+		return regression.ErrInvalidModel
+	}
+	return nil
+}
+
+func readString(r io.Reader) (string, error) {
+	var n uint8
+	if err := read(r, &n); err != nil {
+		return "", err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", regression.ErrInvalidModel
+	}
+	return string(b), nil
+}
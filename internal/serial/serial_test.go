@@ -0,0 +1,144 @@
+package serial
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/erni27/regression"
+)
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name  string
+		model Model
+	}{
+		{
+			name:  "without params",
+			model: Model{Kind: "linear", Coefficients: []float64{-3.896, 1.193}, Accuracy: 0.702},
+		},
+		{
+			name:  "with params",
+			model: Model{Kind: "logistic", Coefficients: []float64{-7.465, 33.217, -4.415}, Accuracy: 0.6, Params: map[string]float64{"b": 2, "a": 0.25}},
+		},
+		{
+			name:  "with classes",
+			model: Model{Kind: "multiclass", Coefficients: []float64{1, 2, 3, 4, 5, 6}, Accuracy: 0.9, Classes: []int{-1, 4, 7}},
+		},
+		{
+			name:  "with negative infinite accuracy",
+			model: Model{Kind: "linear", Coefficients: []float64{5, 0}, Accuracy: math.Inf(-1)},
+		},
+		{
+			name:  "with positive infinite accuracy",
+			model: Model{Kind: "linear", Coefficients: []float64{5, 0}, Accuracy: math.Inf(1)},
+		},
+	}
+	marshalers := []struct {
+		name    string
+		marshal func(Model) ([]byte, error)
+	}{
+		{name: "json", marshal: MarshalJSON},
+		{name: "binary", marshal: MarshalBinary},
+	}
+	for _, tt := range tests {
+		for _, m := range marshalers {
+			t.Run(tt.name+" "+m.name, func(t *testing.T) {
+				data, err := m.marshal(tt.model)
+				if err != nil {
+					t.Fatalf("want nil, got error %v", err)
+				}
+				got, err := Unmarshal(data, tt.model.Kind)
+				if err != nil {
+					t.Fatalf("want nil, got error %v", err)
+				}
+				want := tt.model
+				want.Version = Version
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("got %v, want %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestUnmarshal_Invalid(t *testing.T) {
+	valid, err := MarshalBinary(Model{Kind: "linear", Coefficients: []float64{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		kind string
+		want error
+	}{
+		{name: "kind mismatch", data: valid, kind: "logistic", want: regression.ErrModelKindMismatch},
+		{name: "truncated binary", data: valid[:len(valid)-3], kind: "linear", want: regression.ErrInvalidModel},
+		{name: "trailing bytes", data: append(append([]byte{}, valid...), 0), kind: "linear", want: regression.ErrInvalidModel},
+		{name: "unsupported binary version", data: append(append([]byte{}, magic...), 9, 0), kind: "linear", want: regression.ErrUnsupportedModelVersion},
+		{name: "malformed json", data: []byte(`{"version":1,`), kind: "linear", want: regression.ErrInvalidModel},
+		{name: "unsupported json version", data: []byte(`{"version":3,"kind":"linear","coefficients":[1]}`), kind: "linear", want: regression.ErrUnsupportedModelVersion},
+		{name: "no coefficients", data: []byte(`{"version":1,"kind":"linear","coefficients":[]}`), kind: "linear", want: regression.ErrInvalidModel},
+		{name: "non-numeric accuracy", data: []byte(`{"version":2,"kind":"linear","coefficients":[1],"accuracy":"high"}`), kind: "linear", want: regression.ErrInvalidModel},
+		{name: "finite accuracy as string", data: []byte(`{"version":2,"kind":"linear","coefficients":[1],"accuracy":"0.5"}`), kind: "linear", want: regression.ErrInvalidModel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Unmarshal(tt.data, tt.kind); err != tt.want {
+				t.Fatalf("want %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_Version1(t *testing.T) {
+	want := Model{Version: 1, Kind: "linear", Coefficients: []float64{1.5, -2}, Accuracy: 0.5}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "json",
+			data: []byte(`{"version":1,"kind":"linear","coefficients":[1.5,-2],"accuracy":0.5}`),
+		},
+		{
+			name: "binary",
+			data: []byte{
+				'R', 'G', 'M', 0, // magic
+				1, 0, // version
+				6, 'l', 'i', 'n', 'e', 'a', 'r', // kind
+				0, 0, 0, 0, 0, 0, 0xe0, 0x3f, // accuracy
+				2, 0, 0, 0, // number of coefficients
+				0, 0, 0, 0, 0, 0, 0xf8, 0x3f, // 1.5
+				0, 0, 0, 0, 0, 0, 0, 0xc0, // -2
+				0, 0, // number of params
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Unmarshal(tt.data, "linear")
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMarshalJSON_NaNAccuracy(t *testing.T) {
+	data, err := MarshalJSON(Model{Kind: "linear", Coefficients: []float64{5}, Accuracy: math.NaN()})
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	got, err := Unmarshal(data, "linear")
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if !math.IsNaN(got.Accuracy) {
+		t.Fatalf("want NaN accuracy, got %v", got.Accuracy)
+	}
+}
@@ -4,8 +4,12 @@
 package ts
 
 import (
+	"fmt"
+	"math"
+	"math/rand"
+
 	"github.com/erni27/regression"
-	"github.com/erni27/regression/internal/matrix"
+	"github.com/erni27/regression/options"
 )
 
 // AddDummy adds a dummy feature equals 1 at the beginning of a feature vector.
@@ -15,25 +19,338 @@ func AddDummy(x []float64) []float64 {
 
 // AddDummies adds a dummy feature equals 1 at the beginning of each feature vector
 // being a part of a design matrix.
+//
+// The caller's design matrix isn't modified. Feature vectors of the result share a single contiguous
+// backing array, so matrix operations on it don't copy it again and iterating over it stays cache-friendly.
+// Appending to a feature vector of the result overwrites the next one.
 func AddDummies(x [][]float64) [][]float64 {
+	if len(x) == 0 {
+		return [][]float64{}
+	}
+	n := len(x[0]) + 1
+	data := make([]float64, len(x)*n)
 	d := make([][]float64, len(x))
-	copy(d, x)
-	for i := 0; i < len(x); i++ {
-		d[i] = AddDummy(d[i])
+	for i := range x {
+		if len(x[i])+1 != n {
+			// An irregular design matrix is rejected by Validate, it's kept as it is.
+			d[i] = AddDummy(x[i])
+			continue
+		}
+		r := data[i*n : (i+1)*n]
+		r[0] = 1
+		copy(r[1:], x[i])
+		d[i] = r
 	}
 	return d
 }
 
 // Validate validates a training set.
 //
-// A training set is valid if a design matrix is valid and a target vector length
-// equals a number of rows of the design matrix.
+// A training set is valid if it isn't empty, a design matrix is regular, has more rows than features
+// and all features and targets are finite numbers, and a target vector length equals a number of rows
+// of the design matrix. Otherwise, a *regression.ValidationError wrapping regression.ErrInvalidTrainingSet
+// is returned.
 func Validate(s regression.TrainingSet) error {
-	if !matrix.IsRegular(s.X) || len(s.X) <= len(s.X[0]) {
-		return regression.ErrInvalidTrainingSet
+	if err := validate(s); err != nil {
+		return err
+	}
+	return ValidateSize(len(s.X), len(s.X[0]))
+}
+
+// ValidateSize validates the size of a training set with m rows and n features, which isn't kept in memory.
+// A training set must have more rows than features.
+func ValidateSize(m, n int) error {
+	if m == 0 {
+		return invalidTrainingSet(regression.EmptyTrainingSet, -1, -1, "")
+	}
+	if m <= n {
+		return invalidTrainingSet(regression.TooFewRows, -1, -1, fmt.Sprintf("%d rows, %d features", m, n))
+	}
+	return nil
+}
+
+// ValidateWide validates a training set which may have fewer rows than features. It checks all the conditions
+// of Validate but the number of rows, which suits regularized regressions and the minimum norm least squares
+// solution, both well-defined for more features than training examples.
+func ValidateWide(s regression.TrainingSet) error {
+	return validate(s)
+}
+
+// ValidateBinaryWide validates a training set of a binary classification which may have fewer rows than features.
+// Besides the conditions checked by ValidateWide, every target must equal either 0 or 1.
+func ValidateBinaryWide(s regression.TrainingSet) error {
+	if err := validate(s); err != nil {
+		return err
+	}
+	return validateBinary(s.Y)
+}
+
+// validate checks all the conditions of Validate, but the number of rows.
+func validate(s regression.TrainingSet) error {
+	if len(s.X) == 0 || len(s.X[0]) == 0 {
+		return invalidTrainingSet(regression.EmptyTrainingSet, -1, -1, "")
+	}
+	m, n := len(s.X), len(s.X[0])
+	if len(s.Y) != m {
+		return invalidTrainingSet(regression.LengthMismatch, -1, -1, fmt.Sprintf("%d rows, %d targets", m, len(s.Y)))
+	}
+	for i, x := range s.X {
+		if len(x) != n {
+			return invalidTrainingSet(regression.RaggedRow, i, -1, fmt.Sprintf("%d features, want %d", len(x), n))
+		}
+		for j, v := range x {
+			if !isFinite(v) {
+				return invalidTrainingSet(regression.NonFiniteFeature, i, j, "")
+			}
+		}
+		if !isFinite(s.Y[i]) {
+			return invalidTrainingSet(regression.NonFiniteTarget, i, -1, "")
+		}
+	}
+	return nil
+}
+
+// ValidateBatch validates a mini-batch of training examples of online learning with n features.
+// It checks all the conditions of Validate but the number of rows, and the number of features must equal n.
+func ValidateBatch(s regression.TrainingSet, n int) error {
+	if err := validate(s); err != nil {
+		return err
+	}
+	if len(s.X[0]) != n {
+		return invalidTrainingSet(regression.LengthMismatch, -1, -1, fmt.Sprintf("%d features, want %d", len(s.X[0]), n))
 	}
-	if len(s.X) == len(s.Y) {
+	return nil
+}
+
+// ValidateBinaryBatch validates a mini-batch of training examples of online binary classification with n features.
+// Besides the conditions checked by ValidateBatch, every target must equal either 0 or 1.
+func ValidateBinaryBatch(s regression.TrainingSet, n int) error {
+	if err := ValidateBatch(s, n); err != nil {
+		return err
+	}
+	return validateBinary(s.Y)
+}
+
+// ValidateBinary validates a training set of a binary classification. Besides the conditions checked by Validate,
+// every target must equal either 0 or 1.
+func ValidateBinary(s regression.TrainingSet) error {
+	if err := Validate(s); err != nil {
+		return err
+	}
+	return validateBinary(s.Y)
+}
+
+func validateBinary(y []float64) error {
+	for i, v := range y {
+		if v != 0 && v != 1 {
+			return invalidTrainingSet(regression.NonBinaryTarget, i, -1, fmt.Sprintf("target %v", v))
+		}
+	}
+	return nil
+}
+
+// HoldOut splits a training set, valid according to check, into the training and the validation set according
+// to early stopping options. A given validation set is validated like a training set (but it may have fewer rows
+// than features), and must have as many features as the training set. Otherwise, training examples are held out
+// at random, keeping their order, and the rest of the training set must still be valid according to check.
+//
+// If options are invalid, regression.ErrInvalidEarlyStopping is returned. If the validation set or the rest
+// of the training set is invalid, a *regression.ValidationError is returned.
+func HoldOut(e options.EarlyStopping, s regression.TrainingSet, check func(regression.TrainingSet) error) (regression.TrainingSet, regression.TrainingSet, error) {
+	if !e.IsValid() {
+		return regression.TrainingSet{}, regression.TrainingSet{}, regression.ErrInvalidEarlyStopping
+	}
+	if len(e.Validation.X) > 0 {
+		v := e.Validation
+		if err := validate(v); err != nil {
+			return regression.TrainingSet{}, regression.TrainingSet{}, inValidationSet(err)
+		}
+		if n := len(s.X[0]); len(v.X[0]) != n {
+			err := invalidTrainingSet(regression.LengthMismatch, -1, -1, fmt.Sprintf("%d features, want %d", len(v.X[0]), n))
+			return regression.TrainingSet{}, regression.TrainingSet{}, inValidationSet(err)
+		}
+		return s, v, nil
+	}
+	held := Held(e, len(s.X))
+	var t, v regression.TrainingSet
+	for i := range s.X {
+		if held[i] {
+			v.X, v.Y = append(v.X, s.X[i]), append(v.Y, s.Y[i])
+		} else {
+			t.X, t.Y = append(t.X, s.X[i]), append(t.Y, s.Y[i])
+		}
+	}
+	if err := check(t); err != nil {
+		return regression.TrainingSet{}, regression.TrainingSet{}, err
+	}
+	return t, v, nil
+}
+
+// Held reports which of m training examples HoldOut holds out at random according to valid early stopping options,
+// it returns nil if the validation set is given.
+func Held(e options.EarlyStopping, m int) []bool {
+	if len(e.Validation.X) > 0 {
 		return nil
 	}
+	k := int(math.Round(e.Fraction * float64(m)))
+	if k < 1 {
+		k = 1
+	}
+	held := make([]bool, m)
+	for _, i := range rand.New(rand.NewSource(e.Seed)).Perm(m)[:k] {
+		held[i] = true
+	}
+	return held
+}
+
+// HoldOutBinary splits a training set of a binary classification like HoldOut. Besides, every target
+// of a given validation set must equal either 0 or 1.
+func HoldOutBinary(e options.EarlyStopping, s regression.TrainingSet, check func(regression.TrainingSet) error) (regression.TrainingSet, regression.TrainingSet, error) {
+	t, v, err := HoldOut(e, s, check)
+	if err != nil {
+		return regression.TrainingSet{}, regression.TrainingSet{}, err
+	}
+	if err := validateBinary(v.Y); err != nil {
+		return regression.TrainingSet{}, regression.TrainingSet{}, inValidationSet(err)
+	}
+	return t, v, nil
+}
+
+// inValidationSet marks a validation error as referring to a validation set.
+func inValidationSet(err error) error {
+	if verr, ok := err.(*regression.ValidationError); ok {
+		if verr.Detail == "" {
+			verr.Detail = "validation set"
+		} else {
+			verr.Detail = "validation set, " + verr.Detail
+		}
+	}
+	return err
+}
+
+// ValidateFeatureVector validates a feature vector passed to a model trained on n features.
+// A feature vector is valid if it has n features and all of them are finite numbers. Otherwise,
+// a *regression.ValidationError wrapping regression.ErrInvalidFeatureVector is returned.
+func ValidateFeatureVector(x []float64, n int) error {
+	if len(x) != n {
+		return invalidFeatureVector(regression.LengthMismatch, -1, fmt.Sprintf("%d features, want %d", len(x), n))
+	}
+	for j, v := range x {
+		if !isFinite(v) {
+			return invalidFeatureVector(regression.NonFiniteFeature, j, "")
+		}
+	}
 	return nil
 }
+
+// ValidateSparseVector validates a sparse feature vector passed to a model trained on n features.
+// A sparse feature vector is valid if it has as many indices as values, its indices are strictly increasing
+// within [0, n) and all values are finite numbers.
+func ValidateSparseVector(x regression.SparseVector, n int) error {
+	if !isValidSparseVector(x, n) {
+		return invalidFeatureVector(regression.MalformedRow, -1, "")
+	}
+	for k, v := range x.Values {
+		if !isFinite(v) {
+			return invalidFeatureVector(regression.NonFiniteFeature, x.Indices[k], "")
+		}
+	}
+	return nil
+}
+
+// ValidateSparse validates a sparse training set.
+//
+// A sparse training set is valid if its design matrix is a well-formed CSR matrix with at least one row
+// and one column, all its values and targets are finite numbers, and a target vector length equals a number of rows.
+// Unlike a dense one, a sparse design matrix may have more columns than rows.
+func ValidateSparse(s regression.SparseTrainingSet) error {
+	x := s.X
+	if x.Rows < 1 || x.Cols < 1 {
+		return invalidTrainingSet(regression.EmptyTrainingSet, -1, -1, "")
+	}
+	if len(s.Y) != x.Rows {
+		return invalidTrainingSet(regression.LengthMismatch, -1, -1, fmt.Sprintf("%d rows, %d targets", x.Rows, len(s.Y)))
+	}
+	if len(x.RowOffsets) != x.Rows+1 || x.RowOffsets[0] != 0 {
+		return invalidTrainingSet(regression.MalformedRow, -1, -1, fmt.Sprintf("%d row offsets, want %d starting with 0", len(x.RowOffsets), x.Rows+1))
+	}
+	nnz := x.RowOffsets[x.Rows]
+	if len(x.ColIndices) != nnz || len(x.Values) != nnz {
+		return invalidTrainingSet(regression.MalformedRow, -1, -1, fmt.Sprintf("%d column indices and %d values, want %d", len(x.ColIndices), len(x.Values), nnz))
+	}
+	for i := 0; i < x.Rows; i++ {
+		if x.RowOffsets[i] > x.RowOffsets[i+1] {
+			return invalidTrainingSet(regression.MalformedRow, i, -1, "decreasing row offsets")
+		}
+		r := x.Row(i)
+		if !isValidSparseVector(r, x.Cols) {
+			return invalidTrainingSet(regression.MalformedRow, i, -1, "column indices out of order or out of range")
+		}
+		for k, v := range r.Values {
+			if !isFinite(v) {
+				return invalidTrainingSet(regression.NonFiniteFeature, i, r.Indices[k], "")
+			}
+		}
+		if !isFinite(s.Y[i]) {
+			return invalidTrainingSet(regression.NonFiniteTarget, i, -1, "")
+		}
+	}
+	return nil
+}
+
+// ValidateSparseBinary validates a sparse training set of a binary classification. Besides the conditions checked
+// by ValidateSparse, every target must equal either 0 or 1.
+func ValidateSparseBinary(s regression.SparseTrainingSet) error {
+	if err := ValidateSparse(s); err != nil {
+		return err
+	}
+	for i, v := range s.Y {
+		if v != 0 && v != 1 {
+			return invalidTrainingSet(regression.NonBinaryTarget, i, -1, fmt.Sprintf("target %v", v))
+		}
+	}
+	return nil
+}
+
+func invalidTrainingSet(r regression.ValidationReason, row, col int, detail string) error {
+	return &regression.ValidationError{Err: regression.ErrInvalidTrainingSet, Reason: r, Row: row, Column: col, Detail: detail}
+}
+
+func invalidFeatureVector(r regression.ValidationReason, col int, detail string) error {
+	return &regression.ValidationError{Err: regression.ErrInvalidFeatureVector, Reason: r, Row: -1, Column: col, Detail: detail}
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// Dot calculates the dot product of coefficients and a sparse feature vector with the dummy feature
+// equals 1 at the beginning, i.e. coeffs[0] is the intercept.
+//
+// If the feature vector has an index out of the range of coefficients, it returns ErrInvalidFeatureVector.
+func Dot(x regression.SparseVector, coeffs []float64) (float64, error) {
+	if len(coeffs) == 0 || !isValidSparseVector(x, len(coeffs)-1) {
+		return 0, regression.ErrInvalidFeatureVector
+	}
+	z := coeffs[0]
+	for k, j := range x.Indices {
+		z += x.Values[k] * coeffs[j+1]
+	}
+	return z, nil
+}
+
+// isValidSparseVector checks if a sparse vector has as many indices as values and its indices are
+// strictly increasing within [0, n).
+func isValidSparseVector(x regression.SparseVector, n int) bool {
+	if len(x.Indices) != len(x.Values) {
+		return false
+	}
+	prev := -1
+	for _, j := range x.Indices {
+		if j <= prev || j >= n {
+			return false
+		}
+		prev = j
+	}
+	return true
+}
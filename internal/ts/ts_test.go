@@ -1,10 +1,15 @@
 package ts
 
 import (
+	"errors"
+	"math"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/matrix"
+	"github.com/erni27/regression/options"
 )
 
 func TestAddDummy(t *testing.T) {
@@ -92,76 +97,352 @@ func TestAddDummies(t *testing.T) {
 	}
 }
 
+func TestAddDummies_Contiguous(t *testing.T) {
+	x := [][]float64{
+		{2, 3},
+		{4, 5},
+		{6, 7},
+	}
+	d := AddDummies(x)
+	m, err := matrix.FromRows(d)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	// The dense matrix is a view of the feature vectors, not a copy.
+	d[1][2] = 42
+	if got := m.At(1, 2); got != 42 {
+		t.Fatalf("got %v, want %v", got, 42)
+	}
+	if want := [][]float64{{2, 3}, {4, 5}, {6, 7}}; !reflect.DeepEqual(x, want) {
+		t.Fatalf("design matrix modified, got %v, want %v", x, want)
+	}
+}
+
+// assertValidationError checks if err is a *regression.ValidationError with the same sentinel error, reason,
+// row and column as want. A nil want means no error.
+func assertValidationError(t *testing.T, err error, want *regression.ValidationError) {
+	t.Helper()
+	if want == nil {
+		if err != nil {
+			t.Fatalf("want nil, got error %v", err)
+		}
+		return
+	}
+	var got *regression.ValidationError
+	if !errors.As(err, &got) {
+		t.Fatalf("want %v, got %v", want, err)
+	}
+	if !errors.Is(err, want.Err) || got.Reason != want.Reason || got.Row != want.Row || got.Column != want.Column {
+		t.Fatalf("want %v, got %v", want, err)
+	}
+}
+
+func invalid(r regression.ValidationReason, row, col int) *regression.ValidationError {
+	return &regression.ValidationError{Err: regression.ErrInvalidTrainingSet, Reason: r, Row: row, Column: col}
+}
+
 func TestValidate(t *testing.T) {
+	valid := func() regression.TrainingSet {
+		return regression.TrainingSet{
+			X: [][]float64{
+				{12, 5, 8, 9},
+				{11, 8, 15, 7},
+				{6, 10, 18, 6},
+				{4, 15, 33, 5},
+				{2, 22, 48, 1},
+			},
+			Y: []float64{11, 21, 43, 44, 15},
+		}
+	}
 	tests := []struct {
-		name string
-		ts   regression.TrainingSet
-		want error
+		name   string
+		modify func(s *regression.TrainingSet)
+		want   *regression.ValidationError
 	}{
+		{name: "valid", modify: func(s *regression.TrainingSet) {}},
+		{name: "zero value training set", modify: func(s *regression.TrainingSet) { *s = regression.TrainingSet{} }, want: invalid(regression.EmptyTrainingSet, -1, -1)},
+		{name: "no features", modify: func(s *regression.TrainingSet) { s.X = [][]float64{{}, {}} }, want: invalid(regression.EmptyTrainingSet, -1, -1)},
+		{name: "target vector too short", modify: func(s *regression.TrainingSet) { s.Y = s.Y[:4] }, want: invalid(regression.LengthMismatch, -1, -1)},
+		{name: "target vector too long", modify: func(s *regression.TrainingSet) { s.Y = append(s.Y, 1) }, want: invalid(regression.LengthMismatch, -1, -1)},
+		{name: "ragged row", modify: func(s *regression.TrainingSet) { s.X[1] = s.X[1][:3] }, want: invalid(regression.RaggedRow, 1, -1)},
+		{name: "nan feature", modify: func(s *regression.TrainingSet) { s.X[2][3] = math.NaN() }, want: invalid(regression.NonFiniteFeature, 2, 3)},
+		{name: "infinite feature", modify: func(s *regression.TrainingSet) { s.X[4][0] = math.Inf(-1) }, want: invalid(regression.NonFiniteFeature, 4, 0)},
+		{name: "infinite target", modify: func(s *regression.TrainingSet) { s.Y[3] = math.Inf(1) }, want: invalid(regression.NonFiniteTarget, 3, -1)},
 		{
-			name: "valid",
-			ts: regression.TrainingSet{
-				X: [][]float64{
-					{12, 5, 8, 9},
-					{11, 8, 15, 7},
-					{6, 10, 18, 6},
-					{4, 15, 33, 5},
-					{2, 22, 48, 1},
-				},
-				Y: []float64{11, 21, 43, 44, 15},
+			name: "number of features greater than number of training examples",
+			modify: func(s *regression.TrainingSet) {
+				s.X, s.Y = s.X[:3], s.Y[:3]
 			},
-			want: nil,
+			want: invalid(regression.TooFewRows, -1, -1),
 		},
 		{
-			name: "irregular matrix",
-			ts: regression.TrainingSet{
-				X: [][]float64{
-					{12, 5, 8, 9},
-					{11, 8, 15},
-					{6, 10, 18, 6},
-					{4, 15, 33, 5},
-					{2, 22, 48, 1},
-				},
-				Y: []float64{11, 21, 43, 44, 15},
+			name: "number of features equals number of training examples",
+			modify: func(s *regression.TrainingSet) {
+				s.X, s.Y = s.X[:4], s.Y[:4]
 			},
-			want: regression.ErrInvalidTrainingSet,
+			want: invalid(regression.TooFewRows, -1, -1),
 		},
-		{
-			name: "number of features greater than number of training examples",
-			ts: regression.TrainingSet{
-				X: [][]float64{
-					{12, 5, 8, 9},
-					{11, 8, 15, 7},
-					{6, 10, 18, 6},
-				},
-				Y: []float64{11, 21, 43, 44, 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid()
+			tt.modify(&s)
+			assertValidationError(t, Validate(s), tt.want)
+		})
+	}
+}
+
+func TestValidateBinary(t *testing.T) {
+	s := regression.TrainingSet{X: [][]float64{{1}, {2}, {3}}, Y: []float64{0, 1, 0}}
+	assertValidationError(t, ValidateBinary(s), nil)
+	s.Y[2] = 2
+	assertValidationError(t, ValidateBinary(s), invalid(regression.NonBinaryTarget, 2, -1))
+	s.Y = s.Y[:2]
+	assertValidationError(t, ValidateBinary(s), invalid(regression.LengthMismatch, -1, -1))
+}
+
+func TestValidateBatch(t *testing.T) {
+	// A mini-batch may have fewer rows than features.
+	s := regression.TrainingSet{X: [][]float64{{1, 2}}, Y: []float64{1}}
+	assertValidationError(t, ValidateBatch(s, 2), nil)
+	assertValidationError(t, ValidateBatch(s, 3), invalid(regression.LengthMismatch, -1, -1))
+	assertValidationError(t, ValidateBinaryBatch(s, 2), nil)
+	s.Y[0] = 0.5
+	assertValidationError(t, ValidateBinaryBatch(s, 2), invalid(regression.NonBinaryTarget, 0, -1))
+}
+
+func TestValidateWide(t *testing.T) {
+	s := regression.TrainingSet{X: [][]float64{{1, 2, 3}}, Y: []float64{1}}
+	assertValidationError(t, ValidateWide(s), nil)
+	assertValidationError(t, ValidateBinaryWide(s), nil)
+	assertValidationError(t, ValidateWide(regression.TrainingSet{}), invalid(regression.EmptyTrainingSet, -1, -1))
+	s.Y[0] = 2
+	assertValidationError(t, ValidateBinaryWide(s), invalid(regression.NonBinaryTarget, 0, -1))
+}
+
+func TestValidateSize(t *testing.T) {
+	assertValidationError(t, ValidateSize(3, 2), nil)
+	assertValidationError(t, ValidateSize(2, 2), invalid(regression.TooFewRows, -1, -1))
+	assertValidationError(t, ValidateSize(0, 2), invalid(regression.EmptyTrainingSet, -1, -1))
+}
+
+func TestValidateFeatureVector(t *testing.T) {
+	tests := []struct {
+		name string
+		x    []float64
+		want *regression.ValidationError
+	}{
+		{name: "valid", x: []float64{1, 2, 3}},
+		{name: "too short", x: []float64{1, 2}, want: &regression.ValidationError{Err: regression.ErrInvalidFeatureVector, Reason: regression.LengthMismatch, Row: -1, Column: -1}},
+		{name: "too long", x: []float64{1, 2, 3, 4}, want: &regression.ValidationError{Err: regression.ErrInvalidFeatureVector, Reason: regression.LengthMismatch, Row: -1, Column: -1}},
+		{name: "nan", x: []float64{1, math.NaN(), 3}, want: &regression.ValidationError{Err: regression.ErrInvalidFeatureVector, Reason: regression.NonFiniteFeature, Row: -1, Column: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertValidationError(t, ValidateFeatureVector(tt.x, 3), tt.want)
+		})
+	}
+}
+
+func TestValidateSparseVector(t *testing.T) {
+	tests := []struct {
+		name string
+		x    regression.SparseVector
+		want *regression.ValidationError
+	}{
+		{name: "valid", x: regression.SparseVector{Indices: []int{0, 2}, Values: []float64{1, 2}}},
+		{name: "index out of range", x: regression.SparseVector{Indices: []int{3}, Values: []float64{1}}, want: &regression.ValidationError{Err: regression.ErrInvalidFeatureVector, Reason: regression.MalformedRow, Row: -1, Column: -1}},
+		{name: "infinite value", x: regression.SparseVector{Indices: []int{1}, Values: []float64{math.Inf(1)}}, want: &regression.ValidationError{Err: regression.ErrInvalidFeatureVector, Reason: regression.NonFiniteFeature, Row: -1, Column: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertValidationError(t, ValidateSparseVector(tt.x, 3), tt.want)
+		})
+	}
+}
+
+func TestValidateSparse(t *testing.T) {
+	valid := func() regression.SparseTrainingSet {
+		return regression.SparseTrainingSet{
+			X: regression.SparseMatrix{
+				Rows:       3,
+				Cols:       5,
+				RowOffsets: []int{0, 2, 2, 3},
+				ColIndices: []int{0, 4, 2},
+				Values:     []float64{1, 2, 3},
 			},
-			want: regression.ErrInvalidTrainingSet,
+			Y: []float64{1, 2, 3},
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(s *regression.SparseTrainingSet)
+		want   *regression.ValidationError
+	}{
+		{name: "valid", modify: func(s *regression.SparseTrainingSet) {}},
+		{name: "more columns than rows", modify: func(s *regression.SparseTrainingSet) { s.X.Cols = 1000000 }},
+		{name: "zero value training set", modify: func(s *regression.SparseTrainingSet) { *s = regression.SparseTrainingSet{} }, want: invalid(regression.EmptyTrainingSet, -1, -1)},
+		{name: "target vector too short", modify: func(s *regression.SparseTrainingSet) { s.Y = s.Y[:2] }, want: invalid(regression.LengthMismatch, -1, -1)},
+		{name: "row offsets too short", modify: func(s *regression.SparseTrainingSet) { s.X.RowOffsets = s.X.RowOffsets[:3] }, want: invalid(regression.MalformedRow, -1, -1)},
+		{name: "decreasing row offsets", modify: func(s *regression.SparseTrainingSet) { s.X.RowOffsets[1] = 3; s.X.RowOffsets[2] = 1 }, want: invalid(regression.MalformedRow, 0, -1)},
+		{name: "column index out of range", modify: func(s *regression.SparseTrainingSet) { s.X.ColIndices[1] = 5 }, want: invalid(regression.MalformedRow, 0, -1)},
+		{name: "column indices not increasing", modify: func(s *regression.SparseTrainingSet) { s.X.ColIndices[1] = 0 }, want: invalid(regression.MalformedRow, 0, -1)},
+		{name: "values missing", modify: func(s *regression.SparseTrainingSet) { s.X.Values = s.X.Values[:2] }, want: invalid(regression.MalformedRow, -1, -1)},
+		{name: "nan value", modify: func(s *regression.SparseTrainingSet) { s.X.Values[2] = math.NaN() }, want: invalid(regression.NonFiniteFeature, 2, 2)},
+		{name: "nan target", modify: func(s *regression.SparseTrainingSet) { s.Y[1] = math.NaN() }, want: invalid(regression.NonFiniteTarget, 1, -1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid()
+			tt.modify(&s)
+			assertValidationError(t, ValidateSparse(s), tt.want)
+		})
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	err := error(&regression.ValidationError{Err: regression.ErrInvalidTrainingSet, Reason: regression.NonFiniteFeature, Row: 2, Column: 3})
+	if !errors.Is(err, regression.ErrInvalidTrainingSet) {
+		t.Fatalf("want %v wrapped, got %v", regression.ErrInvalidTrainingSet, err)
+	}
+	if want := "invalid training set: non-finite feature at row 2, column 3"; err.Error() != want {
+		t.Fatalf("want %q, got %q", want, err.Error())
+	}
+	err = invalidTrainingSet(regression.LengthMismatch, -1, -1, "5 rows, 4 targets")
+	if want := "invalid training set: length mismatch (5 rows, 4 targets)"; err.Error() != want {
+		t.Fatalf("want %q, got %q", want, err.Error())
+	}
+}
+
+func TestHoldOut(t *testing.T) {
+	s := regression.TrainingSet{X: make([][]float64, 10), Y: make([]float64, 10)}
+	for i := range s.X {
+		s.X[i] = []float64{float64(i)}
+		s.Y[i] = float64(i)
+	}
+	train, v, err := HoldOut(options.WithValidationSplit(0.3, 1, 5), s, Validate)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if len(train.X) != 7 || len(train.Y) != 7 || len(v.X) != 3 || len(v.Y) != 3 {
+		t.Fatalf("got %d training and %d validation examples, want %d and %d", len(train.X), len(v.X), 7, 3)
+	}
+	// Every training example is held out or not, keeping its order and its target.
+	seen := make(map[float64]bool)
+	for _, part := range []regression.TrainingSet{train, v} {
+		prev := -1.0
+		for i, x := range part.X {
+			if x[0] <= prev || part.Y[i] != x[0] || seen[x[0]] {
+				t.Fatalf("got training set %v and validation set %v", train, v)
+			}
+			prev = x[0]
+			seen[x[0]] = true
+		}
+	}
+	again, _, _ := HoldOut(options.WithValidationSplit(0.3, 1, 5), s, Validate)
+	if !reflect.DeepEqual(again, train) {
+		t.Errorf("got training set %v with the same seed, want %v", again, train)
+	}
+	// Held marks the same training examples as held out.
+	for i, h := range Held(options.WithValidationSplit(0.3, 1, 5), len(s.X)) {
+		if want := !seenIn(train, s.X[i][0]); h != want {
+			t.Errorf("got training example %d held out %v, want %v", i, h, want)
+		}
+	}
+	if held := Held(options.WithValidationSet(regression.TrainingSet{X: [][]float64{{1}}, Y: []float64{1}}, 5), len(s.X)); held != nil {
+		t.Errorf("got held out training examples %v with a given validation set, want nil", held)
+	}
+	// A given validation set is returned as it is.
+	given := regression.TrainingSet{X: [][]float64{{1}}, Y: []float64{1}}
+	if train, v, err = HoldOut(options.WithValidationSet(given, 5), s, Validate); err != nil || !reflect.DeepEqual(train, s) || !reflect.DeepEqual(v, given) {
+		t.Errorf("got training set %v, validation set %v and error %v, want %v, %v and nil", train, v, err, s, given)
+	}
+}
+
+// seenIn checks if a training set s has a training example with the first feature x.
+func seenIn(s regression.TrainingSet, x float64) bool {
+	for _, r := range s.X {
+		if r[0] == x {
+			return true
+		}
+	}
+	return false
+}
+
+func TestHoldOut_Invalid(t *testing.T) {
+	s := regression.TrainingSet{X: [][]float64{{1, 2}, {2, 1}, {3, 5}, {4, 3}}, Y: []float64{0, 1, 1, 0}}
+	tests := []struct {
+		name   string
+		e      options.EarlyStopping
+		binary bool
+		want   *regression.ValidationError
+		err    error
+	}{
+		{name: "no patience", e: options.WithValidationSplit(0.5, 1, 0), err: regression.ErrInvalidEarlyStopping},
+		{name: "negative cadence", e: options.EarlyStopping{Fraction: 0.5, Every: -1, Patience: 1}, err: regression.ErrInvalidEarlyStopping},
+		{name: "no fraction", e: options.WithValidationSplit(0, 1, 1), err: regression.ErrInvalidEarlyStopping},
+		{name: "whole training set", e: options.WithValidationSplit(1, 1, 1), err: regression.ErrInvalidEarlyStopping},
+		{name: "too few rows left", e: options.WithValidationSplit(0.5, 1, 1), want: invalid(regression.TooFewRows, -1, -1)},
+		{
+			name: "features mismatch",
+			e:    options.WithValidationSet(regression.TrainingSet{X: [][]float64{{1}}, Y: []float64{1}}, 1),
+			want: invalid(regression.LengthMismatch, -1, -1),
 		},
 		{
-			name: "target vector length does not equal number of rows in a design matrix",
-			ts: regression.TrainingSet{
-				X: [][]float64{
-					{12, 5, 8, 9},
-					{11, 8, 15, 7},
-					{6, 10, 18, 6},
-					{4, 15, 33, 5},
-					{2, 22, 48, 1},
-				},
-				Y: []float64{11, 21, 43, 44},
-			},
-			want: nil,
+			name: "nan feature",
+			e:    options.WithValidationSet(regression.TrainingSet{X: [][]float64{{1, 2}, {math.NaN(), 1}}, Y: []float64{1, 0}}, 1),
+			want: invalid(regression.NonFiniteFeature, 1, 0),
 		},
 		{
-			name: "zero value training set",
-			ts:   regression.TrainingSet{},
-			want: regression.ErrInvalidTrainingSet,
+			name:   "non-binary target",
+			e:      options.WithValidationSet(regression.TrainingSet{X: [][]float64{{1, 2}, {2, 1}}, Y: []float64{1, 2}}, 1),
+			binary: true,
+			want:   invalid(regression.NonBinaryTarget, 1, -1),
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := Validate(tt.ts); err != tt.want {
-				t.Fatalf("got %v, want %v", err, tt.want)
+			var err error
+			if tt.binary {
+				_, _, err = HoldOutBinary(tt.e, s, ValidateBinary)
+			} else {
+				_, _, err = HoldOut(tt.e, s, Validate)
+			}
+			if tt.err != nil {
+				if err != tt.err {
+					t.Fatalf("want %v, got %v", tt.err, err)
+				}
+				return
+			}
+			assertValidationError(t, err, tt.want)
+			if len(tt.e.Validation.X) > 0 && !strings.Contains(err.Error(), "validation set") {
+				t.Errorf("got %v, want the error to refer to the validation set", err)
+			}
+		})
+	}
+}
+
+func TestDot(t *testing.T) {
+	coeffs := []float64{1, 2, 3, 4}
+	tests := []struct {
+		name    string
+		x       regression.SparseVector
+		want    float64
+		wantErr error
+	}{
+		{name: "empty", x: regression.SparseVector{}, want: 1},
+		{name: "non-zero features", x: regression.SparseVector{Indices: []int{0, 2}, Values: []float64{5, -1}}, want: 1 + 10 - 4},
+		{name: "index out of range", x: regression.SparseVector{Indices: []int{3}, Values: []float64{1}}, wantErr: regression.ErrInvalidFeatureVector},
+		{name: "missing value", x: regression.SparseVector{Indices: []int{0, 1}, Values: []float64{1}}, wantErr: regression.ErrInvalidFeatureVector},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Dot(tt.x, coeffs)
+			if err != tt.wantErr {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
 			}
 		})
 	}
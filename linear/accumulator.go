@@ -0,0 +1,294 @@
+package linear
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"sync"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/ts"
+	"github.com/erni27/regression/options"
+)
+
+// accumulatorVersion is the current version of the serialization format of an accumulator.
+const accumulatorVersion = 1
+
+// accumulatorMagic prefixes every accumulator encoded in the binary form.
+var accumulatorMagic = []byte{'R', 'G', 'A', 0}
+
+// An Accumulator accumulates sufficient statistics of linear regression: XᵀX, Xᵀy, yᵀy and the number of training
+// examples, where X includes the dummy feature. They're all the normal equation and the coefficient of determination
+// need, so a training set can be fitted in a single pass without keeping it in memory.
+//
+// Training examples can be added in chunks from many goroutines, and accumulators of parts of a training set,
+// e.g. built by different workers, can be merged. An Accumulator is safe for concurrent use.
+type Accumulator struct {
+	// mu guards the number of features and the statistics, decoding replaces both.
+	mu  sync.Mutex
+	n   int
+	m   int
+	xtx [][]float64
+	xty []float64
+	yty float64
+}
+
+// NewAccumulator returns a new empty accumulator of a training set with n features.
+func NewAccumulator(n int) (*Accumulator, error) {
+	if n < 1 {
+		return nil, regression.ErrInvalidFeatureVector
+	}
+	return newAccumulator(n), nil
+}
+
+func newAccumulator(n int) *Accumulator {
+	xtx := make([][]float64, n+1)
+	for i := range xtx {
+		xtx[i] = make([]float64, n+1)
+	}
+	return &Accumulator{n: n, xtx: xtx, xty: make([]float64, n+1)}
+}
+
+// Add adds a chunk of training examples. The chunk's statistics are calculated before the accumulator is locked,
+// so chunks added from many goroutines are processed in parallel.
+func (a *Accumulator) Add(s regression.TrainingSet) error {
+	a.mu.Lock()
+	n := a.n
+	a.mu.Unlock()
+	if err := ts.ValidateBatch(s, n); err != nil {
+		return err
+	}
+	c := newAccumulator(n)
+	d := n + 1
+	for i, x := range ts.AddDummies(s.X) {
+		y := s.Y[i]
+		for j := 0; j < d; j++ {
+			// XᵀX is symmetric, only its upper triangle is summed.
+			for k := j; k < d; k++ {
+				c.xtx[j][k] += x[j] * x[k]
+			}
+			c.xty[j] += x[j] * y
+		}
+		c.yty += y * y
+	}
+	for j := 0; j < d; j++ {
+		for k := j + 1; k < d; k++ {
+			c.xtx[k][j] = c.xtx[j][k]
+		}
+	}
+	c.m = len(s.X)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.add(c)
+}
+
+// Merge adds the statistics of the accumulator b, e.g. of another part of a training set.
+// If b accumulates a different number of features, regression.ErrInvalidAccumulator is returned.
+func (a *Accumulator) Merge(b *Accumulator) error {
+	// Copy b first, so that concurrent merges in opposite directions don't deadlock.
+	c := b.clone()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.add(c)
+}
+
+// add adds the statistics of c. The caller must hold the lock. The number of features is compared under the lock,
+// since decoding may change it concurrently, then regression.ErrInvalidAccumulator is returned.
+func (a *Accumulator) add(c *Accumulator) error {
+	if a.n != c.n {
+		return regression.ErrInvalidAccumulator
+	}
+	for j := range a.xtx {
+		for k := range a.xtx[j] {
+			a.xtx[j][k] += c.xtx[j][k]
+		}
+		a.xty[j] += c.xty[j]
+	}
+	a.yty += c.yty
+	a.m += c.m
+	return nil
+}
+
+// clone returns a copy of the accumulator.
+func (a *Accumulator) clone() *Accumulator {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	c := newAccumulator(a.n)
+	for j := range a.xtx {
+		copy(c.xtx[j], a.xtx[j])
+	}
+	copy(c.xty, a.xty)
+	c.yty, c.m = a.yty, a.m
+	return c
+}
+
+// Count returns the number of accumulated training examples.
+func (a *Accumulator) Count() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.m
+}
+
+// Model solves the normal equation of the accumulated training examples. Its coefficients are the same
+// as of WithNormalEquation with the same solver for the whole training set, up to the order of summation.
+//
+// Without the design matrix, the normal equation can be solved only by forming XᵀX, so the solvers decomposing
+// the design matrix (options.SVD, the default of WithNormalEquation, and options.QR) are unsupported.
+// An optional solver chooses options.Cholesky (default) or options.Inverse. If the accumulated training set
+// doesn't have more training examples than features, a *regression.ValidationError is returned.
+//
+// The coefficient of determination is calculated from the statistics as well, see r2 for its precision.
+func (a *Accumulator) Model(ctx context.Context, solver ...options.Solver) (regression.Model[float64], error) {
+	sv := options.Cholesky
+	if len(solver) > 0 {
+		sv = solver[0]
+	}
+	if sv != options.Cholesky && sv != options.Inverse {
+		return nil, regression.ErrUnsupportedSolver
+	}
+	c := a.clone()
+	if err := ts.ValidateSize(c.m, c.n); err != nil {
+		return nil, err
+	}
+	coeffs, err := solveGram(ctx, c.xtx, c.xty, sv)
+	if err != nil {
+		return nil, err
+	}
+	return model{coeffs: coeffs, r2: c.r2(coeffs)}, nil
+}
+
+// r2 calculates the coefficient of determination of coefficients from the statistics.
+// The sum of squared residuals equals yᵀy-2θᵀXᵀy+θᵀXᵀXθ, the sum of targets is the first element of Xᵀy.
+//
+// Both sums of squares are differences of uncentered sums, so they cancel catastrophically once they're small
+// relative to yᵀy: if the mean of targets is large compared to their spread or the fit is nearly perfect,
+// the result may have few correct digits. Centering targets (and features) before adding them avoids it.
+func (a *Accumulator) r2(coeffs []float64) float64 {
+	ssr := a.yty
+	for j := range coeffs {
+		ssr -= 2 * coeffs[j] * a.xty[j]
+		for k := range coeffs {
+			ssr += coeffs[j] * a.xtx[j][k] * coeffs[k]
+		}
+	}
+	sst := a.yty - a.xty[0]*a.xty[0]/float64(a.m)
+	return 1 - ssr/sst
+}
+
+// accumulatorJSON is the JSON form of an accumulator.
+type accumulatorJSON struct {
+	Version  int         `json:"version"`
+	Features int         `json:"features"`
+	Count    int         `json:"count"`
+	XTX      [][]float64 `json:"xtx"`
+	XTY      []float64   `json:"xty"`
+	YTY      float64     `json:"yty"`
+}
+
+// MarshalJSON encodes the accumulator as JSON.
+func (a *Accumulator) MarshalJSON() ([]byte, error) {
+	c := a.clone()
+	return json.Marshal(accumulatorJSON{Version: accumulatorVersion, Features: c.n, Count: c.m, XTX: c.xtx, XTY: c.xty, YTY: c.yty})
+}
+
+// UnmarshalJSON decodes an accumulator encoded as JSON. If data is malformed, regression.ErrInvalidAccumulator
+// is returned.
+func (a *Accumulator) UnmarshalJSON(data []byte) error {
+	var j accumulatorJSON
+	if err := json.Unmarshal(data, &j); err != nil || j.Version != accumulatorVersion || j.Features < 1 {
+		return regression.ErrInvalidAccumulator
+	}
+	c := newAccumulator(j.Features)
+	if len(j.XTX) != len(c.xtx) || len(j.XTY) != len(c.xty) {
+		return regression.ErrInvalidAccumulator
+	}
+	for i := range j.XTX {
+		if len(j.XTX[i]) != len(c.xtx) {
+			return regression.ErrInvalidAccumulator
+		}
+	}
+	c.m, c.xtx, c.xty, c.yty = j.Count, j.XTX, j.XTY, j.YTY
+	return a.set(c)
+}
+
+// MarshalBinary encodes the accumulator in a compact binary form. It consists of the magic number, the format version,
+// the number of features and training examples, and XᵀX, Xᵀy and yᵀy. Numbers are written in the little endian byte order.
+func (a *Accumulator) MarshalBinary() ([]byte, error) {
+	c := a.clone()
+	var b bytes.Buffer
+	b.Write(accumulatorMagic)
+	w := func(v any) { binary.Write(&b, binary.LittleEndian, v) }
+	w(uint16(accumulatorVersion))
+	w(uint32(c.n))
+	w(uint64(c.m))
+	for _, r := range c.xtx {
+		w(r)
+	}
+	w(c.xty)
+	w(c.yty)
+	return b.Bytes(), nil
+}
+
+// UnmarshalBinary decodes an accumulator encoded in the binary form. If data is malformed,
+// regression.ErrInvalidAccumulator is returned.
+func (a *Accumulator) UnmarshalBinary(data []byte) error {
+	if !bytes.HasPrefix(data, accumulatorMagic) {
+		return regression.ErrInvalidAccumulator
+	}
+	r := bytes.NewReader(data[len(accumulatorMagic):])
+	var h struct {
+		Version uint16
+		N       uint32
+		M       uint64
+	}
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil || h.Version != accumulatorVersion || h.N < 1 || h.M > math.MaxInt {
+		return regression.ErrInvalidAccumulator
+	}
+	// Check the length of the statistics before allocating them.
+	if d := uint64(h.N) + 1; d > uint64(r.Len()/8) || (d*d+d+1)*8 != uint64(r.Len()) {
+		return regression.ErrInvalidAccumulator
+	}
+	c := newAccumulator(int(h.N))
+	c.m = int(h.M)
+	for _, row := range c.xtx {
+		if err := binary.Read(r, binary.LittleEndian, row); err != nil {
+			return regression.ErrInvalidAccumulator
+		}
+	}
+	if err := binary.Read(r, binary.LittleEndian, c.xty); err != nil {
+		return regression.ErrInvalidAccumulator
+	}
+	if err := binary.Read(r, binary.LittleEndian, &c.yty); err != nil {
+		return regression.ErrInvalidAccumulator
+	}
+	return a.set(c)
+}
+
+// set replaces the statistics with the decoded ones of c after checking they're consistent.
+func (a *Accumulator) set(c *Accumulator) error {
+	if c.m < 0 || !isFiniteStatistics(c) {
+		return regression.ErrInvalidAccumulator
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.n, a.m, a.xtx, a.xty, a.yty = c.n, c.m, c.xtx, c.xty, c.yty
+	return nil
+}
+
+// isFiniteStatistics checks if all the statistics are finite numbers and XᵀX is symmetric.
+func isFiniteStatistics(c *Accumulator) bool {
+	finite := func(v float64) bool { return !math.IsNaN(v) && !math.IsInf(v, 0) }
+	for j := range c.xtx {
+		for k := range c.xtx[j] {
+			if !finite(c.xtx[j][k]) || c.xtx[j][k] != c.xtx[k][j] {
+				return false
+			}
+		}
+		if !finite(c.xty[j]) {
+			return false
+		}
+	}
+	return finite(c.yty)
+}
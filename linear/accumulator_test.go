@@ -0,0 +1,221 @@
+package linear
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
+	"github.com/erni27/regression/options"
+)
+
+func TestAccumulator_Model(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "n=1 m=97", path: "n=1_m=97.txt"},
+		{name: "n=2 m=47", path: "n=2_m=47.txt"},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := regressiontest.LoadTrainingSet(tt.path)
+			if err != nil {
+				t.Fatalf("cannot load training set %v", err)
+			}
+			// Two workers accumulate halves of the training set, adding chunks from many goroutines.
+			n := len(s.X[0])
+			workers := make([]*Accumulator, 2)
+			for w := range workers {
+				if workers[w], err = NewAccumulator(n); err != nil {
+					t.Fatalf("want nil, got error %v", err)
+				}
+			}
+			var wg sync.WaitGroup
+			for lo := 0; lo < len(s.X); lo += 5 {
+				hi := lo + 5
+				if hi > len(s.X) {
+					hi = len(s.X)
+				}
+				a := workers[lo/5%2]
+				wg.Add(1)
+				go func(lo, hi int) {
+					defer wg.Done()
+					if err := a.Add(regression.TrainingSet{X: s.X[lo:hi], Y: s.Y[lo:hi]}); err != nil {
+						t.Errorf("want nil, got error %v", err)
+					}
+				}(lo, hi)
+			}
+			wg.Wait()
+			if err := workers[0].Merge(workers[1]); err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if c := workers[0].Count(); c != len(s.X) {
+				t.Fatalf("got %d training examples, want %d", c, len(s.X))
+			}
+			for _, sv := range []options.Solver{options.Cholesky, options.Inverse} {
+				want, err := WithNormalEquation(sv).Run(ctx, s)
+				if err != nil {
+					t.Fatalf("want nil, got error %v", err)
+				}
+				got, err := workers[0].Model(ctx, sv)
+				if err != nil {
+					t.Fatalf("want nil, got error %v", err)
+				}
+				if !regressiontest.AreFloatSlicesEqual(got.Coefficients(), want.Coefficients(), 6) {
+					t.Errorf("got coefficients %v, want %v", got.Coefficients(), want.Coefficients())
+				}
+				if !regressiontest.AreFloatEqual(got.Accuracy(), want.Accuracy(), 9) {
+					t.Errorf("got accuracy %v, want %v", got.Accuracy(), want.Accuracy())
+				}
+			}
+		})
+	}
+}
+
+func TestAccumulator_Marshal(t *testing.T) {
+	s, err := regressiontest.LoadTrainingSet("n=2_m=47.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	a, err := NewAccumulator(2)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if err := a.Add(s); err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	ctx := context.Background()
+	want, err := a.Model(ctx)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	jsonData, err := json.Marshal(a)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	binaryData, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	decoders := []struct {
+		name   string
+		decode func(*Accumulator) error
+	}{
+		{name: "json", decode: func(b *Accumulator) error { return json.Unmarshal(jsonData, b) }},
+		{name: "binary", decode: func(b *Accumulator) error { return b.UnmarshalBinary(binaryData) }},
+	}
+	for _, d := range decoders {
+		t.Run(d.name, func(t *testing.T) {
+			var b Accumulator
+			if err := d.decode(&b); err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			got, err := b.Model(ctx)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if !regressiontest.AreFloatSlicesEqual(got.Coefficients(), want.Coefficients(), 9) || got.Accuracy() != want.Accuracy() {
+				t.Errorf("got %v with accuracy %v, want %v with accuracy %v", got.Coefficients(), got.Accuracy(), want.Coefficients(), want.Accuracy())
+			}
+		})
+	}
+	malformed := []struct {
+		name   string
+		decode func(*Accumulator) error
+	}{
+		{name: "json syntax", decode: func(b *Accumulator) error { return b.UnmarshalJSON([]byte(`{"version":1`)) }},
+		{name: "json version", decode: func(b *Accumulator) error { return json.Unmarshal([]byte(`{"version":7,"features":1}`), b) }},
+		{name: "json dimensions", decode: func(b *Accumulator) error {
+			return json.Unmarshal([]byte(`{"version":1,"features":1,"count":2,"xtx":[[1,2]],"xty":[1,2],"yty":1}`), b)
+		}},
+		{name: "json asymmetric", decode: func(b *Accumulator) error {
+			return json.Unmarshal([]byte(`{"version":1,"features":1,"count":2,"xtx":[[1,2],[3,4]],"xty":[1,2],"yty":1}`), b)
+		}},
+		{name: "binary magic", decode: func(b *Accumulator) error { return b.UnmarshalBinary([]byte("RGM\x00")) }},
+		{name: "binary truncated", decode: func(b *Accumulator) error { return b.UnmarshalBinary(binaryData[:len(binaryData)-1]) }},
+		{name: "binary trailing", decode: func(b *Accumulator) error { return b.UnmarshalBinary(append(binaryData, 0)) }},
+	}
+	for _, m := range malformed {
+		t.Run(m.name, func(t *testing.T) {
+			var b Accumulator
+			if err := m.decode(&b); !errors.Is(err, regression.ErrInvalidAccumulator) {
+				t.Fatalf("want %v, got %v", regression.ErrInvalidAccumulator, err)
+			}
+		})
+	}
+}
+
+func TestAccumulator_Invalid(t *testing.T) {
+	if _, err := NewAccumulator(0); err != regression.ErrInvalidFeatureVector {
+		t.Errorf("want %v, got %v", regression.ErrInvalidFeatureVector, err)
+	}
+	a, _ := NewAccumulator(2)
+	b, _ := NewAccumulator(1)
+	if err := a.Merge(b); err != regression.ErrInvalidAccumulator {
+		t.Errorf("want %v, got %v", regression.ErrInvalidAccumulator, err)
+	}
+	var verr *regression.ValidationError
+	if err := a.Add(regression.TrainingSet{X: [][]float64{{1}}, Y: []float64{1}}); !errors.As(err, &verr) || verr.Reason != regression.LengthMismatch {
+		t.Errorf("want %v, got %v", regression.LengthMismatch, err)
+	}
+	ctx := context.Background()
+	if _, err := a.Model(ctx); !errors.As(err, &verr) || verr.Reason != regression.EmptyTrainingSet {
+		t.Errorf("want %v, got %v", regression.EmptyTrainingSet, err)
+	}
+	if err := a.Add(regression.TrainingSet{X: [][]float64{{1, 2}, {2, 1}}, Y: []float64{1, 2}}); err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if _, err := a.Model(ctx); !errors.As(err, &verr) || verr.Reason != regression.TooFewRows {
+		t.Errorf("want %v, got %v", regression.TooFewRows, err)
+	}
+	for _, sv := range []options.Solver{options.SVD, options.QR} {
+		if _, err := a.Model(ctx, sv); err != regression.ErrUnsupportedSolver {
+			t.Errorf("want %v, got %v", regression.ErrUnsupportedSolver, err)
+		}
+	}
+}
+
+func TestAccumulator_ConcurrentDecode(t *testing.T) {
+	a, _ := NewAccumulator(1)
+	b, _ := NewAccumulator(1)
+	if err := b.Add(regression.TrainingSet{X: [][]float64{{1}, {2}}, Y: []float64{1, 2}}); err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	wide, _ := NewAccumulator(2)
+	data, err := json.Marshal(wide)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	// Decoding replaces the number of features of a while b is merged and chunks are added, which either succeed
+	// or find out the mismatch under the lock.
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		if err := a.UnmarshalJSON(data); err != nil {
+			t.Errorf("want nil, got error %v", err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if err := a.Merge(b); err != nil && err != regression.ErrInvalidAccumulator {
+			t.Errorf("want nil or %v, got %v", regression.ErrInvalidAccumulator, err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		var verr *regression.ValidationError
+		if err := a.Add(regression.TrainingSet{X: [][]float64{{3}}, Y: []float64{3}}); err != nil && err != regression.ErrInvalidAccumulator && !errors.As(err, &verr) {
+			t.Errorf("want nil, %v or a validation error, got %v", regression.ErrInvalidAccumulator, err)
+		}
+	}()
+	wg.Wait()
+	if c := a.Count(); c != 0 {
+		t.Errorf("got %d training examples after decoding an empty accumulator, want 0", c)
+	}
+}
@@ -2,29 +2,64 @@ package linear
 
 import (
 	"context"
+	"math"
 
 	"github.com/erni27/regression"
 	"github.com/erni27/regression/internal/long"
 	"github.com/erni27/regression/internal/matrix"
 	"github.com/erni27/regression/internal/ts"
+	"github.com/erni27/regression/options"
 )
 
+// maxCondition is the largest condition number of XᵀX accepted by solvers forming it explicitly.
+// Beyond it, fewer than 4 significant digits of coefficients can be trusted.
+const maxCondition = 1e12
+
 // WithNormalEquation initializes linear regression with analytical approach.
 // It directly finds the value of coefficients by solving normal equation.
-func WithNormalEquation() regression.Regression[float64] {
-	var f regression.RegressionFunc[float64] = analytical
+//
+// An optional solver chooses the method solving the normal equation, options.SVD is used by default.
+// Only the first solver is taken into account.
+func WithNormalEquation(solver ...options.Solver) regression.Regression[float64] {
+	var sv options.Solver
+	if len(solver) > 0 {
+		sv = solver[0]
+	}
+	var f regression.RegressionFunc[float64] = func(ctx context.Context, s regression.TrainingSet) (regression.Model[float64], error) {
+		return analytical(ctx, s, 0, sv)
+	}
+	return f
+}
+
+// WithRidge initializes ridge regression (linear regression with L2 regularization) with analytical approach.
+// It directly finds the value of coefficients by solving the regularized normal equation.
+//
+// The l2 is the regularization strength. It shrinks the coefficients (except the intercept) towards zero
+// and makes the normal equation solvable for collinear features.
+func WithRidge(l2 float64) regression.Regression[float64] {
+	var f regression.RegressionFunc[float64] = func(ctx context.Context, s regression.TrainingSet) (regression.Model[float64], error) {
+		return analytical(ctx, s, l2, options.SVD)
+	}
 	return f
 }
 
 // analytical runs linear regression for given training set. It uses an analytical approach
-// for computing coefficients (normal equation).
-func analytical(ctx context.Context, s regression.TrainingSet) (regression.Model[float64], error) {
-	if err := ts.Validate(s); err != nil {
+// for computing coefficients (normal equation) with l2 regularization strength and the solver sv.
+func analytical(ctx context.Context, s regression.TrainingSet, l2 float64, sv options.Solver) (regression.Model[float64], error) {
+	if !options.IsValidRegularization(l2) {
+		return nil, regression.ErrInvalidRegularization
+	}
+	// Regularization and the minimum norm solution of SVD don't need more training examples than features.
+	validate := ts.Validate
+	if l2 > 0 || sv == options.SVD {
+		validate = ts.ValidateWide
+	}
+	if err := validate(s); err != nil {
 		return nil, err
 	}
 	x := ts.AddDummies(s.X)
 	y := s.Y
-	coeffs, err := solveNormalEquation(ctx, x, y)
+	coeffs, err := long.Run(ctx, func() ([]float64, error) { return solveNormalEquation(ctx, x, y, l2, sv) })
 	if err != nil {
 		return nil, err
 	}
@@ -35,30 +70,94 @@ func analytical(ctx context.Context, s regression.TrainingSet) (regression.Model
 	return model{coeffs: coeffs, r2: r2}, nil
 }
 
-// solveNormalEquation solves the normal equation for given design matrix and target vector.
+// solveNormalEquation solves the normal equation for given design matrix and target vector with the solver sv.
 //
 // The normal equation minimizes the cost function for linear regression (LMS) by explicitly taking its derivatives
-// with respect to the coefficients and setting them to zero.
-func solveNormalEquation(ctx context.Context, x [][]float64, y []float64) ([]float64, error) {
+// with respect to the coefficients and setting them to zero. It's equivalent to the linear least squares problem
+// min|Xθ-y|, which QR and SVD solvers solve directly on the design matrix, while Cholesky and Inverse solvers form XᵀX.
+//
+// If l2 is positive, the regularized normal equation is solved. The regularization adds l2 to the main diagonal
+// of XᵀX, except the element corresponding to the intercept. It's equivalent to appending rows sqrt(l2) times
+// the identity matrix (without the intercept row) to X and zeros to y.
+func solveNormalEquation(ctx context.Context, x [][]float64, y []float64, l2 float64, sv options.Solver) ([]float64, error) {
+	if l2 > 0 {
+		x, y = augment(x, y, l2)
+	}
+	switch sv {
+	case options.SVD:
+		// The pseudo-inverse drops singular values below its tolerance, which bounds the condition number
+		// of the part of the problem actually solved, so there is nothing to report.
+		return matrix.SolveSVD(ctx, x, y)
+	case options.QR:
+		return matrix.SolveQR(ctx, x, y)
+	case options.Cholesky, options.Inverse:
+		return solveSquared(ctx, x, y, sv)
+	default:
+		return nil, regression.ErrUnsupportedSolver
+	}
+}
+
+// solveSquared solves the normal equation XᵀXθ=Xᵀy with the solver sv, which forms XᵀX explicitly.
+//
+// Forming XᵀX squares the condition number of X, so if the condition number of XᵀX exceeds maxCondition,
+// ErrIllConditioned is returned instead of inaccurate coefficients.
+func solveSquared(ctx context.Context, x [][]float64, y []float64, sv options.Solver) ([]float64, error) {
 	xt, err := matrix.Transpose(ctx, x)
 	if err != nil {
 		return nil, err
 	}
-	p, err := long.Run(ctx, func() ([][]float64, error) { return matrix.Multiply(ctx, xt, x) })
+	p, err := matrix.Multiply(ctx, xt, x)
 	if err != nil {
 		return nil, err
 	}
-	p, err = long.Run(ctx, func() ([][]float64, error) { return matrix.Inverse(ctx, p) })
+	b, err := matrix.MultiplyByVector(ctx, xt, y)
 	if err != nil {
 		return nil, err
 	}
-	p, err = long.Run(ctx, func() ([][]float64, error) { return matrix.Multiply(ctx, p, xt) })
-	if err != nil {
-		return nil, err
+	return solveGram(ctx, p, b, sv)
+}
+
+// solveGram solves the normal equation with the Gram matrix p=XᵀX and the vector b=Xᵀy with the solver sv,
+// either options.Cholesky or options.Inverse. If the condition number of p exceeds maxCondition, ErrIllConditioned
+// is returned.
+//
+// The condition number comes from the factorization solving the equation: it's estimated from the Cholesky factor
+// in O(n²), or calculated exactly from the inverse.
+func solveGram(ctx context.Context, p [][]float64, b []float64, sv options.Solver) ([]float64, error) {
+	if sv == options.Cholesky {
+		l, err := matrix.Cholesky(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		if matrix.CholeskyCondition(p, l) > maxCondition {
+			return nil, regression.ErrIllConditioned
+		}
+		return matrix.SolveCholeskyFactor(l, b)
 	}
-	o, err := long.Run(ctx, func() ([]float64, error) { return matrix.MultiplyByVector(ctx, p, y) })
+	inv, c, err := matrix.InverseCondition(ctx, p)
 	if err != nil {
 		return nil, err
 	}
-	return o, nil
+	if c > maxCondition {
+		return nil, regression.ErrIllConditioned
+	}
+	return matrix.MultiplyByVector(ctx, inv, b)
+}
+
+// augment returns the design matrix and target vector of the least squares problem equivalent to
+// the regularized normal equation with l2 regularization strength.
+func augment(x [][]float64, y []float64, l2 float64) ([][]float64, []float64) {
+	m, n := len(x), len(x[0])
+	ax := make([][]float64, m, m+n-1)
+	copy(ax, x)
+	ay := make([]float64, m, m+n-1)
+	copy(ay, y)
+	r := math.Sqrt(l2)
+	for j := 1; j < n; j++ {
+		row := make([]float64, n)
+		row[j] = r
+		ax = append(ax, row)
+		ay = append(ay, 0)
+	}
+	return ax, ay
 }
@@ -2,9 +2,14 @@ package linear
 
 import (
 	"context"
+	"fmt"
+	"math/rand"
 	"testing"
 
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/matrix"
 	"github.com/erni27/regression/internal/regressiontest"
+	"github.com/erni27/regression/options"
 )
 
 func TestRun_WithNormalEquation(t *testing.T) {
@@ -28,7 +33,128 @@ func TestRun_WithNormalEquation(t *testing.T) {
 			want: expected{r2: 0.733, coeffs: []float64{89597.91, 139.211, -8738.019}},
 		},
 	}
-	r := WithNormalEquation()
+	solvers := []struct {
+		name string
+		r    regression.Regression[float64]
+	}{
+		{name: "default", r: WithNormalEquation()},
+		{name: "svd", r: WithNormalEquation(options.SVD)},
+		{name: "qr", r: WithNormalEquation(options.QR)},
+		{name: "cholesky", r: WithNormalEquation(options.Cholesky)},
+		{name: "inverse", r: WithNormalEquation(options.Inverse)},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		for _, sv := range solvers {
+			t.Run(tt.name+" "+sv.name, func(t *testing.T) {
+				s, err := regressiontest.LoadTrainingSet(tt.path)
+				if err != nil {
+					t.Fatalf("cannot load training set %v", err)
+				}
+				got, err := sv.r.Run(ctx, s)
+				if err != nil {
+					t.Fatalf("want nil, got error %v", err)
+				}
+				coeffs := got.Coefficients()
+				if !regressiontest.AreFloatSlicesEqual(coeffs, tt.want.coeffs, 3) {
+					t.Errorf("got coefficients %v, want %v", coeffs, tt.want.coeffs)
+				}
+				r2 := got.Accuracy()
+				if !regressiontest.AreFloatEqual(r2, tt.want.r2, 3) {
+					t.Errorf("got r2 %v, want %v", r2, tt.want.r2)
+				}
+			})
+		}
+	}
+}
+
+func TestRun_WithNormalEquation_CollinearFeatures(t *testing.T) {
+	// The second and third features are one-hot encoded, so together they duplicate the intercept.
+	s := regression.TrainingSet{
+		X: [][]float64{{1, 1, 0}, {2, 1, 0}, {3, 0, 1}, {4, 0, 1}, {5, 0, 1}},
+		Y: []float64{3, 5, 10, 12, 14},
+	}
+	ctx := context.Background()
+	got, err := WithNormalEquation().Run(ctx, s)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	// The minimum norm solution of y=2x1+1*(first category)+4*(second category).
+	want := []float64{5.0 / 3, 2, -2.0 / 3, 7.0 / 3}
+	if coeffs := got.Coefficients(); !regressiontest.AreFloatSlicesEqual(coeffs, want, 6) {
+		t.Errorf("got coefficients %v, want %v", coeffs, want)
+	}
+	if r2 := got.Accuracy(); !regressiontest.AreFloatEqual(r2, 1, 6) {
+		t.Errorf("got r2 %v, want %v", r2, 1)
+	}
+	for _, sv := range []options.Solver{options.QR, options.Cholesky, options.Inverse} {
+		if _, err := WithNormalEquation(sv).Run(ctx, s); err != matrix.ErrNonInvertibleMatrix {
+			t.Errorf("solver %v: want %v, got %v", sv, matrix.ErrNonInvertibleMatrix, err)
+		}
+	}
+}
+
+func TestRun_WithNormalEquation_IllConditioned(t *testing.T) {
+	// The second feature differs from the first one by less than 1e-6.
+	s := regression.TrainingSet{
+		X: [][]float64{{1, 1 + 3e-7}, {2, 2 - 1e-7}, {3, 3 + 2e-7}, {4, 4 - 4e-7}, {5, 5}},
+		Y: []float64{3, 5, 7, 9, 11},
+	}
+	ctx := context.Background()
+	for _, sv := range []options.Solver{options.Cholesky, options.Inverse} {
+		if _, err := WithNormalEquation(sv).Run(ctx, s); err != regression.ErrIllConditioned {
+			t.Errorf("solver %v: want %v, got %v", sv, regression.ErrIllConditioned, err)
+		}
+	}
+	got, err := WithNormalEquation(options.SVD).Run(ctx, s)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if r2 := got.Accuracy(); !regressiontest.AreFloatEqual(r2, 1, 6) {
+		t.Errorf("got r2 %v, want %v", r2, 1)
+	}
+}
+
+func TestRun_WithNormalEquation_UnsupportedSolver(t *testing.T) {
+	s, err := regressiontest.LoadTrainingSet("n=1_m=97.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	if _, err := WithNormalEquation(options.Solver(-1)).Run(context.Background(), s); err != regression.ErrUnsupportedSolver {
+		t.Fatalf("want %v, got %v", regression.ErrUnsupportedSolver, err)
+	}
+}
+
+func TestRun_WithRidge(t *testing.T) {
+	type expected struct {
+		r2     float64
+		coeffs []float64
+	}
+	tests := []struct {
+		name string
+		path string
+		l2   float64
+		want expected
+	}{
+		{
+			name: "n=1 m=97 lambda=0",
+			path: "n=1_m=97.txt",
+			l2:   0,
+			want: expected{r2: 0.702, coeffs: []float64{-3.896, 1.193}},
+		},
+		{
+			name: "n=1 m=97 lambda=1000",
+			path: "n=1_m=97.txt",
+			l2:   1000,
+			want: expected{r2: 0.584, coeffs: []float64{0.098, 0.704}},
+		},
+		{
+			name: "n=2 m=47 lambda=1000000",
+			path: "n=2_m=47.txt",
+			l2:   1e6,
+			want: expected{r2: 0.730, coeffs: []float64{80226.75, 130.049, -0.09}},
+		},
+	}
 	ctx := context.Background()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -36,7 +162,7 @@ func TestRun_WithNormalEquation(t *testing.T) {
 			if err != nil {
 				t.Fatalf("cannot load training set %v", err)
 			}
-			got, err := r.Run(ctx, s)
+			got, err := WithRidge(tt.l2).Run(ctx, s)
 			if err != nil {
 				t.Fatalf("want nil, got error %v", err)
 			}
@@ -51,3 +177,71 @@ func TestRun_WithNormalEquation(t *testing.T) {
 		})
 	}
 }
+
+func TestRun_WithRidge_InvalidRegularization(t *testing.T) {
+	s, err := regressiontest.LoadTrainingSet("n=1_m=97.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	if _, err := WithRidge(-1).Run(context.Background(), s); err != regression.ErrInvalidRegularization {
+		t.Fatalf("want %v, got %v", regression.ErrInvalidRegularization, err)
+	}
+}
+
+func TestRun_WithNormalEquation_ContextCanceled(t *testing.T) {
+	s := regressiontest.RandomTrainingSet(1, 500, 50, false)
+	tests := []struct {
+		name string
+		sv   options.Solver
+	}{
+		{name: "svd", sv: options.SVD},
+		{name: "qr", sv: options.QR},
+		{name: "cholesky", sv: options.Cholesky},
+		{name: "inverse", sv: options.Inverse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The context is done in the middle of the decomposition, after a few checks.
+			ctx := regressiontest.CountdownContext(context.Background(), 3)
+			if _, err := WithNormalEquation(tt.sv).Run(ctx, s); err != context.Canceled {
+				t.Fatalf("want %v, got %v", context.Canceled, err)
+			}
+		})
+	}
+}
+
+func BenchmarkRun_WithNormalEquation(b *testing.B) {
+	sizes := []struct{ m, n int }{{m: 1000, n: 10}, {m: 10000, n: 50}}
+	solvers := []struct {
+		name string
+		sv   options.Solver
+	}{
+		{name: "svd", sv: options.SVD},
+		{name: "qr", sv: options.QR},
+		{name: "cholesky", sv: options.Cholesky},
+		{name: "inverse", sv: options.Inverse},
+	}
+	ctx := context.Background()
+	for _, size := range sizes {
+		rnd := rand.New(rand.NewSource(1))
+		s := regression.TrainingSet{X: make([][]float64, size.m), Y: make([]float64, size.m)}
+		for i := range s.X {
+			s.X[i] = make([]float64, size.n)
+			for j := range s.X[i] {
+				s.X[i][j] = 2*rnd.Float64() - 1
+				s.Y[i] += float64(j+1) * s.X[i][j]
+			}
+			s.Y[i] += rnd.NormFloat64()
+		}
+		for _, sv := range solvers {
+			b.Run(fmt.Sprintf("%s m=%d n=%d", sv.name, size.m, size.n), func(b *testing.B) {
+				r := WithNormalEquation(sv.sv)
+				for i := 0; i < b.N; i++ {
+					if _, err := r.Run(ctx, s); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
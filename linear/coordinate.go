@@ -0,0 +1,54 @@
+package linear
+
+import (
+	"context"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/cd"
+	"github.com/erni27/regression/internal/long"
+	"github.com/erni27/regression/internal/ts"
+	"github.com/erni27/regression/options"
+)
+
+// WithLasso initializes lasso regression (linear regression with L1 regularization).
+// It finds the value of coefficients through coordinate descent.
+//
+// The l1 is the regularization strength. The L1 penalty drives coefficients of irrelevant features
+// to exactly zero, so lasso regression can be used for feature selection. The intercept is never penalized.
+func WithLasso(l1 float64) regression.Regression[float64] {
+	return WithElasticNet(l1, 0)
+}
+
+// WithElasticNet initializes elastic net regression (linear regression with both L1 and L2 regularization).
+// It finds the value of coefficients through coordinate descent.
+//
+// The l1 and l2 are regularization strengths of the L1 and L2 penalty respectively. They're scaled in the same way
+// as in WithLasso and WithRidge. The intercept is never penalized.
+func WithElasticNet(l1, l2 float64) regression.Regression[float64] {
+	var f regression.RegressionFunc[float64] = func(ctx context.Context, s regression.TrainingSet) (regression.Model[float64], error) {
+		return coordinate(ctx, s, cd.Penalty{L1: l1, L2: l2})
+	}
+	return f
+}
+
+// coordinate runs linear regression for given training set. It uses coordinate descent
+// for computing elastic net regularized coefficients.
+func coordinate(ctx context.Context, s regression.TrainingSet, p cd.Penalty) (regression.Model[float64], error) {
+	if !options.IsValidRegularization(p.L1) || !options.IsValidRegularization(p.L2) {
+		return nil, regression.ErrInvalidRegularization
+	}
+	if err := ts.ValidateWide(s); err != nil {
+		return nil, err
+	}
+	x := ts.AddDummies(s.X)
+	y := s.Y
+	coeffs, err := long.Run(ctx, func() ([]float64, error) { return cd.LeastSquares(ctx, x, y, nil, p) })
+	if err != nil {
+		return nil, err
+	}
+	r2, err := calcR2(x, y, coeffs)
+	if err != nil {
+		return nil, err
+	}
+	return model{coeffs: coeffs, r2: r2}, nil
+}
@@ -0,0 +1,92 @@
+package linear
+
+import (
+	"context"
+	"testing"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
+)
+
+func TestRun_WithElasticNet(t *testing.T) {
+	type expected struct {
+		r2     float64
+		coeffs []float64
+	}
+	tests := []struct {
+		name string
+		path string
+		r    regression.Regression[float64]
+		want expected
+	}{
+		{
+			name: "lasso n=1 m=97 lambda=0",
+			path: "n=1_m=97.txt",
+			r:    WithLasso(0),
+			want: expected{r2: 0.702, coeffs: []float64{-3.896, 1.193}},
+		},
+		{
+			name: "lasso n=1 m=97 lambda=100",
+			path: "n=1_m=97.txt",
+			r:    WithLasso(100),
+			want: expected{r2: 0.7, coeffs: []float64{-3.328, 1.123}},
+		},
+		{
+			name: "lasso n=1 m=97 lambda=100000",
+			path: "n=1_m=97.txt",
+			r:    WithLasso(100000),
+			want: expected{r2: 0, coeffs: []float64{5.839, 0}},
+		},
+		{
+			name: "lasso n=2 m=47 lambda=1000000",
+			path: "n=2_m=47.txt",
+			r:    WithLasso(1e6),
+			want: expected{r2: 0.731, coeffs: []float64{71339.359, 134.491, 0}},
+		},
+		{
+			name: "elastic net n=2 m=47 l1=0 l2=1000000",
+			path: "n=2_m=47.txt",
+			r:    WithElasticNet(0, 1e6),
+			want: expected{r2: 0.730, coeffs: []float64{80226.75, 130.049, -0.09}},
+		},
+		{
+			name: "elastic net n=2 m=47 l1=100000 l2=1000000",
+			path: "n=2_m=47.txt",
+			r:    WithElasticNet(1e5, 1e6),
+			want: expected{r2: 0.730, coeffs: []float64{80233.216, 130.045, 0}},
+		},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := regressiontest.LoadTrainingSet(tt.path)
+			if err != nil {
+				t.Fatalf("cannot load training set %v", err)
+			}
+			got, err := tt.r.Run(ctx, s)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			coeffs := got.Coefficients()
+			if !regressiontest.AreFloatSlicesEqual(coeffs, tt.want.coeffs, 3) {
+				t.Errorf("got coefficients %v, want %v", coeffs, tt.want.coeffs)
+			}
+			r2 := got.Accuracy()
+			if !regressiontest.AreFloatEqual(r2, tt.want.r2, 3) {
+				t.Errorf("got r2 %v, want %v", r2, tt.want.r2)
+			}
+		})
+	}
+}
+
+func TestRun_WithElasticNet_InvalidRegularization(t *testing.T) {
+	s, err := regressiontest.LoadTrainingSet("n=1_m=97.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	for _, r := range []regression.Regression[float64]{WithLasso(-1), WithElasticNet(1, -1)} {
+		if _, err := r.Run(context.Background(), s); err != regression.ErrInvalidRegularization {
+			t.Fatalf("want %v, got %v", regression.ErrInvalidRegularization, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package linear
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
+	"github.com/erni27/regression/options"
+)
+
+func TestRun_InvalidTrainingSet(t *testing.T) {
+	// Wide regressions accept more features than training examples.
+	regressions := []struct {
+		name string
+		r    regression.Regression[float64]
+		wide bool
+	}{
+		{name: "gradient descent", r: WithGradientDescent(options.WithIterativeConvergence(0.01, options.Batch, 10))},
+		{name: "normal equation", r: WithNormalEquation(), wide: true},
+		{name: "normal equation cholesky", r: WithNormalEquation(options.Cholesky)},
+		{name: "ridge", r: WithRidge(1), wide: true},
+		{name: "lasso", r: WithLasso(1), wide: true},
+		{name: "elastic net", r: WithElasticNet(1, 1), wide: true},
+	}
+	sets := []struct {
+		name   string
+		s      regression.TrainingSet
+		reason regression.ValidationReason
+	}{
+		{name: "empty", s: regression.TrainingSet{}, reason: regression.EmptyTrainingSet},
+		{name: "length mismatch", s: regression.TrainingSet{X: [][]float64{{1}, {2}, {3}}, Y: []float64{1, 2}}, reason: regression.LengthMismatch},
+		{name: "ragged row", s: regression.TrainingSet{X: [][]float64{{1}, {2, 3}, {3}}, Y: []float64{1, 2, 3}}, reason: regression.RaggedRow},
+		{name: "nan feature", s: regression.TrainingSet{X: [][]float64{{1}, {math.NaN()}, {3}}, Y: []float64{1, 2, 3}}, reason: regression.NonFiniteFeature},
+		{name: "infinite target", s: regression.TrainingSet{X: [][]float64{{1}, {2}, {3}}, Y: []float64{1, math.Inf(1), 3}}, reason: regression.NonFiniteTarget},
+		{name: "too few rows", s: regression.TrainingSet{X: [][]float64{{1, 2}, {2, 3}}, Y: []float64{1, 2}}, reason: regression.TooFewRows},
+	}
+	ctx := context.Background()
+	for _, r := range regressions {
+		for _, s := range sets {
+			if r.wide && s.reason == regression.TooFewRows {
+				continue
+			}
+			t.Run(r.name+" "+s.name, func(t *testing.T) {
+				_, err := r.r.Run(ctx, s.s)
+				var verr *regression.ValidationError
+				if !errors.Is(err, regression.ErrInvalidTrainingSet) || !errors.As(err, &verr) || verr.Reason != s.reason {
+					t.Fatalf("want %v caused by %v, got %v", regression.ErrInvalidTrainingSet, s.reason, err)
+				}
+			})
+		}
+	}
+}
+
+func TestRun_WideTrainingSet(t *testing.T) {
+	// Two training examples of three features, the second and the third ones are duplicates.
+	s := regression.TrainingSet{X: [][]float64{{1, 2, 2}, {3, 1, 1}}, Y: []float64{3, 5}}
+	regressions := []struct {
+		name string
+		r    regression.Regression[float64]
+	}{
+		{name: "normal equation", r: WithNormalEquation()},
+		{name: "ridge", r: WithRidge(0.1)},
+		{name: "lasso", r: WithLasso(0.01)},
+		{name: "elastic net", r: WithElasticNet(0.01, 0.01)},
+		{name: "gradient descent with regularization", r: WithGradientDescent(options.WithIterativeConvergence(0.05, options.Batch, 2000).WithRegularization(0.1))},
+	}
+	ctx := context.Background()
+	for _, r := range regressions {
+		t.Run(r.name, func(t *testing.T) {
+			m, err := r.r.Run(ctx, s)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			coeffs := m.Coefficients()
+			if len(coeffs) != 4 || math.Abs(coeffs[2]-coeffs[3]) > 1e-6 {
+				t.Errorf("got %v, want equal coefficients of duplicate features", coeffs)
+			}
+		})
+	}
+	// The minimum norm solution fits the training examples exactly.
+	m, err := WithNormalEquation().Run(ctx, s)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	for i, x := range s.X {
+		if p, err := m.Predict(x); err != nil || math.Abs(p-s.Y[i]) > 1e-9 {
+			t.Errorf("got prediction %v (error %v) of training example %d, want %v", p, err, i, s.Y[i])
+		}
+	}
+}
+
+func TestRun_WideRidge(t *testing.T) {
+	s := regression.TrainingSet{X: [][]float64{{1, 0, 2}, {0, 1, 1}}, Y: []float64{2, 1}}
+	m, err := WithRidge(0.5).Run(context.Background(), s)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	// With more features than training examples, the ridge coefficients equal Xᵀ(XXᵀ+λI)⁻¹y of the centered
+	// design matrix X and target vector y. Centered rows are (1/2,-1/2,1/2) and (-1/2,1/2,-1/2), so XXᵀ+λI equals
+	// [[5/4,-3/4],[-3/4,5/4]] and (XXᵀ+λI)⁻¹y equals (1/4,-1/4). The intercept equals the mean target less
+	// the coefficients times the mean features (1/2,1/2,3/2).
+	want := []float64{1.125, 0.25, -0.25, 0.25}
+	if got := m.Coefficients(); !regressiontest.AreFloatSlicesEqual(got, want, 9) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRun_WideEarlyStopping(t *testing.T) {
+	s := regressiontest.RandomTrainingSet(1, 5, 10, false)
+	o := options.WithIterativeConvergence(0.01, options.Batch, 100).WithRegularization(1)
+	ctx := context.Background()
+	// The training examples left after holding out the validation set are validated like the whole training set.
+	for _, e := range []options.EarlyStopping{
+		options.WithValidationSplit(0.2, 1, 3),
+		options.WithValidationSet(regressiontest.RandomTrainingSet(2, 2, 10, false), 3),
+	} {
+		if _, err := WithGradientDescent(o.WithEarlyStopping(e)).Run(ctx, s); err != nil {
+			t.Errorf("want nil, got error %v", err)
+		}
+	}
+	if _, err := WithGradientDescent(o.WithRegularization(0).WithEarlyStopping(options.WithValidationSplit(0.2, 1, 3))).Run(ctx, s); err == nil {
+		t.Errorf("want an error for an unregularized wide training set")
+	}
+}
+
+func TestPredict_NonFiniteFeature(t *testing.T) {
+	m := model{coeffs: []float64{1, 2, 3}}
+	_, err := m.Predict([]float64{1, math.NaN()})
+	var verr *regression.ValidationError
+	if !errors.Is(err, regression.ErrInvalidFeatureVector) || !errors.As(err, &verr) || verr.Reason != regression.NonFiniteFeature || verr.Column != 1 {
+		t.Fatalf("want %v caused by %v at column 1, got %v", regression.ErrInvalidFeatureVector, regression.NonFiniteFeature, err)
+	}
+	if _, err := m.PredictSparse(regression.SparseVector{Indices: []int{2}, Values: []float64{1}}); !errors.Is(err, regression.ErrInvalidFeatureVector) {
+		t.Fatalf("want %v, got %v", regression.ErrInvalidFeatureVector, err)
+	}
+}
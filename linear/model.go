@@ -3,19 +3,39 @@ package linear
 import (
 	"fmt"
 	"math"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/serial"
+	"github.com/erni27/regression/internal/ts"
 )
 
+// kind identifies linear regression models in their serialized form.
+const kind = "linear"
+
 // A model is a linear regression model.
 type model struct {
 	coeffs []float64
 	r2     float64
+	// report is the training report, nil if the model wasn't trained with gradient descent.
+	report *regression.Report
 }
 
 func (m model) Predict(x []float64) (float64, error) {
+	if err := ts.ValidateFeatureVector(x, len(m.coeffs)-1); err != nil {
+		return 0, err
+	}
 	// Include dummy feature equals 1 at the beginning.
 	return hyphothesis(append([]float64{1}, x...), m.coeffs)
 }
 
+// PredictSparse returns the predicated target value for the given sparse input.
+func (m model) PredictSparse(x regression.SparseVector) (float64, error) {
+	if err := ts.ValidateSparseVector(x, len(m.coeffs)-1); err != nil {
+		return 0, err
+	}
+	return ts.Dot(x, m.coeffs)
+}
+
 func (m model) Coefficients() []float64 {
 	coeffs := make([]float64, len(m.coeffs))
 	copy(coeffs, m.coeffs)
@@ -26,6 +46,16 @@ func (m model) Accuracy() float64 {
 	return m.r2
 }
 
+// Report returns the report of the model's training with gradient descent.
+func (m model) Report() (regression.Report, bool) {
+	if m.report == nil {
+		return regression.Report{}, false
+	}
+	r := *m.report
+	r.LearningCurve = append([]regression.Iteration(nil), r.LearningCurve...)
+	return r, true
+}
+
 func (m model) String() string {
 	s := fmt.Sprintf("y = %f", m.coeffs[0])
 	for i, coeff := range m.coeffs[1:] {
@@ -34,19 +64,50 @@ func (m model) String() string {
 	return s
 }
 
+// MarshalJSON encodes the model as JSON.
+func (m model) MarshalJSON() ([]byte, error) {
+	return serial.MarshalJSON(serial.Model{Kind: kind, Coefficients: m.coeffs, Accuracy: m.r2})
+}
+
+// MarshalBinary encodes the model in a compact binary form.
+func (m model) MarshalBinary() ([]byte, error) {
+	return serial.MarshalBinary(serial.Model{Kind: kind, Coefficients: m.coeffs, Accuracy: m.r2})
+}
+
+// Load restores a linear regression model encoded either as JSON or in the binary form.
+//
+// A trained model can be encoded with json.Marshal or through the encoding.BinaryMarshaler interface.
+// Load returns regression.ErrModelKindMismatch if data holds a model trained by a different package.
+func Load(data []byte) (regression.Model[float64], error) {
+	sm, err := serial.Unmarshal(data, kind)
+	if err != nil {
+		return nil, err
+	}
+	return model{coeffs: sm.Coefficients, r2: sm.Accuracy}, nil
+}
+
 // calcR2 calculates the coefficient of determination (R squared).
 func calcR2(x [][]float64, y, coeffs []float64) (float64, error) {
-	var ssr, sst float64
-	mr := calcMean(y)
+	p := make([]float64, len(x))
 	for i := 0; i < len(x); i++ {
 		v, err := hyphothesis(x[i], coeffs)
 		if err != nil {
 			return 0, err
 		}
-		ssr += math.Pow(y[i]-v, 2)
+		p[i] = v
+	}
+	return r2(y, p), nil
+}
+
+// r2 calculates the coefficient of determination (R squared) of predictions p.
+func r2(y, p []float64) float64 {
+	var ssr, sst float64
+	mr := calcMean(y)
+	for i := range y {
+		ssr += math.Pow(y[i]-p[i], 2)
 		sst += math.Pow(y[i]-mr, 2)
 	}
-	return 1 - ssr/sst, nil
+	return 1 - ssr/sst
 }
 
 func calcMean(y []float64) float64 {
@@ -1,6 +1,10 @@
 package linear
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
 	"reflect"
 	"testing"
 
@@ -53,7 +57,7 @@ func TestPredict_InvalidFeatureVector(t *testing.T) {
 				coeffs: tt.coeffs,
 			}
 			_, err := m.Predict(tt.arg)
-			if err != regression.ErrInvalidFeatureVector {
+			if !errors.Is(err, regression.ErrInvalidFeatureVector) {
 				t.Fatalf("want %v, got %v", regression.ErrInvalidFeatureVector, err)
 			}
 		})
@@ -107,3 +111,70 @@ func TestAccuracy(t *testing.T) {
 		})
 	}
 }
+
+func TestLoad(t *testing.T) {
+	m := model{coeffs: []float64{89597.909542, 139.210674, -8738.019112}, r2: 0.732945}
+	tests := []struct {
+		name    string
+		marshal func() ([]byte, error)
+	}{
+		{name: "json", marshal: func() ([]byte, error) { return json.Marshal(m) }},
+		{name: "binary", marshal: m.MarshalBinary},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.marshal()
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			got, err := Load(data)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if !reflect.DeepEqual(got, m) {
+				t.Fatalf("want %v, got %v", m, got)
+			}
+		})
+	}
+}
+
+func TestLoad_ConstantTarget(t *testing.T) {
+	// The coefficient of determination of a constant target vector isn't finite.
+	s := regression.TrainingSet{X: [][]float64{{1}, {2}, {3}, {4}}, Y: []float64{5, 5, 5, 5}}
+	m, err := WithNormalEquation().Run(context.Background(), s)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if r2 := m.Accuracy(); !math.IsNaN(r2) && !math.IsInf(r2, 0) {
+		t.Fatalf("want non-finite accuracy, got %v", r2)
+	}
+	tests := []struct {
+		name    string
+		marshal func() ([]byte, error)
+	}{
+		{name: "json", marshal: func() ([]byte, error) { return json.Marshal(m) }},
+		{name: "binary", marshal: m.(model).MarshalBinary},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.marshal()
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			got, err := Load(data)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if !reflect.DeepEqual(got.Coefficients(), m.Coefficients()) || math.Float64bits(got.Accuracy()) != math.Float64bits(m.Accuracy()) {
+				t.Fatalf("want %v with accuracy %v, got %v with accuracy %v", m, m.Accuracy(), got, got.Accuracy())
+			}
+		})
+	}
+}
+
+func TestLoad_ModelKindMismatch(t *testing.T) {
+	data := []byte(`{"version":1,"kind":"logistic","coefficients":[1,2],"accuracy":0.6}`)
+	if _, err := Load(data); err != regression.ErrModelKindMismatch {
+		t.Fatalf("want %v, got %v", regression.ErrModelKindMismatch, err)
+	}
+}
@@ -24,19 +24,35 @@ func WithGradientDescent(o options.Options) regression.Regression[float64] {
 
 // numerical runs linear regression for given training set. It uses an numerical approach
 // for computing coefficients (gradient descent).
+//
+// With early stopping, the validation set is held out of the training set and the coefficient of determination
+// is calculated on the rest.
 func numerical(ctx context.Context, o options.Options, s regression.TrainingSet) (regression.Model[float64], error) {
-	if err := ts.Validate(s); err != nil {
+	// The regularization makes the cost function strictly convex even with more features than training examples.
+	validate := ts.Validate
+	if o.Regularization > 0 {
+		validate = ts.ValidateWide
+	}
+	if err := validate(s); err != nil {
 		return nil, err
 	}
+	var v regression.TrainingSet
+	if o.EarlyStopping != nil {
+		var err error
+		if s, v, err = ts.HoldOut(*o.EarlyStopping, s, validate); err != nil {
+			return nil, err
+		}
+	}
 	x := ts.AddDummies(s.X)
 	y := s.Y
-	coeffs, err := long.Run(ctx, func() ([]float64, error) { return gradientDescent.Run(ctx, o, x, y) })
+	vx := ts.AddDummies(v.X)
+	res, err := long.Run(ctx, func() (gd.Result, error) { return gradientDescent.RunWithValidation(ctx, o, x, y, vx, v.Y) })
 	if err != nil {
 		return nil, err
 	}
-	r2, err := calcR2(x, y, coeffs)
+	r2, err := calcR2(x, y, res.Coefficients)
 	if err != nil {
 		return nil, err
 	}
-	return model{coeffs: coeffs, r2: r2}, nil
+	return model{coeffs: res.Coefficients, r2: r2, report: &res.Report}, nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/erni27/regression"
 	"github.com/erni27/regression/internal/regressiontest"
 	"github.com/erni27/regression/options"
 )
@@ -43,6 +44,60 @@ func TestRun_WithGradientDescent(t *testing.T) {
 			options: options.WithAutomaticConvergence(0.0001, options.Stochastic, 0.0000000001),
 			want:    expected{r2: 0.702, coeffs: []float64{-3.126, 1.116}},
 		},
+		{
+			name:    "batch iterative n=1 m=97 alpha=0.0001 i=20000 lambda=1000",
+			path:    "n=1_m=97.txt",
+			options: options.WithIterativeConvergence(0.0001, options.Batch, 20000).WithRegularization(1000),
+			want:    expected{r2: 0.584, coeffs: []float64{0.098, 0.704}},
+		},
+		{
+			name:    "mini-batch iterative n=1 m=97 alpha=0.0002 size=16 i=5000",
+			path:    "n=1_m=97.txt",
+			options: options.WithIterativeConvergence(0.0002, options.Batch, 5000).WithMiniBatch(16, 1),
+			want:    expected{r2: 0.702, coeffs: []float64{-3.898, 1.186}},
+		},
+		{
+			name:    "batch momentum iterative n=1 m=97 alpha=0.0001 i=2000",
+			path:    "n=1_m=97.txt",
+			options: options.WithIterativeConvergence(0.0001, options.Batch, 2000).WithMomentum(0.9),
+			want:    expected{r2: 0.702, coeffs: []float64{-3.896, 1.193}},
+		},
+		{
+			name:    "batch nesterov iterative n=1 m=97 alpha=0.0001 i=2000",
+			path:    "n=1_m=97.txt",
+			options: options.WithIterativeConvergence(0.0001, options.Batch, 2000).WithNesterov(0.9),
+			want:    expected{r2: 0.702, coeffs: []float64{-3.896, 1.193}},
+		},
+		{
+			name:    "l-bfgs iterative n=1 m=97 i=10",
+			path:    "n=1_m=97.txt",
+			options: options.WithIterativeConvergence(0, options.LBFGS, 10),
+			want:    expected{r2: 0.702, coeffs: []float64{-3.896, 1.193}},
+		},
+		{
+			name:    "conjugate gradient iterative n=1 m=97 i=10",
+			path:    "n=1_m=97.txt",
+			options: options.WithIterativeConvergence(0, options.ConjugateGradient, 10),
+			want:    expected{r2: 0.702, coeffs: []float64{-3.896, 1.193}},
+		},
+		{
+			name:    "l-bfgs iterative n=2 m=47 i=100",
+			path:    "n=2_m=47.txt",
+			options: options.WithIterativeConvergence(0, options.LBFGS, 100),
+			want:    expected{r2: 0.733, coeffs: []float64{89597.91, 139.211, -8738.019}},
+		},
+		{
+			name:    "batch adam iterative n=2 m=47 alpha=1000 i=20000",
+			path:    "n=2_m=47.txt",
+			options: options.WithIterativeConvergence(1000, options.Batch, 20000).WithAdam(0.9, 0.999, 1e-8),
+			want:    expected{r2: 0.733, coeffs: []float64{89597.837, 139.21, -8737.995}},
+		},
+		{
+			name:    "batch adagrad iterative n=2 m=47 alpha=30000 i=20000",
+			path:    "n=2_m=47.txt",
+			options: options.WithIterativeConvergence(30000, options.Batch, 20000).WithAdaGrad(1e-8),
+			want:    expected{r2: 0.724, coeffs: []float64{40879.726, 137.51, 7044.057}},
+		},
 	}
 	ctx := context.Background()
 	for _, tt := range tests {
@@ -67,3 +122,184 @@ func TestRun_WithGradientDescent(t *testing.T) {
 		})
 	}
 }
+
+func BenchmarkRun_WithGradientDescent(b *testing.B) {
+	benchmarks := []struct {
+		name    string
+		path    string
+		options options.Options
+	}{
+		{name: "batch n=1 m=97 i=2000", path: "n=1_m=97.txt", options: options.WithIterativeConvergence(0.0001, options.Batch, 2000)},
+		{name: "batch adam n=2 m=47 i=2000", path: "n=2_m=47.txt", options: options.WithIterativeConvergence(1000, options.Batch, 2000).WithAdam(0.9, 0.999, 1e-8)},
+		{name: "l-bfgs n=2 m=47 i=100", path: "n=2_m=47.txt", options: options.WithIterativeConvergence(0, options.LBFGS, 100)},
+	}
+	ctx := context.Background()
+	for _, bb := range benchmarks {
+		b.Run(bb.name, func(b *testing.B) {
+			s, err := regressiontest.LoadTrainingSet(bb.path)
+			if err != nil {
+				b.Fatalf("cannot load training set %v", err)
+			}
+			r := WithGradientDescent(bb.options)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := r.Run(ctx, s); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestRun_WithGradientDescent_ContextCanceled(t *testing.T) {
+	s := regressiontest.RandomTrainingSet(1, 2000, 20, false)
+	tests := []struct {
+		name    string
+		options options.Options
+	}{
+		{name: "batch", options: options.WithIterativeConvergence(0.1, options.Batch, 1000000)},
+		{name: "stochastic", options: options.WithAutomaticConvergence(0.001, options.Stochastic, 0)},
+		{name: "l-bfgs", options: options.WithAutomaticConvergence(0, options.LBFGS, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The context is done after a few iterations.
+			ctx := regressiontest.CountdownContext(context.Background(), 10)
+			if _, err := WithGradientDescent(tt.options).Run(ctx, s); err != context.Canceled {
+				t.Fatalf("want %v, got %v", context.Canceled, err)
+			}
+		})
+	}
+}
+
+func TestRun_WithGradientDescent_Report(t *testing.T) {
+	s, err := regressiontest.LoadTrainingSet("n=1_m=97.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	tests := []struct {
+		name       string
+		options    options.Options
+		iterations int
+		reason     regression.StopReason
+	}{
+		{
+			name:       "batch iterative alpha=0.0001 i=200",
+			options:    options.WithIterativeConvergence(0.0001, options.Batch, 200).WithLearningCurve(),
+			iterations: 200,
+			reason:     regression.IterationLimit,
+		},
+		{
+			name:    "batch automatic alpha=0.0001 t=0.0000001",
+			options: options.WithAutomaticConvergence(0.0001, options.Batch, 0.0000001).WithLearningCurve(),
+			reason:  regression.Converged,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := WithGradientDescent(tt.options).Run(context.Background(), s)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			rm, ok := m.(regression.ReportingModel)
+			if !ok {
+				t.Fatalf("want reporting model, got %T", m)
+			}
+			r, ok := rm.Report()
+			if !ok {
+				t.Fatalf("want report of a model trained with gradient descent")
+			}
+			if r.StopReason != tt.reason || (tt.iterations > 0 && r.Iterations != tt.iterations) {
+				t.Errorf("got %d iterations and stop reason %v, want %d and %v", r.Iterations, r.StopReason, tt.iterations, tt.reason)
+			}
+			if len(r.LearningCurve) != r.Iterations {
+				t.Fatalf("got learning curve of %d iterations, want %d", len(r.LearningCurve), r.Iterations)
+			}
+			// The batch variant with a small learning rate decreases the cost function in every iteration.
+			prev := r.LearningCurve[0]
+			for k, it := range r.LearningCurve[1:] {
+				if it.Cost >= prev.Cost || it.GradientNorm >= prev.GradientNorm {
+					t.Fatalf("got cost %v and gradient norm %v after iteration %d, want less than %v and %v", it.Cost, it.GradientNorm, k+2, prev.Cost, prev.GradientNorm)
+				}
+				prev = it
+			}
+			if r.Cost != prev.Cost {
+				t.Errorf("got final cost %v, want %v", r.Cost, prev.Cost)
+			}
+		})
+	}
+}
+
+func TestRun_WithGradientDescent_Observer(t *testing.T) {
+	s, err := regressiontest.LoadTrainingSet("n=1_m=97.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	var last options.Progress
+	// Stop once the cost function drops below 6.
+	o := options.WithIterativeConvergence(0.0001, options.Batch, 20000).WithObserver(options.ObserverFunc(func(p options.Progress) error {
+		last = p
+		if p.Cost < 6 {
+			return regression.ErrStopTraining
+		}
+		return nil
+	}), 10)
+	m, err := WithGradientDescent(o).Run(context.Background(), s)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	r, _ := m.(regression.ReportingModel).Report()
+	if r.StopReason != regression.Stopped || r.Iterations != last.Iteration || r.Iterations%10 != 0 || r.Cost >= 6 {
+		t.Fatalf("got %d iterations, stop reason %v and cost %v, want %d, %v and less than 6", r.Iterations, r.StopReason, r.Cost, last.Iteration, regression.Stopped)
+	}
+	if !regressiontest.AreFloatSlicesEqual(m.Coefficients(), last.Coefficients, -1) {
+		t.Errorf("got coefficients %v, want %v", m.Coefficients(), last.Coefficients)
+	}
+}
+
+func TestRun_WithGradientDescent_EarlyStopping(t *testing.T) {
+	s, err := regressiontest.LoadTrainingSet("n=1_m=97.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	o := options.WithIterativeConvergence(0.0001, options.Batch, 2000)
+	m, err := WithGradientDescent(o.WithEarlyStopping(options.WithValidationSplit(0.2, 1, 5))).Run(context.Background(), s)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	r, _ := m.(regression.ReportingModel).Report()
+	// The validation cost stops improving long before the iteration limit.
+	if r.StopReason != regression.EarlyStopped || r.BestIteration != r.Iterations-5 || r.Iterations >= 2000 {
+		t.Errorf("got stop reason %v with best iteration %d of %d, want %v 5 iterations before the end", r.StopReason, r.BestIteration, r.Iterations, regression.EarlyStopped)
+	}
+	if _, err := WithGradientDescent(o.WithEarlyStopping(options.WithValidationSplit(0.2, 1, 0))).Run(context.Background(), s); err != regression.ErrInvalidEarlyStopping {
+		t.Errorf("want %v, got %v", regression.ErrInvalidEarlyStopping, err)
+	}
+	sparse := regressiontest.ToSparse(s)
+	if _, err := WithSparseGradientDescent(o.WithEarlyStopping(options.WithValidationSplit(0.2, 1, 5))).Run(context.Background(), sparse); err != regression.ErrInvalidEarlyStopping {
+		t.Errorf("want %v, got %v", regression.ErrInvalidEarlyStopping, err)
+	}
+}
+
+func TestRun_WithGradientDescent_WarmStart(t *testing.T) {
+	s, err := regressiontest.LoadTrainingSet("n=1_m=97.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	o := options.WithIterativeConvergence(0.0001, options.Batch, 200)
+	prev, err := WithGradientDescent(o).Run(context.Background(), s)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	m, err := WithGradientDescent(o.WithWarmStart(prev.Coefficients())).Run(context.Background(), s)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	// Warm started gradient descent continues to improve the previous model.
+	if m.Accuracy() <= prev.Accuracy() {
+		t.Errorf("got accuracy %v, want greater than %v", m.Accuracy(), prev.Accuracy())
+	}
+	if _, err := WithGradientDescent(o.WithWarmStart([]float64{1})).Run(context.Background(), s); err != regression.ErrInvalidCheckpoint {
+		t.Errorf("want %v, got %v", regression.ErrInvalidCheckpoint, err)
+	}
+}
@@ -0,0 +1,77 @@
+package linear
+
+import (
+	"context"
+	"sync"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/gd"
+	"github.com/erni27/regression/internal/ts"
+	"github.com/erni27/regression/options"
+)
+
+// An online is a linear regression learning online with the stochastic gradient descent.
+type online struct {
+	g *gd.Online
+	n int
+	// mu serializes PartialFit calls and guards the progressive validation statistics: the number of training
+	// examples m, the sum of squared residuals ssr, and the mean and the sum of squared deviations of targets.
+	mu   sync.Mutex
+	m    int
+	ssr  float64
+	mean float64
+	sst  float64
+}
+
+// NewOnline returns a new linear regression learning online from training examples with n features.
+// Every training example passed to PartialFit is learned with a single step of the stochastic gradient descent,
+// so options must choose the options.Stochastic variant. A warm start or a checkpoint in options sets
+// the initial coefficients.
+//
+// The accuracy of a snapshot is the coefficient of determination of progressive validation: every training example
+// is predicted just before it's learned. It's zero until targets vary.
+func NewOnline(o options.Options, n int) (regression.OnlineRegression[float64], error) {
+	if n < 1 {
+		return nil, regression.ErrInvalidFeatureVector
+	}
+	g, err := gd.NewOnline(o, hyphothesis, n+1)
+	if err != nil {
+		return nil, err
+	}
+	return &online{g: g, n: n}, nil
+}
+
+func (r *online) PartialFit(ctx context.Context, s regression.TrainingSet) error {
+	if err := ts.ValidateBatch(s, r.n); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// If Fit fails, the statistics still account for the training examples learned before the failing one.
+	p, err := r.g.Fit(ctx, ts.AddDummies(s.X), s.Y)
+	for i, hr := range p {
+		y := s.Y[i]
+		r.ssr += (y - hr) * (y - hr)
+		r.m++
+		d := y - r.mean
+		r.mean += d / float64(r.m)
+		r.sst += d * (y - r.mean)
+	}
+	return err
+}
+
+func (r *online) Predict(x []float64) (float64, error) {
+	return model{coeffs: r.g.Coefficients()}.Predict(x)
+}
+
+func (r *online) Snapshot() regression.Model[float64] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var r2 float64
+	if r.sst > 0 {
+		r2 = 1 - r.ssr/r.sst
+	}
+	coeffs := make([]float64, r.n+1)
+	copy(coeffs, r.g.Coefficients())
+	return model{coeffs: coeffs, r2: r2}
+}
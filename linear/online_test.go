@@ -0,0 +1,126 @@
+package linear
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
+	"github.com/erni27/regression/options"
+)
+
+func TestOnline_PartialFit(t *testing.T) {
+	r, err := NewOnline(options.WithIterativeConvergence(0.05, options.Stochastic, 1), 2)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	rnd := rand.New(rand.NewSource(1))
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	// Predictions are served while the model learns.
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if _, err := r.Predict([]float64{1, 1}); err != nil {
+				t.Errorf("want nil, got error %v", err)
+				return
+			}
+		}
+	}()
+	// Mini-batches of the stream y = 1 + 2*x1 - x2.
+	for k := 0; k < 200; k++ {
+		var s regression.TrainingSet
+		for i := 0; i < 10; i++ {
+			x := []float64{rnd.Float64(), rnd.Float64()}
+			s.X = append(s.X, x)
+			s.Y = append(s.Y, 1+2*x[0]-x[1])
+		}
+		if err := r.PartialFit(ctx, s); err != nil {
+			t.Fatalf("want nil, got error %v", err)
+		}
+	}
+	close(done)
+	wg.Wait()
+	m := r.Snapshot()
+	if want := []float64{1, 2, -1}; !regressiontest.AreFloatSlicesEqual(m.Coefficients(), want, 2) {
+		t.Errorf("got %v, want %v", m.Coefficients(), want)
+	}
+	if acc := m.Accuracy(); acc < 0.9 || acc >= 1 {
+		t.Errorf("got progressive validation accuracy %v, want within [0.9, 1)", acc)
+	}
+	got, err := r.Predict([]float64{1, 1})
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if want, _ := m.Predict([]float64{1, 1}); got != want {
+		t.Errorf("got prediction %v, want %v of the snapshot", got, want)
+	}
+}
+
+func TestOnline_PartialFit_Invalid(t *testing.T) {
+	if _, err := NewOnline(options.WithIterativeConvergence(0.05, options.Batch, 1), 2); err != regression.ErrUnsupportedGradientDescentVariant {
+		t.Errorf("want %v, got %v", regression.ErrUnsupportedGradientDescentVariant, err)
+	}
+	r, err := NewOnline(options.WithIterativeConvergence(0.05, options.Stochastic, 1), 2)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	err = r.PartialFit(context.Background(), regression.TrainingSet{X: [][]float64{{1, 2, 3}}, Y: []float64{1}})
+	var verr *regression.ValidationError
+	if !errors.As(err, &verr) || verr.Reason != regression.LengthMismatch {
+		t.Fatalf("want %v, got %v", regression.LengthMismatch, err)
+	}
+	if _, err := r.Predict([]float64{1}); !errors.Is(err, regression.ErrInvalidFeatureVector) {
+		t.Errorf("want %v, got %v", regression.ErrInvalidFeatureVector, err)
+	}
+	if m := r.Snapshot(); m.Accuracy() != 0 || !regressiontest.AreFloatSlicesEqual(m.Coefficients(), []float64{0, 0, 0}, 9) {
+		t.Errorf("got %v with accuracy %v, want zero coefficients and accuracy", m.Coefficients(), m.Accuracy())
+	}
+}
+
+func TestOnline_PartialFit_Canceled(t *testing.T) {
+	o := options.WithIterativeConvergence(0.05, options.Stochastic, 1).WithRegularization(0.1)
+	s := regression.TrainingSet{X: [][]float64{{1, 2}, {2, 1}, {3, 5}, {4, 2}}, Y: []float64{4, 3, 9, 5}}
+	ctx := context.Background()
+	want, err := NewOnline(o, 2)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	r, err := NewOnline(o, 2)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	// The context is checked before every training example, so the first two are learned.
+	if err := r.PartialFit(regressiontest.CountdownContext(ctx, 2), s); err != context.Canceled {
+		t.Fatalf("want %v, got %v", context.Canceled, err)
+	}
+	if err := want.PartialFit(ctx, regression.TrainingSet{X: s.X[:2], Y: s.Y[:2]}); err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	got, wm := r.Snapshot(), want.Snapshot()
+	if !reflect.DeepEqual(got.Coefficients(), wm.Coefficients()) || got.Accuracy() != wm.Accuracy() {
+		t.Errorf("got %v with accuracy %v, want %v with accuracy %v", got.Coefficients(), got.Accuracy(), wm.Coefficients(), wm.Accuracy())
+	}
+	// The training examples which haven't been learned can be passed again.
+	if err := r.PartialFit(ctx, regression.TrainingSet{X: s.X[2:], Y: s.Y[2:]}); err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if err := want.PartialFit(ctx, regression.TrainingSet{X: s.X[2:], Y: s.Y[2:]}); err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	got, wm = r.Snapshot(), want.Snapshot()
+	if !reflect.DeepEqual(got.Coefficients(), wm.Coefficients()) || got.Accuracy() != wm.Accuracy() {
+		t.Errorf("got %v with accuracy %v, want %v with accuracy %v", got.Coefficients(), got.Accuracy(), wm.Coefficients(), wm.Accuracy())
+	}
+}
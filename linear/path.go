@@ -0,0 +1,55 @@
+package linear
+
+import (
+	"context"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/cd"
+	"github.com/erni27/regression/internal/long"
+	"github.com/erni27/regression/internal/ts"
+	"github.com/erni27/regression/options"
+)
+
+// RegularizationPath computes the elastic net regularization path for given training set.
+//
+// It trains one model per regularization strength, for a decreasing geometric sequence of strengths. The largest one
+// is derived from the training set as the smallest strength for which all coefficients (except the intercept) equal zero.
+// Each model is warm-started from the coefficients of the previous one, which makes computing the whole path
+// much faster than training every model from scratch.
+func RegularizationPath(ctx context.Context, s regression.TrainingSet, o options.PathOptions) ([]regression.PathPoint[float64], error) {
+	if !o.IsValid() {
+		return nil, regression.ErrInvalidRegularizationPath
+	}
+	if err := ts.ValidateWide(s); err != nil {
+		return nil, err
+	}
+	x := ts.AddDummies(s.X)
+	y := s.Y
+	return long.Run(ctx, func() ([]regression.PathPoint[float64], error) {
+		lambdas, coeffs, err := cd.Path(ctx, cd.LeastSquares, x, y, o.Alpha, o.Lambdas, o.MinRatio)
+		if err != nil {
+			return nil, err
+		}
+		w := make([]float64, len(x))
+		for i := range w {
+			w[i] = 1
+		}
+		path := make([]regression.PathPoint[float64], len(lambdas))
+		for k, l := range lambdas {
+			r2, err := calcR2(x, y, coeffs[k])
+			if err != nil {
+				return nil, err
+			}
+			c, err := cost(x, y, coeffs[k])
+			if err != nil {
+				return nil, err
+			}
+			df, err := cd.DegreesOfFreedom(ctx, x, w, coeffs[k], (1-o.Alpha)*l)
+			if err != nil {
+				return nil, err
+			}
+			path[k] = regression.PathPoint[float64]{Lambda: l, Model: model{coeffs: coeffs[k], r2: r2}, Cost: c, DegreesOfFreedom: df}
+		}
+		return path, nil
+	})
+}
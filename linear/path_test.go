@@ -0,0 +1,107 @@
+package linear
+
+import (
+	"context"
+	"testing"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
+	"github.com/erni27/regression/options"
+)
+
+func TestRegularizationPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		options options.PathOptions
+	}{
+		{name: "lasso n=1 m=97", path: "n=1_m=97.txt", options: options.WithRegularizationPath(1, 10, 0.001)},
+		{name: "lasso n=2 m=47", path: "n=2_m=47.txt", options: options.WithRegularizationPath(1, 20, 0.0001)},
+		{name: "elastic net n=2 m=47", path: "n=2_m=47.txt", options: options.WithRegularizationPath(0.5, 20, 0.0001)},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := regressiontest.LoadTrainingSet(tt.path)
+			if err != nil {
+				t.Fatalf("cannot load training set %v", err)
+			}
+			got, err := RegularizationPath(ctx, s, tt.options)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if len(got) != tt.options.Lambdas {
+				t.Fatalf("want %d models, got %d", tt.options.Lambdas, len(got))
+			}
+			// The largest regularization strength zeroes all coefficients except the intercept.
+			if coeffs := got[0].Model.Coefficients(); !regressiontest.AreFloatSlicesEqual(coeffs[1:], make([]float64, len(coeffs)-1), -1) {
+				t.Errorf("want zero coefficients for the largest lambda, got %v", coeffs)
+			}
+			if df := got[0].DegreesOfFreedom; df != 0 {
+				t.Errorf("want zero degrees of freedom for the largest lambda, got %v", df)
+			}
+			if r := got[len(got)-1].Lambda / got[0].Lambda; !regressiontest.AreFloatEqual(r, tt.options.MinRatio, 9) {
+				t.Errorf("want lambdas ratio %v, got %v", tt.options.MinRatio, r)
+			}
+			for k := 1; k < len(got); k++ {
+				if got[k].Lambda >= got[k-1].Lambda {
+					t.Errorf("want decreasing lambdas, got %v after %v", got[k].Lambda, got[k-1].Lambda)
+				}
+				if got[k].Cost > got[k-1].Cost {
+					t.Errorf("want non-increasing cost, got %v after %v", got[k].Cost, got[k-1].Cost)
+				}
+			}
+			// Every model on the path equals the model trained from scratch.
+			for _, p := range got {
+				a := tt.options.Alpha
+				want, err := WithElasticNet(a*p.Lambda, (1-a)*p.Lambda).Run(ctx, s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !regressiontest.AreFloatSlicesEqual(p.Model.Coefficients(), want.Coefficients(), 3) {
+					t.Errorf("got coefficients %v for lambda %v, want %v", p.Model.Coefficients(), p.Lambda, want.Coefficients())
+				}
+			}
+		})
+	}
+}
+
+func TestRegularizationPath_DegreesOfFreedom(t *testing.T) {
+	s, err := regressiontest.LoadTrainingSet("n=2_m=47.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	got, err := RegularizationPath(context.Background(), s, options.WithRegularizationPath(1, 5, 0.0001))
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	want := []float64{0, 1, 1, 1, 1}
+	for k, p := range got {
+		if p.DegreesOfFreedom != want[k] {
+			t.Errorf("got %v degrees of freedom for lambda %v, want %v", p.DegreesOfFreedom, p.Lambda, want[k])
+		}
+	}
+}
+
+func TestRegularizationPath_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options options.PathOptions
+	}{
+		{name: "negative alpha", options: options.WithRegularizationPath(-0.5, 10, 0.001)},
+		{name: "alpha greater than one", options: options.WithRegularizationPath(1.5, 10, 0.001)},
+		{name: "no lambdas", options: options.WithRegularizationPath(1, 0, 0.001)},
+		{name: "ratio equals one", options: options.WithRegularizationPath(1, 10, 1)},
+	}
+	s, err := regressiontest.LoadTrainingSet("n=1_m=97.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := RegularizationPath(context.Background(), s, tt.options); err != regression.ErrInvalidRegularizationPath {
+				t.Fatalf("want %v, got %v", regression.ErrInvalidRegularizationPath, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package linear
+
+import (
+	"context"
+	"math"
+	"sync"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/matrix"
+	"github.com/erni27/regression/internal/ts"
+)
+
+// An rls is a linear regression learning online with recursive least squares.
+//
+// Until XᵀX of the training examples seen so far is well-conditioned, their design matrix and target vector are
+// reduced to the triangular factor R of XᵀX=RᵀR and z=Qᵀy by Givens rotations. Then the inverse p of the
+// (exponentially weighted) XᵀX is calculated once from R and updated with every training example by
+// the Sherman-Morrison formula. Both take O(n²) per training example.
+type rls struct {
+	n      int
+	lambda float64
+	// mu serializes PartialFit calls and guards the state of the estimator below.
+	mu sync.Mutex
+	// r and z are the triangular factor and the rotated target vector, rho is the weighted sum of squared residuals
+	// left by the rotations. next is the number of training examples before which the condition number of XᵀX
+	// isn't estimated again.
+	r     [][]float64
+	z     []float64
+	rho   float64
+	next  int
+	p     [][]float64
+	theta []float64
+	// m is the number of training examples and w is their total weight. ssr is the weighted sum of squared residuals,
+	// sy and syy are the weighted sums of targets and squared targets.
+	m   int
+	w   float64
+	ssr float64
+	sy  float64
+	syy float64
+	// u is a buffer for a feature vector being rotated or P times a feature vector.
+	u []float64
+	// pmu guards coeffs, the coefficients published after the last PartialFit.
+	pmu    sync.RWMutex
+	coeffs []float64
+}
+
+// NewRecursiveLeastSquares returns a new linear regression learning online from training examples with n features
+// by recursive least squares. Unlike the stochastic gradient descent, it finds the exact least squares solution
+// for the training examples seen so far, so once all of them are passed to PartialFit, its coefficients and accuracy
+// are the same as of WithNormalEquation up to the floating point precision. Every training example takes O(n²) time,
+// except for the one determining the coefficients, which takes O(n³).
+//
+// The forgetting factor lambda within (0, 1] weights training examples exponentially, the k-th last one by lambda^k,
+// so that the estimator tracks a drifting process. 1 weights all training examples equally. Otherwise,
+// regression.ErrInvalidForgettingFactor is returned.
+//
+// Coefficients are zero until the training examples seen so far determine them uniquely. The accuracy of a snapshot
+// is the weighted coefficient of determination of the training examples seen so far, zero until targets vary.
+func NewRecursiveLeastSquares(n int, lambda float64) (regression.OnlineRegression[float64], error) {
+	if n < 1 {
+		return nil, regression.ErrInvalidFeatureVector
+	}
+	if !(lambda > 0 && lambda <= 1) {
+		return nil, regression.ErrInvalidForgettingFactor
+	}
+	d := n + 1
+	r := make([][]float64, d)
+	for i := range r {
+		r[i] = make([]float64, d)
+	}
+	return &rls{
+		n: n, lambda: lambda, r: r, z: make([]float64, d), theta: make([]float64, d),
+		u: make([]float64, d), coeffs: make([]float64, d),
+	}, nil
+}
+
+func (r *rls) PartialFit(ctx context.Context, s regression.TrainingSet) error {
+	if err := ts.ValidateBatch(s, r.n); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer r.publish()
+	for i, x := range ts.AddDummies(s.X) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		r.update(x, s.Y[i])
+	}
+	return nil
+}
+
+// update learns a single training example (x, y), where x includes the dummy feature.
+func (r *rls) update(x []float64, y float64) {
+	l := r.lambda
+	if r.p == nil {
+		r.rotate(x, y)
+	} else {
+		r.step(x, y)
+	}
+	r.m++
+	r.w = l*r.w + 1
+	r.sy = l*r.sy + y
+	r.syy = l*r.syy + y*y
+	if r.p == nil {
+		r.determine()
+	}
+}
+
+// step updates the inverse of XᵀX and the coefficients with a training example (x, y) by the Sherman-Morrison formula.
+func (r *rls) step(x []float64, y float64) {
+	l := r.lambda
+	px := r.u
+	var den float64
+	for i := range px {
+		px[i] = 0
+		for j := range x {
+			px[i] += r.p[i][j] * x[j]
+		}
+		den += x[i] * px[i]
+	}
+	den += l
+	// e is the a priori residual, the residual after the update equals e*lambda/den.
+	e := y
+	for j := range x {
+		e -= r.theta[j] * x[j]
+	}
+	theta := make([]float64, len(x))
+	for i := range theta {
+		theta[i] = r.theta[i] + px[i]*e/den
+	}
+	// P = (P - Pxxᵀ P/den)/lambda, kept symmetric against rounding errors.
+	for i := range x {
+		for j := i; j < len(x); j++ {
+			v := (r.p[i][j] - px[i]*px[j]/den) / l
+			r.p[i][j], r.p[j][i] = v, v
+		}
+	}
+	r.theta = theta
+	r.ssr = l*r.ssr + e*e*l/den
+}
+
+// rotate adds a training example (x, y) to the triangular factor R and the rotated target vector z.
+// The training examples seen so far are weighted down by the forgetting factor first. The rotations eliminate
+// the feature vector, so the target left over is the residual of the training example.
+func (r *rls) rotate(x []float64, y float64) {
+	d := len(x)
+	if r.lambda < 1 {
+		sl := math.Sqrt(r.lambda)
+		for i := range r.r {
+			for j := i; j < d; j++ {
+				r.r[i][j] *= sl
+			}
+			r.z[i] *= sl
+		}
+		r.rho *= r.lambda
+	}
+	u := r.u
+	copy(u, x)
+	for k := 0; k < d; k++ {
+		if u[k] == 0 {
+			continue
+		}
+		rk := r.r[k]
+		h := math.Hypot(rk[k], u[k])
+		c, s := rk[k]/h, u[k]/h
+		for j := k; j < d; j++ {
+			rk[j], u[j] = c*rk[j]+s*u[j], c*u[j]-s*rk[j]
+		}
+		r.z[k], y = c*r.z[k]+s*y, c*y-s*r.z[k]
+	}
+	r.rho += y * y
+}
+
+// determine calculates the inverse of XᵀX and the coefficients from the triangular factor R, once XᵀX is
+// well-conditioned.
+//
+// The ratio of the largest and the smallest diagonal element of R squared is a lower bound of the condition number
+// of XᵀX, which rules out rank deficient XᵀX in O(n). Otherwise, the condition number is estimated in O(n³).
+// If it's still too large, it isn't estimated again before the number of training examples doubles.
+func (r *rls) determine() {
+	d := len(r.r)
+	if r.m < d || r.m < r.next {
+		return
+	}
+	lo, hi := math.Inf(1), 0.0
+	for k := range r.r {
+		v := math.Abs(r.r[k][k])
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	if lo == 0 || (hi/lo)*(hi/lo) > maxCondition {
+		return
+	}
+	// XᵀX=RᵀR, so Rᵀ is its Cholesky factor up to the signs of columns.
+	rt := make([][]float64, d)
+	a := make([][]float64, d)
+	for i := range rt {
+		rt[i] = make([]float64, d)
+		a[i] = make([]float64, d)
+		for j := 0; j <= i; j++ {
+			rt[i][j] = r.r[j][i]
+		}
+	}
+	for i := range a {
+		for j := range a[i] {
+			for k := 0; k <= i && k <= j; k++ {
+				a[i][j] += r.r[k][i] * r.r[k][j]
+			}
+		}
+	}
+	if matrix.CholeskyCondition(a, rt) > maxCondition {
+		r.next = 2 * r.m
+		return
+	}
+	// P = R⁻¹R⁻ᵀ, where the inverse of R is upper triangular.
+	ri := make([][]float64, d)
+	for i := range ri {
+		ri[i] = make([]float64, d)
+	}
+	for j := 0; j < d; j++ {
+		ri[j][j] = 1 / r.r[j][j]
+		for i := j - 1; i >= 0; i-- {
+			var s float64
+			for k := i + 1; k <= j; k++ {
+				s += r.r[i][k] * ri[k][j]
+			}
+			ri[i][j] = -s / r.r[i][i]
+		}
+	}
+	p := make([][]float64, d)
+	theta := make([]float64, d)
+	for i := range p {
+		p[i] = make([]float64, d)
+		for j := range p[i] {
+			// Both rows of the inverse of R are zero before the diagonal.
+			k := i
+			if j > k {
+				k = j
+			}
+			for ; k < d; k++ {
+				p[i][j] += ri[i][k] * ri[j][k]
+			}
+		}
+		for k := i; k < d; k++ {
+			theta[i] += ri[i][k] * r.z[k]
+		}
+	}
+	r.p, r.theta, r.ssr = p, theta, r.rho
+	r.r, r.z = nil, nil
+}
+
+// publish publishes the current coefficients. They're never modified in place, so the published ones can be read
+// without holding the lock.
+func (r *rls) publish() {
+	r.pmu.Lock()
+	r.coeffs = r.theta
+	r.pmu.Unlock()
+}
+
+func (r *rls) current() []float64 {
+	r.pmu.RLock()
+	defer r.pmu.RUnlock()
+	return r.coeffs
+}
+
+func (r *rls) Predict(x []float64) (float64, error) {
+	return model{coeffs: r.current()}.Predict(x)
+}
+
+func (r *rls) Snapshot() regression.Model[float64] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var r2 float64
+	if sst := r.syy - r.sy*r.sy/r.w; r.m > 0 && sst > 0 {
+		r2 = 1 - r.ssr/sst
+	}
+	coeffs := make([]float64, r.n+1)
+	copy(coeffs, r.current())
+	return model{coeffs: coeffs, r2: r2}
+}
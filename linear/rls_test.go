@@ -0,0 +1,199 @@
+package linear
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
+)
+
+func TestRecursiveLeastSquares_PartialFit(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		batch int
+	}{
+		{name: "n=1 m=97 batch=1", path: "n=1_m=97.txt", batch: 1},
+		{name: "n=1 m=97 batch=10", path: "n=1_m=97.txt", batch: 10},
+		{name: "n=2 m=47 batch=1", path: "n=2_m=47.txt", batch: 1},
+		{name: "n=2 m=47 batch=47", path: "n=2_m=47.txt", batch: 47},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := regressiontest.LoadTrainingSet(tt.path)
+			if err != nil {
+				t.Fatalf("cannot load training set %v", err)
+			}
+			want, err := WithNormalEquation().Run(ctx, s)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			r, err := NewRecursiveLeastSquares(len(s.X[0]), 1)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			for lo := 0; lo < len(s.X); lo += tt.batch {
+				hi := lo + tt.batch
+				if hi > len(s.X) {
+					hi = len(s.X)
+				}
+				if err := r.PartialFit(ctx, regression.TrainingSet{X: s.X[lo:hi], Y: s.Y[lo:hi]}); err != nil {
+					t.Fatalf("want nil, got error %v", err)
+				}
+			}
+			got := r.Snapshot()
+			if !regressiontest.AreFloatSlicesEqual(got.Coefficients(), want.Coefficients(), 6) {
+				t.Errorf("got coefficients %v, want %v", got.Coefficients(), want.Coefficients())
+			}
+			if !regressiontest.AreFloatEqual(got.Accuracy(), want.Accuracy(), 9) {
+				t.Errorf("got accuracy %v, want %v", got.Accuracy(), want.Accuracy())
+			}
+		})
+	}
+}
+
+func TestRecursiveLeastSquares_ForgettingFactor(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	// The process drifts from y = 1 + 2*x to y = 3 - x.
+	stream := func(m int, f func(x float64) float64) regression.TrainingSet {
+		var s regression.TrainingSet
+		for i := 0; i < m; i++ {
+			x := rnd.Float64()
+			s.X = append(s.X, []float64{x})
+			s.Y = append(s.Y, f(x)+0.01*rnd.NormFloat64())
+		}
+		return s
+	}
+	r, err := NewRecursiveLeastSquares(1, 0.9)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	ctx := context.Background()
+	if err := r.PartialFit(ctx, stream(200, func(x float64) float64 { return 1 + 2*x })); err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if err := r.PartialFit(ctx, stream(200, func(x float64) float64 { return 3 - x })); err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if got, want := r.Snapshot().Coefficients(), []float64{3, -1}; !regressiontest.AreFloatSlicesEqual(got, want, 1) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	p, err := r.Predict([]float64{1})
+	if err != nil || !regressiontest.AreFloatEqual(p, 2, 1) {
+		t.Errorf("got %v and error %v, want %v", p, err, 2)
+	}
+}
+
+func TestRecursiveLeastSquares_Underdetermined(t *testing.T) {
+	r, err := NewRecursiveLeastSquares(2, 1)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	// Collinear training examples don't determine the coefficients.
+	s := regression.TrainingSet{X: [][]float64{{1, 2}, {2, 4}, {3, 6}, {4, 8}}, Y: []float64{1, 2, 3, 4}}
+	if err := r.PartialFit(context.Background(), s); err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if m := r.Snapshot(); !regressiontest.AreFloatSlicesEqual(m.Coefficients(), []float64{0, 0, 0}, 9) {
+		t.Errorf("got %v, want zero coefficients", m.Coefficients())
+	}
+	if err := r.PartialFit(context.Background(), regression.TrainingSet{X: [][]float64{{1, 0}}, Y: []float64{0.5}}); err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	// y = 0.5*x1 + 0.25*x2 fits all the training examples.
+	if m := r.Snapshot(); !regressiontest.AreFloatSlicesEqual(m.Coefficients(), []float64{0, 0.5, 0.25}, 6) || !regressiontest.AreFloatEqual(m.Accuracy(), 1, 9) {
+		t.Errorf("got %v with accuracy %v, want %v with accuracy 1", m.Coefficients(), m.Accuracy(), []float64{0, 0.5, 0.25})
+	}
+}
+
+func TestRecursiveLeastSquares_Canceled(t *testing.T) {
+	s := regression.TrainingSet{X: [][]float64{{1, 2}, {2, 1}, {3, 5}, {4, 2}, {5, 7}}, Y: []float64{4, 3, 9, 5, 12}}
+	ctx := context.Background()
+	want, err := NewRecursiveLeastSquares(2, 0.9)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if err := want.PartialFit(ctx, s); err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	r, err := NewRecursiveLeastSquares(2, 0.9)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	// The context is checked before every training example, so the first three are learned,
+	// which determines the coefficients.
+	if err := r.PartialFit(regressiontest.CountdownContext(ctx, 3), s); err != context.Canceled {
+		t.Fatalf("want %v, got %v", context.Canceled, err)
+	}
+	if err := r.PartialFit(ctx, regression.TrainingSet{X: s.X[3:], Y: s.Y[3:]}); err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	got, wm := r.Snapshot(), want.Snapshot()
+	if !reflect.DeepEqual(got.Coefficients(), wm.Coefficients()) || got.Accuracy() != wm.Accuracy() {
+		t.Errorf("got %v with accuracy %v, want %v with accuracy %v", got.Coefficients(), got.Accuracy(), wm.Coefficients(), wm.Accuracy())
+	}
+}
+
+func TestRecursiveLeastSquares_RankDeficient(t *testing.T) {
+	r, err := NewRecursiveLeastSquares(3, 1)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	rnd := rand.New(rand.NewSource(1))
+	// The third feature duplicates the first one, so the coefficients are never determined.
+	var s regression.TrainingSet
+	for i := 0; i < 100; i++ {
+		x := []float64{rnd.Float64(), rnd.Float64()}
+		s.X = append(s.X, []float64{x[0], x[1], x[0]})
+		s.Y = append(s.Y, 1+x[0]+x[1])
+	}
+	if err := r.PartialFit(context.Background(), s); err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if m := r.Snapshot(); !reflect.DeepEqual(m.Coefficients(), []float64{0, 0, 0, 0}) {
+		t.Errorf("got %v, want zero coefficients", m.Coefficients())
+	}
+	// The condition number is never estimated, since the triangular factor is singular.
+	if next := r.(*rls).next; next != 0 {
+		t.Errorf("got the next estimate at %d training examples, want none", next)
+	}
+}
+
+func BenchmarkRecursiveLeastSquares_PartialFit(b *testing.B) {
+	for _, dup := range []bool{false, true} {
+		b.Run(fmt.Sprintf("duplicated column=%t", dup), func(b *testing.B) {
+			s := regressiontest.RandomTrainingSet(1, 1000, 99, false)
+			if dup {
+				for _, x := range s.X {
+					x[1] = x[0]
+				}
+			}
+			ctx := context.Background()
+			for i := 0; i < b.N; i++ {
+				r, err := NewRecursiveLeastSquares(99, 1)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if err := r.PartialFit(ctx, s); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestNewRecursiveLeastSquares_Invalid(t *testing.T) {
+	for _, lambda := range []float64{0, -0.5, 1.1} {
+		if _, err := NewRecursiveLeastSquares(1, lambda); err != regression.ErrInvalidForgettingFactor {
+			t.Errorf("want %v for lambda %v, got %v", regression.ErrInvalidForgettingFactor, lambda, err)
+		}
+	}
+	if _, err := NewRecursiveLeastSquares(0, 1); err != regression.ErrInvalidFeatureVector {
+		t.Errorf("want %v, got %v", regression.ErrInvalidFeatureVector, err)
+	}
+}
@@ -0,0 +1,166 @@
+package linear
+
+import (
+	"context"
+	"math"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/gd"
+	"github.com/erni27/regression/internal/long"
+	"github.com/erni27/regression/internal/ts"
+	"github.com/erni27/regression/options"
+)
+
+var sparseGradientDescent gd.SparseGradientDescent = gd.NewSparse(ts.Dot, sparseCost)
+
+// WithSparseGradientDescent initializes linear regression with numerical approach for a sparse training set.
+// It finds the value of coefficients by taking steps in each iteration towards the minimum of a cost function (LMS).
+// Every step takes time proportional to the number of non-zero features (plus the number of coefficients).
+func WithSparseGradientDescent(o options.Options) regression.SparseRegression[float64] {
+	var f regression.SparseRegressionFunc[float64] = func(ctx context.Context, s regression.SparseTrainingSet) (regression.SparseModel[float64], error) {
+		if err := ts.ValidateSparse(s); err != nil {
+			return nil, err
+		}
+		res, err := long.Run(ctx, func() (gd.Result, error) { return sparseGradientDescent.Run(ctx, o, s.X, s.Y) })
+		if err != nil {
+			return nil, err
+		}
+		return newSparseModel(s, res.Coefficients, &res.Report)
+	}
+	return f
+}
+
+// WithSparseLeastSquares initializes linear regression with an iterative least squares solver for a sparse training set.
+// It finds the value of coefficients by solving the normal equation with the conjugate gradient method (CGLS),
+// which needs only products of the design matrix (and its transposition) by a vector, so XᵀX is never formed.
+//
+// The solver converges in at most as many iterations as there are coefficients, but usually in far fewer.
+// If it doesn't reach the tolerance within the maximum number of iterations, the last coefficients are returned.
+func WithSparseLeastSquares(o options.LeastSquaresOptions) regression.SparseRegression[float64] {
+	var f regression.SparseRegressionFunc[float64] = func(ctx context.Context, s regression.SparseTrainingSet) (regression.SparseModel[float64], error) {
+		if !o.IsValid() {
+			return nil, regression.ErrInvalidLeastSquares
+		}
+		if err := ts.ValidateSparse(s); err != nil {
+			return nil, err
+		}
+		coeffs, err := long.Run(ctx, func() ([]float64, error) { return cgls(ctx, o, s.X, s.Y) })
+		if err != nil {
+			return nil, err
+		}
+		return newSparseModel(s, coeffs, nil)
+	}
+	return f
+}
+
+// newSparseModel returns a model with given coefficients and training report, its coefficient of determination
+// is calculated on a sparse training set.
+func newSparseModel(s regression.SparseTrainingSet, coeffs []float64, report *regression.Report) (regression.SparseModel[float64], error) {
+	p := make([]float64, s.X.Rows)
+	for i := range p {
+		v, err := ts.Dot(s.X.Row(i), coeffs)
+		if err != nil {
+			return nil, err
+		}
+		p[i] = v
+	}
+	return model{coeffs: coeffs, r2: r2(s.Y, p), report: report}, nil
+}
+
+// sparseCost calculates cost function value for linear regression on a sparse design matrix.
+func sparseCost(x regression.SparseMatrix, y []float64, coeffs []float64) (float64, error) {
+	var c float64
+	for i := 0; i < x.Rows; i++ {
+		hr, err := ts.Dot(x.Row(i), coeffs)
+		if err != nil {
+			return 0, err
+		}
+		c += math.Pow(hr-y[i], 2)
+	}
+	return c / float64((2 * x.Rows)), nil
+}
+
+// cgls solves the (regularized) normal equation (XᵀX+l2*I)O=Xᵀy with the conjugate gradient method applied
+// to the least squares problem. X is a sparse design matrix with the implicit dummy feature, the intercept isn't penalized.
+//
+// Each iteration takes time proportional to the number of non-zero features.
+func cgls(ctx context.Context, o options.LeastSquaresOptions, x regression.SparseMatrix, y []float64) ([]float64, error) {
+	n := x.Cols + 1
+	l2 := o.Regularization
+	coeffs := make([]float64, n)
+	// r is the residual y-XO, s is the normal equation residual Xᵀr-l2*O and p is the search direction.
+	r := make([]float64, len(y))
+	copy(r, y)
+	s := multiplyTransposed(x, r)
+	p := make([]float64, n)
+	copy(p, s)
+	gamma := dot(s, s)
+	stop := o.Tolerance * math.Sqrt(gamma)
+	for k := 0; k < o.MaxIterations && math.Sqrt(gamma) > stop; k++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		q := multiply(x, p)
+		// The regularization adds l2*|p|² (without the intercept) to the curvature along p.
+		curv := dot(q, q) + l2*(dot(p, p)-p[0]*p[0])
+		if curv == 0 {
+			break
+		}
+		alpha := gamma / curv
+		for j := range coeffs {
+			coeffs[j] += alpha * p[j]
+		}
+		for i := range r {
+			r[i] -= alpha * q[i]
+		}
+		s = multiplyTransposed(x, r)
+		for j := 1; j < n; j++ {
+			s[j] -= l2 * coeffs[j]
+		}
+		ng := dot(s, s)
+		beta := ng / gamma
+		gamma = ng
+		for j := range p {
+			p[j] = s[j] + beta*p[j]
+		}
+	}
+	for _, c := range coeffs {
+		if math.IsNaN(c) || math.IsInf(c, 0) {
+			return nil, regression.ErrCannotConverge
+		}
+	}
+	return coeffs, nil
+}
+
+// multiply multiplies a sparse design matrix with the implicit dummy feature by a vector of x.Cols+1 elements.
+func multiply(x regression.SparseMatrix, v []float64) []float64 {
+	p := make([]float64, x.Rows)
+	for i := range p {
+		p[i] = v[0]
+		for k := x.RowOffsets[i]; k < x.RowOffsets[i+1]; k++ {
+			p[i] += x.Values[k] * v[x.ColIndices[k]+1]
+		}
+	}
+	return p
+}
+
+// multiplyTransposed multiplies the transposition of a sparse design matrix with the implicit dummy feature by a vector
+// of x.Rows elements.
+func multiplyTransposed(x regression.SparseMatrix, v []float64) []float64 {
+	p := make([]float64, x.Cols+1)
+	for i := 0; i < x.Rows; i++ {
+		p[0] += v[i]
+		for k := x.RowOffsets[i]; k < x.RowOffsets[i+1]; k++ {
+			p[x.ColIndices[k]+1] += x.Values[k] * v[i]
+		}
+	}
+	return p
+}
+
+func dot(x, y []float64) float64 {
+	var v float64
+	for j := range x {
+		v += x[j] * y[j]
+	}
+	return v
+}
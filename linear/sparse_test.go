@@ -0,0 +1,161 @@
+package linear
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
+	"github.com/erni27/regression/options"
+)
+
+func TestRun_WithSparseGradientDescent(t *testing.T) {
+	type expected struct {
+		r2     float64
+		coeffs []float64
+	}
+	tests := []struct {
+		name    string
+		path    string
+		options options.Options
+		want    expected
+	}{
+		{
+			name:    "batch iterative n=1 m=97 alpha=0.0001 i=2000",
+			path:    "n=1_m=97.txt",
+			options: options.WithIterativeConvergence(0.0001, options.Batch, 2000),
+			want:    expected{r2: 0.702, coeffs: []float64{-3.776, 1.181}},
+		},
+		{
+			name:    "l-bfgs iterative n=2 m=47 i=100",
+			path:    "n=2_m=47.txt",
+			options: options.WithIterativeConvergence(0, options.LBFGS, 100),
+			want:    expected{r2: 0.733, coeffs: []float64{89597.91, 139.211, -8738.019}},
+		},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := regressiontest.LoadTrainingSet(tt.path)
+			if err != nil {
+				t.Fatalf("cannot load training set %v", err)
+			}
+			got, err := WithSparseGradientDescent(tt.options).Run(ctx, regressiontest.ToSparse(s))
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			coeffs := got.Coefficients()
+			if !regressiontest.AreFloatSlicesEqual(coeffs, tt.want.coeffs, 3) {
+				t.Errorf("got coefficients %v, want %v", coeffs, tt.want.coeffs)
+			}
+			r2 := got.Accuracy()
+			if !regressiontest.AreFloatEqual(r2, tt.want.r2, 3) {
+				t.Errorf("got r2 %v, want %v", r2, tt.want.r2)
+			}
+		})
+	}
+}
+
+func TestRun_WithSparseLeastSquares(t *testing.T) {
+	type expected struct {
+		r2     float64
+		coeffs []float64
+	}
+	tests := []struct {
+		name    string
+		path    string
+		options options.LeastSquaresOptions
+		want    expected
+	}{
+		{
+			name:    "n=1 m=97",
+			path:    "n=1_m=97.txt",
+			options: options.WithLeastSquares(1e-12, 100),
+			want:    expected{r2: 0.702, coeffs: []float64{-3.896, 1.193}},
+		},
+		{
+			name:    "n=2 m=47",
+			path:    "n=2_m=47.txt",
+			options: options.WithLeastSquares(1e-12, 100),
+			want:    expected{r2: 0.733, coeffs: []float64{89597.91, 139.211, -8738.019}},
+		},
+		{
+			name:    "n=1 m=97 lambda=1000",
+			path:    "n=1_m=97.txt",
+			options: options.WithLeastSquares(1e-12, 100).WithRegularization(1000),
+			want:    expected{r2: 0.584, coeffs: []float64{0.098, 0.704}},
+		},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := regressiontest.LoadTrainingSet(tt.path)
+			if err != nil {
+				t.Fatalf("cannot load training set %v", err)
+			}
+			got, err := WithSparseLeastSquares(tt.options).Run(ctx, regressiontest.ToSparse(s))
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			coeffs := got.Coefficients()
+			if !regressiontest.AreFloatSlicesEqual(coeffs, tt.want.coeffs, 3) {
+				t.Errorf("got coefficients %v, want %v", coeffs, tt.want.coeffs)
+			}
+			r2 := got.Accuracy()
+			if !regressiontest.AreFloatEqual(r2, tt.want.r2, 3) {
+				t.Errorf("got r2 %v, want %v", r2, tt.want.r2)
+			}
+		})
+	}
+}
+
+func TestRun_WithSparseLeastSquares_MoreFeaturesThanExamples(t *testing.T) {
+	// One-hot encoded features with the intercept, plus a million of features which are never set.
+	s := regressiontest.ToSparse(regression.TrainingSet{
+		X: [][]float64{{1, 1, 0}, {2, 1, 0}, {3, 0, 1}, {4, 0, 1}, {5, 0, 1}},
+		Y: []float64{3, 5, 10, 12, 14},
+	})
+	s.X.Cols = 1000000
+	got, err := WithSparseLeastSquares(options.WithLeastSquares(1e-12, 100)).Run(context.Background(), s)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	// The minimum norm solution, the same as the one of the normal equation solved with SVD.
+	want := []float64{5.0 / 3, 2, -2.0 / 3, 7.0 / 3}
+	coeffs := got.Coefficients()
+	if len(coeffs) != s.X.Cols+1 {
+		t.Fatalf("got %d coefficients, want %d", len(coeffs), s.X.Cols+1)
+	}
+	if !regressiontest.AreFloatSlicesEqual(coeffs[:4], want, 6) {
+		t.Errorf("got coefficients %v, want %v", coeffs[:4], want)
+	}
+	if r2 := got.Accuracy(); !regressiontest.AreFloatEqual(r2, 1, 6) {
+		t.Errorf("got r2 %v, want %v", r2, 1)
+	}
+	p, err := got.PredictSparse(regression.SparseVector{Indices: []int{0, 2, 999999}, Values: []float64{6, 1, 8}})
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if !regressiontest.AreFloatEqual(p, 16, 6) {
+		t.Errorf("got prediction %v, want %v", p, 16)
+	}
+}
+
+func TestRun_WithSparseLeastSquares_InvalidOptions(t *testing.T) {
+	s := regressiontest.ToSparse(regression.TrainingSet{X: [][]float64{{1}, {2}}, Y: []float64{1, 2}})
+	if _, err := WithSparseLeastSquares(options.WithLeastSquares(1e-6, 0)).Run(context.Background(), s); err != regression.ErrInvalidLeastSquares {
+		t.Fatalf("want %v, got %v", regression.ErrInvalidLeastSquares, err)
+	}
+}
+
+func TestRun_WithSparse_InvalidTrainingSet(t *testing.T) {
+	s := regression.SparseTrainingSet{X: regression.SparseMatrix{Rows: 1, Cols: 1, RowOffsets: []int{0, 1}, ColIndices: []int{1}, Values: []float64{1}}, Y: []float64{1}}
+	ctx := context.Background()
+	if _, err := WithSparseLeastSquares(options.WithLeastSquares(1e-6, 10)).Run(ctx, s); !errors.Is(err, regression.ErrInvalidTrainingSet) {
+		t.Errorf("want %v, got %v", regression.ErrInvalidTrainingSet, err)
+	}
+	if _, err := WithSparseGradientDescent(options.WithIterativeConvergence(0.01, options.Batch, 10)).Run(ctx, s); !errors.Is(err, regression.ErrInvalidTrainingSet) {
+		t.Errorf("want %v, got %v", regression.ErrInvalidTrainingSet, err)
+	}
+}
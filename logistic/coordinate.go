@@ -0,0 +1,54 @@
+package logistic
+
+import (
+	"context"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/cd"
+	"github.com/erni27/regression/internal/long"
+	"github.com/erni27/regression/internal/ts"
+	"github.com/erni27/regression/options"
+)
+
+// WithLasso initializes logistic regression with L1 regularization.
+// It finds the value of coefficients through coordinate descent.
+//
+// The l1 is the regularization strength. The L1 penalty drives coefficients of irrelevant features
+// to exactly zero, so the regression can be used for feature selection. The intercept is never penalized.
+func WithLasso(l1 float64) regression.Regression[int] {
+	return WithElasticNet(l1, 0)
+}
+
+// WithElasticNet initializes logistic regression with both L1 and L2 regularization.
+// It finds the value of coefficients through coordinate descent.
+//
+// The l1 and l2 are regularization strengths of the L1 and L2 penalty respectively. The L2 penalty is scaled
+// in the same way as the regularization of gradient descent. The intercept is never penalized.
+func WithElasticNet(l1, l2 float64) regression.Regression[int] {
+	var f regression.RegressionFunc[int] = func(ctx context.Context, s regression.TrainingSet) (regression.Model[int], error) {
+		return coordinate(ctx, s, cd.Penalty{L1: l1, L2: l2})
+	}
+	return f
+}
+
+// coordinate runs logistic regression for given training set. It uses coordinate descent
+// for computing elastic net regularized coefficients.
+func coordinate(ctx context.Context, s regression.TrainingSet, p cd.Penalty) (regression.Model[int], error) {
+	if !options.IsValidRegularization(p.L1) || !options.IsValidRegularization(p.L2) {
+		return nil, regression.ErrInvalidRegularization
+	}
+	if err := ts.ValidateBinaryWide(s); err != nil {
+		return nil, err
+	}
+	x := ts.AddDummies(s.X)
+	y := s.Y
+	coeffs, err := long.Run(ctx, func() ([]float64, error) { return cd.Logistic(ctx, x, y, nil, p) })
+	if err != nil {
+		return nil, err
+	}
+	acc, err := calcAccuracy(x, y, coeffs, DefaultThreshold)
+	if err != nil {
+		return nil, err
+	}
+	return model{coeffs: coeffs, acc: acc}, nil
+}
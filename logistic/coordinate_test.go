@@ -0,0 +1,73 @@
+package logistic
+
+import (
+	"context"
+	"testing"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
+)
+
+func TestRun_WithElasticNet(t *testing.T) {
+	type expected struct {
+		acc    float64
+		coeffs []float64
+	}
+	tests := []struct {
+		name string
+		path string
+		r    regression.Regression[int]
+		want expected
+	}{
+		{
+			name: "lasso n=2 m=100 lambda=0",
+			path: "n=2_m=100.txt",
+			r:    WithLasso(0),
+			want: expected{acc: 0.89, coeffs: []float64{-25.161, 0.206, 0.201}},
+		},
+		{
+			name: "lasso n=2 m=100 lambda=10",
+			path: "n=2_m=100.txt",
+			r:    WithLasso(10),
+			want: expected{acc: 0.89, coeffs: []float64{-20.899, 0.172, 0.167}},
+		},
+		{
+			name: "elastic net n=2 m=100 l1=10 l2=10",
+			path: "n=2_m=100.txt",
+			r:    WithElasticNet(10, 10),
+			want: expected{acc: 0.89, coeffs: []float64{-20.358, 0.168, 0.162}},
+		},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := regressiontest.LoadTrainingSet(tt.path)
+			if err != nil {
+				t.Fatalf("cannot load training set %v", err)
+			}
+			got, err := tt.r.Run(ctx, s)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			coeffs := got.Coefficients()
+			if !regressiontest.AreFloatSlicesEqual(coeffs, tt.want.coeffs, 3) {
+				t.Errorf("got coefficients %v, want %v", coeffs, tt.want.coeffs)
+			}
+			if acc := got.Accuracy(); acc != tt.want.acc {
+				t.Errorf("got acc %v, want %v", acc, tt.want.acc)
+			}
+		})
+	}
+}
+
+func TestRun_WithElasticNet_InvalidRegularization(t *testing.T) {
+	s, err := regressiontest.LoadTrainingSet("n=2_m=100.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	for _, r := range []regression.Regression[int]{WithLasso(-1), WithElasticNet(1, -1)} {
+		if _, err := r.Run(context.Background(), s); err != regression.ErrInvalidRegularization {
+			t.Fatalf("want %v, got %v", regression.ErrInvalidRegularization, err)
+		}
+	}
+}
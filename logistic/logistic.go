@@ -3,6 +3,7 @@ package logistic
 
 import (
 	"context"
+	"errors"
 	"math"
 
 	"github.com/erni27/regression"
@@ -12,6 +13,15 @@ import (
 	"github.com/erni27/regression/options"
 )
 
+var (
+	// ErrInvalidThreshold is returned if a decision threshold is not a probability strictly between 0 and 1.
+	ErrInvalidThreshold = errors.New("invalid threshold")
+	// ErrUnsupportedModel is returned if a wrapped regression doesn't produce a logistic regression model.
+	ErrUnsupportedModel = errors.New("unsupported model")
+	// ErrPerfectSeparation is returned if training examples are perfectly separable, so the cost function has no minimum.
+	ErrPerfectSeparation = errors.New("perfect separation")
+)
+
 var gradientDescent gd.GradientDescent = gd.New(hyphothesis, cost)
 
 // WithGradientDescent initializes logistic regression with numerical approach.
@@ -24,23 +34,79 @@ func WithGradientDescent(o options.Options) regression.Regression[int] {
 	return f
 }
 
+// WithThreshold wraps a logistic regression with a custom decision threshold.
+// The trained model predicts the positive class if the estimated probability is greater than
+// or equal to t, and its accuracy is calculated with respect to t.
+//
+// It's useful for imbalanced training sets where the default threshold (0.5) rarely predicts the minority class.
+// Like the accuracy of the wrapped regression's model, it's calculated on the training set without examples held out
+// for early stopping.
+func WithThreshold(r regression.Regression[int], t float64) regression.Regression[int] {
+	var f regression.RegressionFunc[int] = func(ctx context.Context, s regression.TrainingSet) (regression.Model[int], error) {
+		if !isValidThreshold(t) {
+			return nil, ErrInvalidThreshold
+		}
+		rm, err := r.Run(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := rm.(model)
+		if !ok {
+			return nil, ErrUnsupportedModel
+		}
+		if m.held != nil {
+			var train regression.TrainingSet
+			for i, h := range m.held {
+				if !h {
+					train.X, train.Y = append(train.X, s.X[i]), append(train.Y, s.Y[i])
+				}
+			}
+			s = train
+		}
+		acc, err := calcAccuracy(ts.AddDummies(s.X), s.Y, m.coeffs, t)
+		if err != nil {
+			return nil, err
+		}
+		return model{coeffs: m.coeffs, acc: acc, threshold: t, report: m.report, held: m.held}, nil
+	}
+	return f
+}
+
 // run runs logistic regression for given training set. It uses an numerical approach
 // for computing coefficients (gradient descent).
+//
+// With early stopping, the validation set is held out of the training set and the accuracy is calculated on the rest.
 func run(ctx context.Context, o options.Options, s regression.TrainingSet) (regression.Model[int], error) {
-	if err := ts.Validate(s); err != nil {
+	// The regularization makes the cost function strictly convex even with more features than training examples.
+	validate := ts.ValidateBinary
+	if o.Regularization > 0 {
+		validate = ts.ValidateBinaryWide
+	}
+	if err := validate(s); err != nil {
 		return nil, err
 	}
+	var v regression.TrainingSet
+	var held []bool
+	if o.EarlyStopping != nil {
+		m := len(s.X)
+		var err error
+		if s, v, err = ts.HoldOutBinary(*o.EarlyStopping, s, validate); err != nil {
+			return nil, err
+		}
+		held = ts.Held(*o.EarlyStopping, m)
+	}
 	x := ts.AddDummies(s.X)
 	y := s.Y
-	coeffs, err := long.Run(ctx, func() ([]float64, error) { return gradientDescent.Run(ctx, o, x, y) })
+	vx := ts.AddDummies(v.X)
+	res, err := long.Run(ctx, func() (gd.Result, error) { return gradientDescent.RunWithValidation(ctx, o, x, y, vx, v.Y) })
 	if err != nil {
 		return nil, err
 	}
-	acc, err := calcAccuracy(x, y, coeffs)
+	acc, err := calcAccuracy(x, y, res.Coefficients, DefaultThreshold)
 	if err != nil {
 		return nil, err
 	}
-	return model{coeffs: coeffs, acc: acc}, nil
+	return model{coeffs: res.Coefficients, acc: acc, report: &res.Report, held: held}, nil
 }
 
 // hyphothesis calculates a hyphothesis function value for the logistic regression model.
@@ -2,9 +2,12 @@ package logistic
 
 import (
 	"context"
+	"errors"
 	"testing"
 
+	"github.com/erni27/regression"
 	"github.com/erni27/regression/internal/regressiontest"
+	"github.com/erni27/regression/internal/ts"
 	"github.com/erni27/regression/options"
 )
 
@@ -25,6 +28,30 @@ func TestRun_WithGradientDescent(t *testing.T) {
 			options: options.WithIterativeConvergence(0.01, options.Batch, 100),
 			want:    expected{acc: 0.6, coeffs: []float64{-7.465, 33.217, -4.415}},
 		},
+		{
+			name:    "batch gd n=2 m=100 alpha=0.01 i=100 lambda=10",
+			path:    "n=2_m=100.txt",
+			options: options.WithIterativeConvergence(0.01, options.Batch, 100).WithRegularization(10),
+			want:    expected{acc: 0.6, coeffs: []float64{-6.5, 9.878, 1.444}},
+		},
+		{
+			name:    "batch gd adam n=2 m=100 alpha=0.1 i=5000",
+			path:    "n=2_m=100.txt",
+			options: options.WithIterativeConvergence(0.1, options.Batch, 5000).WithAdam(0.9, 0.999, 1e-8),
+			want:    expected{acc: 0.9, coeffs: []float64{-25.546, 0.208, 0.204}},
+		},
+		{
+			name:    "l-bfgs n=2 m=100 i=100",
+			path:    "n=2_m=100.txt",
+			options: options.WithIterativeConvergence(0, options.LBFGS, 100),
+			want:    expected{acc: 0.89, coeffs: []float64{-25.161, 0.206, 0.201}},
+		},
+		{
+			name:    "conjugate gradient n=2 m=100 i=200",
+			path:    "n=2_m=100.txt",
+			options: options.WithIterativeConvergence(0, options.ConjugateGradient, 200),
+			want:    expected{acc: 0.89, coeffs: []float64{-25.161, 0.206, 0.201}},
+		},
 	}
 	ctx := context.Background()
 	for _, tt := range tests {
@@ -49,3 +76,203 @@ func TestRun_WithGradientDescent(t *testing.T) {
 		})
 	}
 }
+
+func TestRun_WithThreshold(t *testing.T) {
+	// Estimated probabilities equal 0.119, 0.269, 0.5, 0.731, 0.881 and 0.953.
+	s := regression.TrainingSet{
+		X: [][]float64{{-2}, {-1}, {0}, {1}, {2}, {3}},
+		Y: []float64{0, 0, 0, 0, 1, 1},
+	}
+	var r regression.RegressionFunc[int] = func(ctx context.Context, s regression.TrainingSet) (regression.Model[int], error) {
+		return model{coeffs: []float64{0, 1}}, nil
+	}
+	tests := []struct {
+		name      string
+		threshold float64
+		want      float64
+	}{
+		{name: "t=0.5", threshold: 0.5, want: 4.0 / 6},
+		{name: "t=0.8", threshold: 0.8, want: 1},
+		{name: "t=0.95", threshold: 0.95, want: 5.0 / 6},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := WithThreshold(r, tt.threshold).Run(ctx, s)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if acc := got.Accuracy(); acc != tt.want {
+				t.Errorf("got acc %v, want %v", acc, tt.want)
+			}
+			if th := got.(model).Threshold(); th != tt.threshold {
+				t.Errorf("got threshold %v, want %v", th, tt.threshold)
+			}
+		})
+	}
+}
+
+func TestRun_WithThreshold_InvalidThreshold(t *testing.T) {
+	o := options.WithIterativeConvergence(0.01, options.Batch, 100)
+	for _, th := range []float64{-0.5, 0, 1, 1.5} {
+		_, err := WithThreshold(WithGradientDescent(o), th).Run(context.Background(), regression.TrainingSet{})
+		if err != ErrInvalidThreshold {
+			t.Fatalf("want %v, got %v", ErrInvalidThreshold, err)
+		}
+	}
+}
+
+func BenchmarkRun_WithGradientDescent(b *testing.B) {
+	benchmarks := []struct {
+		name    string
+		options options.Options
+	}{
+		{name: "batch n=2 m=100 i=100", options: options.WithIterativeConvergence(0.01, options.Batch, 100)},
+		{name: "l-bfgs n=2 m=100 i=100", options: options.WithIterativeConvergence(0, options.LBFGS, 100)},
+	}
+	s, err := regressiontest.LoadTrainingSet("n=2_m=100.txt")
+	if err != nil {
+		b.Fatalf("cannot load training set %v", err)
+	}
+	ctx := context.Background()
+	for _, bb := range benchmarks {
+		b.Run(bb.name, func(b *testing.B) {
+			r := WithGradientDescent(bb.options)
+			for i := 0; i < b.N; i++ {
+				if _, err := r.Run(ctx, s); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestRun_NonBinaryTarget(t *testing.T) {
+	o := options.WithIterativeConvergence(0.01, options.Batch, 10)
+	regressions := []struct {
+		name string
+		r    regression.Regression[int]
+	}{
+		{name: "gradient descent", r: WithGradientDescent(o)},
+		{name: "threshold", r: WithThreshold(WithGradientDescent(o), 0.7)},
+		{name: "newton", r: WithNewton()},
+		{name: "lasso", r: WithLasso(1)},
+		{name: "elastic net", r: WithElasticNet(1, 1)},
+	}
+	s := regression.TrainingSet{X: [][]float64{{1}, {2}, {3}, {4}}, Y: []float64{0, 1, 2, 1}}
+	for _, tt := range regressions {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.r.Run(context.Background(), s)
+			var verr *regression.ValidationError
+			if !errors.As(err, &verr) || verr.Err != regression.ErrInvalidTrainingSet || verr.Reason != regression.NonBinaryTarget || verr.Row != 2 {
+				t.Fatalf("want %v caused by %v at row 2, got %v", regression.ErrInvalidTrainingSet, regression.NonBinaryTarget, err)
+			}
+		})
+	}
+	if _, err := RegularizationPath(context.Background(), s, options.WithRegularizationPath(1, 10, 0.01)); !errors.Is(err, regression.ErrInvalidTrainingSet) {
+		t.Fatalf("want %v, got %v", regression.ErrInvalidTrainingSet, err)
+	}
+	if _, err := WithSparseGradientDescent(o).Run(context.Background(), regressiontest.ToSparse(s)); !errors.Is(err, regression.ErrInvalidTrainingSet) {
+		t.Fatalf("want %v, got %v", regression.ErrInvalidTrainingSet, err)
+	}
+}
+
+func TestRun_Report(t *testing.T) {
+	s, err := regressiontest.LoadTrainingSet("n=2_m=100.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	o := options.WithIterativeConvergence(0, options.LBFGS, 50).WithLearningCurve()
+	tests := []struct {
+		name   string
+		r      regression.Regression[int]
+		report bool
+	}{
+		{name: "gradient descent", r: WithGradientDescent(o), report: true},
+		{name: "threshold", r: WithThreshold(WithGradientDescent(o), 0.7), report: true},
+		{name: "newton", r: WithNewton(), report: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := tt.r.Run(context.Background(), s)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			r, ok := m.(regression.ReportingModel).Report()
+			if ok != tt.report {
+				t.Fatalf("got report %t, want %t", ok, tt.report)
+			}
+			if !ok {
+				return
+			}
+			if r.Iterations == 0 || len(r.LearningCurve) != r.Iterations {
+				t.Fatalf("got %d iterations with learning curve of %d, want equal and positive", r.Iterations, len(r.LearningCurve))
+			}
+			if last := r.LearningCurve[len(r.LearningCurve)-1]; last.Cost != r.Cost || last.Cost >= r.LearningCurve[0].Cost {
+				t.Errorf("got final cost %v and learning curve from %v to %v", r.Cost, r.LearningCurve[0].Cost, last.Cost)
+			}
+		})
+	}
+}
+
+func TestRun_EarlyStopping(t *testing.T) {
+	// Few training examples with many features, so the model over-fits.
+	s := regressiontest.RandomTrainingSet(1, 60, 40, true)
+	v := regressiontest.RandomTrainingSet(2, 500, 40, true)
+	o := options.WithIterativeConvergence(0.5, options.Batch, 5000)
+	full, err := WithGradientDescent(o).Run(context.Background(), s)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	m, err := WithGradientDescent(o.WithEarlyStopping(options.WithValidationSet(v, 20))).Run(context.Background(), s)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	r, _ := m.(regression.ReportingModel).Report()
+	if r.StopReason != regression.EarlyStopped || r.BestIteration != r.Iterations-20 {
+		t.Fatalf("got stop reason %v with best iteration %d of %d, want %v 20 iterations before the end", r.StopReason, r.BestIteration, r.Iterations, regression.EarlyStopped)
+	}
+	vx := ts.AddDummies(v.X)
+	vc, err := cost(vx, v.Y, m.Coefficients())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regressiontest.AreFloatEqual(vc, r.ValidationCost, 9) {
+		t.Errorf("got validation cost %v, want %v", r.ValidationCost, vc)
+	}
+	if fc, _ := cost(vx, v.Y, full.Coefficients()); fc <= vc {
+		t.Errorf("got validation cost %v of the early stopped model, want less than %v of the fully trained one", vc, fc)
+	}
+}
+
+func TestRun_WithThreshold_EarlyStopping(t *testing.T) {
+	s := regressiontest.RandomTrainingSet(1, 60, 5, true)
+	e := options.WithValidationSplit(0.3, 7, 5)
+	r := WithGradientDescent(options.WithIterativeConvergence(0.5, options.Batch, 200).WithEarlyStopping(e))
+	base, err := r.Run(context.Background(), s)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	// With the default threshold, the accuracy is the same as of the wrapped model, calculated without held out examples.
+	m, err := WithThreshold(r, DefaultThreshold).Run(context.Background(), s)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if m.Accuracy() != base.Accuracy() {
+		t.Errorf("got accuracy %v, want %v", m.Accuracy(), base.Accuracy())
+	}
+	train, _, err := ts.HoldOutBinary(e, s, ts.ValidateBinary)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if m, err = WithThreshold(r, 0.3).Run(context.Background(), s); err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	want, err := calcAccuracy(ts.AddDummies(train.X), train.Y, m.Coefficients(), 0.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Accuracy() != want {
+		t.Errorf("got accuracy %v, want %v", m.Accuracy(), want)
+	}
+}
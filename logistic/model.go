@@ -2,22 +2,62 @@ package logistic
 
 import (
 	"fmt"
-	"math"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/serial"
+	"github.com/erni27/regression/internal/ts"
 )
 
+// kind identifies logistic regression models in their serialized form.
+const kind = "logistic"
+
+// DefaultThreshold is the decision threshold used if no other was chosen.
+const DefaultThreshold = 0.5
+
 // A model is a logistic regression model.
 type model struct {
 	coeffs []float64
 	acc    float64
+	// threshold is the decision threshold. Zero value means DefaultThreshold.
+	threshold float64
+	// report is the training report, nil if the model wasn't trained with gradient descent.
+	report *regression.Report
+	// held marks training examples held out for validation, which the accuracy isn't calculated on,
+	// nil if none were.
+	held []bool
 }
 
 func (m model) Predict(x []float64) (int, error) {
+	p, err := m.PredictProba(x)
+	if err != nil {
+		return 0, err
+	}
+	return classify(p, m.cutoff()), nil
+}
+
+func (m model) PredictProba(x []float64) (float64, error) {
+	if err := ts.ValidateFeatureVector(x, len(m.coeffs)-1); err != nil {
+		return 0, err
+	}
 	// Include dummy feature equals 1 at the beginning.
-	hr, err := hyphothesis(append([]float64{1}, x...), m.coeffs)
+	return hyphothesis(append([]float64{1}, x...), m.coeffs)
+}
+
+// PredictSparse returns the predicated class for the given sparse input.
+func (m model) PredictSparse(x regression.SparseVector) (int, error) {
+	p, err := m.PredictProbaSparse(x)
 	if err != nil {
 		return 0, err
 	}
-	return int(math.Round(hr)), nil
+	return classify(p, m.cutoff()), nil
+}
+
+// PredictProbaSparse returns the estimated probability that the given sparse input belongs to the positive class.
+func (m model) PredictProbaSparse(x regression.SparseVector) (float64, error) {
+	if err := ts.ValidateSparseVector(x, len(m.coeffs)-1); err != nil {
+		return 0, err
+	}
+	return sparseHyphothesis(x, m.coeffs)
 }
 
 func (m model) Coefficients() []float64 {
@@ -30,22 +70,101 @@ func (m model) Accuracy() float64 {
 	return m.acc
 }
 
+// Threshold returns the decision threshold used by the model.
+func (m model) Threshold() float64 {
+	return m.cutoff()
+}
+
+// Report returns the report of the model's training with gradient descent.
+func (m model) Report() (regression.Report, bool) {
+	if m.report == nil {
+		return regression.Report{}, false
+	}
+	r := *m.report
+	r.LearningCurve = append([]regression.Iteration(nil), r.LearningCurve...)
+	return r, true
+}
+
 func (m model) String() string {
 	s := fmt.Sprintf("y = round(%f", m.coeffs[0])
 	for i, coeff := range m.coeffs[1:] {
 		s += fmt.Sprintf(" + x%d*%f", i+1, coeff)
 	}
+	if t := m.cutoff(); t != DefaultThreshold {
+		return s + fmt.Sprintf(", threshold=%f)", t)
+	}
 	return s + ")"
 }
 
-func calcAccuracy(x [][]float64, y []float64, coeffs []float64) (float64, error) {
+func (m model) cutoff() float64 {
+	if m.threshold == 0 {
+		return DefaultThreshold
+	}
+	return m.threshold
+}
+
+// MarshalJSON encodes the model as JSON.
+func (m model) MarshalJSON() ([]byte, error) {
+	return serial.MarshalJSON(m.serial())
+}
+
+// MarshalBinary encodes the model in a compact binary form.
+func (m model) MarshalBinary() ([]byte, error) {
+	return serial.MarshalBinary(m.serial())
+}
+
+func (m model) serial() serial.Model {
+	return serial.Model{
+		Kind:         kind,
+		Coefficients: m.coeffs,
+		Accuracy:     m.acc,
+		Params:       map[string]float64{"threshold": m.cutoff()},
+	}
+}
+
+// Load restores a logistic regression model (binary or multiclass) encoded either as JSON or in the binary form.
+//
+// A trained model can be encoded with json.Marshal or through the encoding.BinaryMarshaler interface.
+// Load returns regression.ErrModelKindMismatch if data holds a model trained by a different package.
+func Load(data []byte) (regression.Model[int], error) {
+	sm, err := serial.Unmarshal(data, kind, oneVsRestKind, softmaxKind)
+	if err != nil {
+		return nil, err
+	}
+	if sm.Kind != kind {
+		return loadMulticlass(sm)
+	}
+	t, ok := sm.Params["threshold"]
+	if !ok {
+		t = DefaultThreshold
+	}
+	if !isValidThreshold(t) {
+		return nil, regression.ErrInvalidModel
+	}
+	return model{coeffs: sm.Coefficients, acc: sm.Accuracy, threshold: t}, nil
+}
+
+// classify returns the positive class if the probability p reaches the threshold t.
+func classify(p, t float64) int {
+	if p >= t {
+		return 1
+	}
+	return 0
+}
+
+// isValidThreshold checks if t is a probability strictly between 0 and 1.
+func isValidThreshold(t float64) bool {
+	return t > 0 && t < 1
+}
+
+func calcAccuracy(x [][]float64, y []float64, coeffs []float64, t float64) (float64, error) {
 	var correct int
 	for i := 0; i < len(x); i++ {
 		hr, err := hyphothesis(x[i], coeffs)
 		if err != nil {
 			return 0, err
 		}
-		if int(math.Round(hr)) == int(y[i]) {
+		if classify(hr, t) == int(y[i]) {
 			correct++
 		}
 	}
@@ -1,10 +1,13 @@
 package logistic
 
 import (
+	"encoding/json"
+	"errors"
 	"reflect"
 	"testing"
 
 	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
 )
 
 func TestPredict(t *testing.T) {
@@ -53,7 +56,7 @@ func TestPredict_InvalidFeatureVector(t *testing.T) {
 				coeffs: tt.coeffs,
 			}
 			_, err := m.Predict(tt.arg)
-			if err != regression.ErrInvalidFeatureVector {
+			if !errors.Is(err, regression.ErrInvalidFeatureVector) {
 				t.Fatalf("want %v, got %v", regression.ErrInvalidFeatureVector, err)
 			}
 		})
@@ -107,3 +110,110 @@ func TestAccuracy(t *testing.T) {
 		})
 	}
 }
+
+func TestLoad(t *testing.T) {
+	m := model{coeffs: []float64{-7.465, 33.217, -4.415}, acc: 0.6, threshold: 0.35}
+	tests := []struct {
+		name    string
+		marshal func() ([]byte, error)
+	}{
+		{name: "json", marshal: func() ([]byte, error) { return json.Marshal(m) }},
+		{name: "binary", marshal: m.MarshalBinary},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.marshal()
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			got, err := Load(data)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if !reflect.DeepEqual(got, m) {
+				t.Fatalf("want %v, got %v", m, got)
+			}
+		})
+	}
+}
+
+func TestLoad_ModelKindMismatch(t *testing.T) {
+	data := []byte(`{"version":1,"kind":"linear","coefficients":[1,2],"accuracy":0.7}`)
+	if _, err := Load(data); err != regression.ErrModelKindMismatch {
+		t.Fatalf("want %v, got %v", regression.ErrModelKindMismatch, err)
+	}
+}
+
+func TestLoad_InvalidMulticlass(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "single class", data: `{"version":2,"kind":"logistic-ovr","coefficients":[1,2],"classes":[1]}`},
+		{name: "no coefficients", data: `{"version":2,"kind":"logistic-softmax","coefficients":[],"classes":[1,2]}`},
+		{name: "uneven coefficients", data: `{"version":2,"kind":"logistic-softmax","coefficients":[1,2,3],"classes":[1,2]}`},
+		{name: "unsorted classes", data: `{"version":2,"kind":"logistic-ovr","coefficients":[1,2,3,4],"classes":[2,1]}`},
+		{name: "duplicate classes", data: `{"version":2,"kind":"logistic-softmax","coefficients":[1,2,3,4],"classes":[1,1]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load([]byte(tt.data)); err != regression.ErrInvalidModel {
+				t.Fatalf("want %v, got %v", regression.ErrInvalidModel, err)
+			}
+		})
+	}
+}
+
+func TestPredictProba(t *testing.T) {
+	tests := []struct {
+		name   string
+		arg    []float64
+		coeffs []float64
+		want   float64
+	}{
+		{name: "z=0", arg: []float64{2}, coeffs: []float64{-1, 0.5}, want: 0.5},
+		{name: "z=1", arg: []float64{1, 1}, coeffs: []float64{0, 2, -1}, want: 0.731059},
+		{name: "z=-2", arg: []float64{4}, coeffs: []float64{2, -1}, want: 0.119203},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m regression.ProbabilisticModel = model{coeffs: tt.coeffs}
+			got, err := m.PredictProba(tt.arg)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if !regressiontest.AreFloatEqual(got, tt.want, 6) {
+				t.Fatalf("want %f, got %f", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPredict_Threshold(t *testing.T) {
+	// The estimated probability equals 0.731059.
+	arg := []float64{1, 1}
+	coeffs := []float64{0, 2, -1}
+	tests := []struct {
+		name      string
+		threshold float64
+		want      int
+	}{
+		{name: "default", threshold: 0, want: 1},
+		{name: "t=0.7", threshold: 0.7, want: 1},
+		{name: "t=0.731058", threshold: 0.731058, want: 1},
+		{name: "t=0.75", threshold: 0.75, want: 0},
+		{name: "t=0.9", threshold: 0.9, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := model{coeffs: coeffs, threshold: tt.threshold}
+			got, err := m.Predict(arg)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("want %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
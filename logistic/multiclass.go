@@ -0,0 +1,245 @@
+package logistic
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/serial"
+	"github.com/erni27/regression/internal/ts"
+)
+
+const (
+	// oneVsRestKind identifies one-vs-rest models in their serialized form.
+	oneVsRestKind = "logistic-ovr"
+	// softmaxKind identifies softmax models in their serialized form.
+	softmaxKind = "logistic-softmax"
+)
+
+// strategy identifies how a multiclass model estimates class probabilities.
+type strategy int
+
+const (
+	oneVsRest strategy = iota + 1
+	softmax
+)
+
+// A multiclassModel is a multiclass logistic regression model.
+type multiclassModel struct {
+	classes  []int
+	coeffs   [][]float64
+	acc      float64
+	strategy strategy
+	// report is the training report of softmax regression, nil if the model wasn't trained with gradient descent
+	// as a whole.
+	report *regression.Report
+}
+
+func (m multiclassModel) Predict(x []float64) (int, error) {
+	p, err := m.ClassProbabilities(x)
+	if err != nil {
+		return 0, err
+	}
+	return m.classes[argmax(p)], nil
+}
+
+func (m multiclassModel) ClassProbabilities(x []float64) ([]float64, error) {
+	if err := ts.ValidateFeatureVector(x, len(m.coeffs[0])-1); err != nil {
+		return nil, err
+	}
+	// Include dummy feature equals 1 at the beginning.
+	return m.probabilities(append([]float64{1}, x...))
+}
+
+func (m multiclassModel) Classes() []int {
+	classes := make([]int, len(m.classes))
+	copy(classes, m.classes)
+	return classes
+}
+
+func (m multiclassModel) ClassCoefficients() [][]float64 {
+	coeffs := make([][]float64, len(m.coeffs))
+	for k := range m.coeffs {
+		coeffs[k] = make([]float64, len(m.coeffs[k]))
+		copy(coeffs[k], m.coeffs[k])
+	}
+	return coeffs
+}
+
+func (m multiclassModel) Coefficients() []float64 {
+	var coeffs []float64
+	for k := range m.coeffs {
+		coeffs = append(coeffs, m.coeffs[k]...)
+	}
+	return coeffs
+}
+
+func (m multiclassModel) Accuracy() float64 {
+	return m.acc
+}
+
+// Report returns the report of the model's training with gradient descent.
+func (m multiclassModel) Report() (regression.Report, bool) {
+	if m.report == nil {
+		return regression.Report{}, false
+	}
+	r := *m.report
+	r.LearningCurve = append([]regression.Iteration(nil), r.LearningCurve...)
+	return r, true
+}
+
+func (m multiclassModel) String() string {
+	var s string
+	for k, c := range m.classes {
+		if k > 0 {
+			s += "\n"
+		}
+		s += fmt.Sprintf("class %d: z = %f", c, m.coeffs[k][0])
+		for i, coeff := range m.coeffs[k][1:] {
+			s += fmt.Sprintf(" + x%d*%f", i+1, coeff)
+		}
+	}
+	return s
+}
+
+// MarshalJSON encodes the model as JSON.
+func (m multiclassModel) MarshalJSON() ([]byte, error) {
+	return serial.MarshalJSON(m.serial())
+}
+
+// MarshalBinary encodes the model in a compact binary form.
+func (m multiclassModel) MarshalBinary() ([]byte, error) {
+	return serial.MarshalBinary(m.serial())
+}
+
+func (m multiclassModel) serial() serial.Model {
+	k := oneVsRestKind
+	if m.strategy == softmax {
+		k = softmaxKind
+	}
+	return serial.Model{Kind: k, Coefficients: m.Coefficients(), Accuracy: m.acc, Classes: m.classes}
+}
+
+// loadMulticlass restores a multiclass model from its serialized form. Class labels must be distinct and
+// in ascending order, like the ones returned by classesOf, and each of them must have a non-empty row of coefficients.
+func loadMulticlass(sm serial.Model) (regression.Model[int], error) {
+	k := len(sm.Classes)
+	if k < 2 || len(sm.Coefficients) == 0 || len(sm.Coefficients)%k != 0 {
+		return nil, regression.ErrInvalidModel
+	}
+	for i := 1; i < k; i++ {
+		if sm.Classes[i] <= sm.Classes[i-1] {
+			return nil, regression.ErrInvalidModel
+		}
+	}
+	m := multiclassModel{classes: sm.Classes, coeffs: split(sm.Coefficients, k), acc: sm.Accuracy, strategy: oneVsRest}
+	if sm.Kind == softmaxKind {
+		m.strategy = softmax
+	}
+	return m, nil
+}
+
+// probabilities calculates class probabilities for the given feature vector (including the dummy feature).
+func (m multiclassModel) probabilities(x []float64) ([]float64, error) {
+	if m.strategy == softmax {
+		return softmaxProbabilities(x, m.coeffs)
+	}
+	p := make([]float64, len(m.coeffs))
+	var sum float64
+	for k := range m.coeffs {
+		hr, err := hyphothesis(x, m.coeffs[k])
+		if err != nil {
+			return nil, err
+		}
+		p[k] = hr
+		sum += hr
+	}
+	// Normalize the binary classifiers' outputs so that they sum up to one.
+	for k := range p {
+		p[k] /= sum
+	}
+	return p, nil
+}
+
+// softmaxProbabilities calculates the softmax function value for the given feature vector (including the dummy feature).
+//
+// The softmax function equals p(k)=e^(OkX)/sum(e^(OjX)), where Ok stands for coefficients vector of the k-th class.
+func softmaxProbabilities(x []float64, coeffs [][]float64) ([]float64, error) {
+	p := make([]float64, len(coeffs))
+	max := math.Inf(-1)
+	for k := range coeffs {
+		if len(x) != len(coeffs[k]) {
+			return nil, regression.ErrInvalidFeatureVector
+		}
+		for i := range x {
+			p[k] += x[i] * coeffs[k][i]
+		}
+		if p[k] > max {
+			max = p[k]
+		}
+	}
+	// Subtract the maximum before exponentiation to avoid an overflow.
+	var sum float64
+	for k := range p {
+		p[k] = math.Exp(p[k] - max)
+		sum += p[k]
+	}
+	for k := range p {
+		p[k] /= sum
+	}
+	return p, nil
+}
+
+// calcMulticlassAccuracy calculates a fraction of correctly classified training examples.
+// A design matrix must include dummy features.
+func calcMulticlassAccuracy(x [][]float64, y []float64, m multiclassModel) (float64, error) {
+	var correct int
+	for i := 0; i < len(x); i++ {
+		p, err := m.probabilities(x[i])
+		if err != nil {
+			return 0, err
+		}
+		if m.classes[argmax(p)] == int(y[i]) {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(x)), nil
+}
+
+// classesOf returns distinct class labels of a target vector in ascending order.
+// A target vector must consist of integers within the range of int and contain at least two classes.
+func classesOf(y []float64) ([]int, error) {
+	seen := make(map[int]bool)
+	var classes []int
+	for i, v := range y {
+		if !isClassLabel(v) {
+			return nil, &regression.ValidationError{Err: regression.ErrInvalidTrainingSet, Reason: regression.NonIntegerTarget, Row: i, Column: -1, Detail: fmt.Sprintf("target %v", v)}
+		}
+		if c := int(v); !seen[c] {
+			seen[c] = true
+			classes = append(classes, c)
+		}
+	}
+	if len(classes) < 2 {
+		return nil, &regression.ValidationError{Err: regression.ErrInvalidTrainingSet, Reason: regression.SingleClass, Row: -1, Column: -1}
+	}
+	sort.Ints(classes)
+	return classes, nil
+}
+
+// isClassLabel checks if v is an integer within the range of int, so it converts to a class label exactly.
+func isClassLabel(v float64) bool {
+	return v == math.Trunc(v) && v >= math.MinInt && v < -math.MinInt
+}
+
+// argmax returns the index of the largest value.
+func argmax(v []float64) int {
+	var j int
+	for i := range v {
+		if v[i] > v[j] {
+			j = i
+		}
+	}
+	return j
+}
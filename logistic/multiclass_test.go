@@ -0,0 +1,100 @@
+package logistic
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
+)
+
+func TestMulticlassPredict(t *testing.T) {
+	coeffs := [][]float64{
+		{1, -1, 0},
+		{0, 1, -1},
+		{-1, 0, 1},
+	}
+	tests := []struct {
+		name     string
+		strategy strategy
+		arg      []float64
+		want     int
+		probs    []float64
+	}{
+		{name: "one-vs-rest class 0", strategy: oneVsRest, arg: []float64{0, 0}, want: 0, probs: []float64{0.487, 0.333, 0.179}},
+		{name: "one-vs-rest class 5", strategy: oneVsRest, arg: []float64{3, 0}, want: 5, probs: []float64{0.089, 0.71, 0.201}},
+		{name: "softmax class 0", strategy: softmax, arg: []float64{0, 0}, want: 0, probs: []float64{0.665, 0.245, 0.09}},
+		{name: "softmax class 9", strategy: softmax, arg: []float64{0, 3}, want: 9, probs: []float64{0.268, 0.005, 0.727}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := multiclassModel{classes: []int{0, 5, 9}, coeffs: coeffs, strategy: tt.strategy}
+			got, err := m.Predict(tt.arg)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("want %d, got %d", tt.want, got)
+			}
+			probs, err := m.ClassProbabilities(tt.arg)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			if !regressiontest.AreFloatSlicesEqual(probs, tt.probs, 3) {
+				t.Fatalf("want %v, got %v", tt.probs, probs)
+			}
+		})
+	}
+}
+
+func TestMulticlassPredict_InvalidFeatureVector(t *testing.T) {
+	for _, s := range []strategy{oneVsRest, softmax} {
+		m := multiclassModel{classes: []int{0, 1}, coeffs: [][]float64{{1, 2}, {3, 4}}, strategy: s}
+		if _, err := m.Predict([]float64{1, 2}); !errors.Is(err, regression.ErrInvalidFeatureVector) {
+			t.Fatalf("want %v, got %v", regression.ErrInvalidFeatureVector, err)
+		}
+	}
+}
+
+func TestMulticlassCoefficients(t *testing.T) {
+	m := multiclassModel{classes: []int{1, 2}, coeffs: [][]float64{{1, 2}, {3, 4}}}
+	if got, want := m.Coefficients(), []float64{1, 2, 3, 4}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+	got := m.ClassCoefficients()
+	got[0][0] = 100
+	if m.coeffs[0][0] != 1 {
+		t.Fatalf("want coefficients copy, got the underlying slice")
+	}
+}
+
+func TestLoad_Multiclass(t *testing.T) {
+	coeffs := [][]float64{{1.5, -2}, {0.25, 3}, {-1, 0.125}}
+	tests := []struct {
+		name     string
+		strategy strategy
+	}{
+		{name: "one-vs-rest", strategy: oneVsRest},
+		{name: "softmax", strategy: softmax},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := multiclassModel{classes: []int{-1, 0, 7}, coeffs: coeffs, acc: 0.75, strategy: tt.strategy}
+			for _, marshal := range []func() ([]byte, error){func() ([]byte, error) { return json.Marshal(m) }, m.MarshalBinary} {
+				data, err := marshal()
+				if err != nil {
+					t.Fatalf("want nil, got error %v", err)
+				}
+				got, err := Load(data)
+				if err != nil {
+					t.Fatalf("want nil, got error %v", err)
+				}
+				if !reflect.DeepEqual(got, m) {
+					t.Fatalf("want %v, got %v", m, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package logistic
+
+import (
+	"context"
+	"math"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/long"
+	"github.com/erni27/regression/internal/matrix"
+	"github.com/erni27/regression/internal/ts"
+)
+
+const (
+	// maxNewtonIterations limits the number of Newton's method iterations.
+	maxNewtonIterations = 100
+	// maxStepHalvings limits the number of times a single Newton's step can be halved.
+	maxStepHalvings = 30
+	// newtonTolerance determines the convergence of Newton's method. It's compared against the largest change
+	// of a coefficient, relative to the largest coefficient.
+	newtonTolerance = 1e-10
+)
+
+// WithNewton initializes logistic regression with Newton's method (iteratively reweighted least squares).
+// It finds the value of coefficients by solving a weighted least squares problem built from the cost function
+// gradient and Hessian in each iteration. Unlike gradient descent, it doesn't need a learning rate and usually
+// converges in a handful of iterations, but every iteration inverts a matrix as big as the number of features.
+//
+// If training examples are perfectly separable, the cost function has no minimum (coefficients grow without
+// bound) and ErrPerfectSeparation is returned.
+func WithNewton() regression.Regression[int] {
+	var f regression.RegressionFunc[int] = func(ctx context.Context, s regression.TrainingSet) (regression.Model[int], error) {
+		return runNewton(ctx, s)
+	}
+	return f
+}
+
+// runNewton runs logistic regression for given training set. It uses Newton's method for computing coefficients.
+func runNewton(ctx context.Context, s regression.TrainingSet) (regression.Model[int], error) {
+	if err := ts.ValidateBinary(s); err != nil {
+		return nil, err
+	}
+	x := ts.AddDummies(s.X)
+	y := s.Y
+	coeffs, err := long.Run(ctx, func() ([]float64, error) { return newton(ctx, x, y) })
+	if err != nil {
+		return nil, err
+	}
+	acc, err := calcAccuracy(x, y, coeffs, DefaultThreshold)
+	if err != nil {
+		return nil, err
+	}
+	return model{coeffs: coeffs, acc: acc}, nil
+}
+
+// newton minimizes the logistic regression cost function with Newton's method.
+//
+// Each iteration takes the step O=O+(X'WX)^-1*X'(y-h), where W is a diagonal matrix of weights h*(1-h).
+// The step is halved until the cost function decreases.
+func newton(ctx context.Context, x [][]float64, y []float64) ([]float64, error) {
+	m, n := len(x), len(x[0])
+	coeffs := make([]float64, n)
+	c, err := cost(x, y, coeffs)
+	if err != nil {
+		return nil, err
+	}
+	for k := 0; k < maxNewtonIterations; k++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		g := make([]float64, n)
+		h := make([][]float64, n)
+		for j := range h {
+			h[j] = make([]float64, n)
+		}
+		for i := 0; i < m; i++ {
+			hr, err := hyphothesis(x[i], coeffs)
+			if err != nil {
+				return nil, err
+			}
+			w := hr * (1 - hr)
+			for j := 0; j < n; j++ {
+				g[j] += (y[i] - hr) * x[i][j]
+				for l := 0; l <= j; l++ {
+					h[j][l] += w * x[i][j] * x[i][l]
+				}
+			}
+		}
+		// The Hessian is symmetric.
+		for j := 0; j < n; j++ {
+			for l := j + 1; l < n; l++ {
+				h[j][l] = h[l][j]
+			}
+		}
+		inv, err := matrix.Inverse(ctx, h)
+		if err == matrix.ErrNonInvertibleMatrix && diverged(x, coeffs) == ErrPerfectSeparation {
+			// Saturated probabilities make the Hessian numerically singular.
+			return nil, ErrPerfectSeparation
+		}
+		if err != nil {
+			return nil, err
+		}
+		step, err := matrix.MultiplyByVector(ctx, inv, g)
+		if err != nil {
+			return nil, err
+		}
+		nc := make([]float64, n)
+		var nv float64
+		for t := 0; ; t++ {
+			if t == maxStepHalvings {
+				return nil, diverged(x, coeffs)
+			}
+			for j := range nc {
+				nc[j] = coeffs[j] + step[j]
+				if math.IsNaN(nc[j]) || math.IsInf(nc[j], 0) {
+					return nil, diverged(x, coeffs)
+				}
+			}
+			if isSeparating(x, y, nc) {
+				return nil, ErrPerfectSeparation
+			}
+			if nv, err = cost(x, y, nc); err != nil {
+				return nil, err
+			}
+			if nv <= c {
+				break
+			}
+			for j := range step {
+				step[j] /= 2
+			}
+		}
+		var d, s float64
+		for j := range nc {
+			d = math.Max(d, math.Abs(nc[j]-coeffs[j]))
+			s = math.Max(s, math.Abs(nc[j]))
+		}
+		coeffs, c = nc, nv
+		if d <= newtonTolerance*(1+s) {
+			return coeffs, nil
+		}
+	}
+	return nil, diverged(x, coeffs)
+}
+
+// diverged returns the error explaining why Newton's method didn't converge. If some probabilities estimated
+// with the last coefficients are numerically 0 or 1, training examples are quasi-completely separable
+// (separable except for examples on the decision boundary) and ErrPerfectSeparation is returned.
+func diverged(x [][]float64, coeffs []float64) error {
+	for i := range x {
+		hr, err := hyphothesis(x[i], coeffs)
+		if err != nil {
+			return err
+		}
+		if hr < 1e-10 || hr > 1-1e-10 {
+			return ErrPerfectSeparation
+		}
+	}
+	return regression.ErrCannotConverge
+}
+
+// isSeparating checks if coefficients perfectly separate training examples, i.e. OX is positive
+// for every positive example and negative for every negative one.
+func isSeparating(x [][]float64, y []float64, coeffs []float64) bool {
+	for i := range x {
+		var z float64
+		for j := range coeffs {
+			z += x[i][j] * coeffs[j]
+		}
+		if (y[i] == 1 && z <= 0) || (y[i] != 1 && z >= 0) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package logistic
+
+import (
+	"context"
+	"testing"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
+)
+
+func TestRun_WithNewton(t *testing.T) {
+	s, err := regressiontest.LoadTrainingSet("n=2_m=100.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	got, err := WithNewton().Run(context.Background(), s)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	// Newton's method finds the same minimum as the unregularized coordinate descent.
+	want := []float64{-25.161, 0.206, 0.201}
+	if coeffs := got.Coefficients(); !regressiontest.AreFloatSlicesEqual(coeffs, want, 3) {
+		t.Errorf("got coefficients %v, want %v", coeffs, want)
+	}
+	if acc := got.Accuracy(); acc != 0.89 {
+		t.Errorf("got acc %v, want %v", acc, 0.89)
+	}
+	if _, ok := got.(regression.ProbabilisticModel); !ok {
+		t.Errorf("want model implementing regression.ProbabilisticModel")
+	}
+}
+
+func TestRun_WithNewton_PerfectSeparation(t *testing.T) {
+	tests := []struct {
+		name string
+		s    regression.TrainingSet
+	}{
+		{
+			name: "complete separation",
+			s:    regression.TrainingSet{X: [][]float64{{1}, {2}, {3}, {4}}, Y: []float64{0, 0, 1, 1}},
+		},
+		{
+			name: "quasi-complete separation",
+			s:    regression.TrainingSet{X: [][]float64{{1}, {2}, {3}, {3}, {4}}, Y: []float64{0, 0, 0, 1, 1}},
+		},
+		{
+			name: "single class",
+			s:    regression.TrainingSet{X: [][]float64{{1, 2}, {2, 1}, {3, 5}}, Y: []float64{1, 1, 1}},
+		},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := WithNewton().Run(ctx, tt.s); err != ErrPerfectSeparation {
+				t.Fatalf("want %v, got %v", ErrPerfectSeparation, err)
+			}
+		})
+	}
+}
+
+func TestRun_WithNewton_Overlapping(t *testing.T) {
+	s := regression.TrainingSet{X: [][]float64{{1}, {2}, {3}, {4}, {2.5}}, Y: []float64{0, 1, 0, 1, 1}}
+	got, err := WithNewton().Run(context.Background(), s)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	want := []float64{-1.901, 0.956}
+	if coeffs := got.Coefficients(); !regressiontest.AreFloatSlicesEqual(coeffs, want, 3) {
+		t.Errorf("got coefficients %v, want %v", coeffs, want)
+	}
+}
@@ -0,0 +1,71 @@
+package logistic
+
+import (
+	"context"
+	"sync"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/gd"
+	"github.com/erni27/regression/internal/ts"
+	"golang.org/x/sync/errgroup"
+)
+
+// OneVsRest initializes multiclass logistic regression with one-vs-rest strategy.
+// It trains one binary model per class with the given regression, separating the class from all the others.
+// Binary models are trained in parallel. An observer in options of the binary regression is called by one of them
+// at a time with options.Progress.Class set to the class of the binary model.
+//
+// A target vector must consist of integer class labels. A trained model implements regression.MulticlassModel
+// and predicts the class whose binary model estimates the highest probability.
+func OneVsRest(r regression.Regression[int]) regression.Regression[int] {
+	var f regression.RegressionFunc[int] = func(ctx context.Context, s regression.TrainingSet) (regression.Model[int], error) {
+		return runOneVsRest(ctx, r, s)
+	}
+	return f
+}
+
+// runOneVsRest runs one-vs-rest multiclass logistic regression for given training set.
+func runOneVsRest(ctx context.Context, r regression.Regression[int], s regression.TrainingSet) (regression.Model[int], error) {
+	// The number of rows is validated by the binary regression, which may accept more features than training examples.
+	if err := ts.ValidateWide(s); err != nil {
+		return nil, err
+	}
+	classes, err := classesOf(s.Y)
+	if err != nil {
+		return nil, err
+	}
+	coeffs := make([][]float64, len(classes))
+	g, gctx := errgroup.WithContext(ctx)
+	// Binary models share the options, so calls of their observer are serialized.
+	var observe sync.Mutex
+	for k, c := range classes {
+		k, c := k, c
+		g.Go(func() error {
+			// Separate the class from all the others.
+			y := make([]float64, len(s.Y))
+			for i, v := range s.Y {
+				if int(v) == c {
+					y[i] = 1
+				}
+			}
+			rm, err := r.Run(gd.WithClass(gctx, c, &observe), regression.TrainingSet{X: s.X, Y: y})
+			if err != nil {
+				return err
+			}
+			m, ok := rm.(model)
+			if !ok {
+				return ErrUnsupportedModel
+			}
+			coeffs[k] = m.coeffs
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	m := multiclassModel{classes: classes, coeffs: coeffs, strategy: oneVsRest}
+	if m.acc, err = calcMulticlassAccuracy(ts.AddDummies(s.X), s.Y, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
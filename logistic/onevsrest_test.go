@@ -0,0 +1,112 @@
+package logistic
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
+	"github.com/erni27/regression/options"
+)
+
+func TestRun_OneVsRest(t *testing.T) {
+	s, err := regressiontest.LoadTrainingSet("n=2_m=150_k=3.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	r := OneVsRest(WithGradientDescent(options.WithIterativeConvergence(0.001, options.Batch, 1000)))
+	got, err := r.Run(context.Background(), s)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if acc := got.Accuracy(); !regressiontest.AreFloatEqual(acc, 0.993, 3) {
+		t.Errorf("got acc %v, want %v", acc, 0.993)
+	}
+	m, ok := got.(regression.MulticlassModel)
+	if !ok {
+		t.Fatalf("want multiclass model, got %T", got)
+	}
+	if classes := m.Classes(); !reflect.DeepEqual(classes, []int{1, 2, 3}) {
+		t.Errorf("got classes %v, want %v", classes, []int{1, 2, 3})
+	}
+	want := [][]float64{
+		{6.057, -1.018, -0.640},
+		{-4.791, 1.420, -0.959},
+		{-5.405, -0.550, 1.552},
+	}
+	if coeffs := m.ClassCoefficients(); !regressiontest.Are2DFloatSlicesEqual(coeffs, want, 3) {
+		t.Errorf("got coefficients %v, want %v", coeffs, want)
+	}
+}
+
+func TestRun_OneVsRest_InvalidTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		y    []float64
+	}{
+		{name: "single class", y: []float64{1, 1, 1, 1}},
+		{name: "non integer label", y: []float64{0, 1, 2.5, 1}},
+		{name: "label out of int range", y: []float64{0, 1, 1e300, 1}},
+		{name: "infinite label", y: []float64{0, 1, math.Inf(1), 1}},
+	}
+	r := OneVsRest(WithGradientDescent(options.WithIterativeConvergence(0.01, options.Batch, 10)))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := regression.TrainingSet{X: [][]float64{{1}, {2}, {3}, {4}}, Y: tt.y}
+			if _, err := r.Run(context.Background(), s); !errors.Is(err, regression.ErrInvalidTrainingSet) {
+				t.Fatalf("want %v, got %v", regression.ErrInvalidTrainingSet, err)
+			}
+		})
+	}
+}
+
+func TestRun_OneVsRest_ContextCanceled(t *testing.T) {
+	s := regressiontest.RandomTrainingSet(1, 2000, 10, false)
+	// Split targets into four classes.
+	for i, v := range s.Y {
+		s.Y[i] = math.Max(0, math.Min(3, math.Floor(v/4+2)))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls int
+	// The context is canceled after the third iteration of any binary model, the others stop as well.
+	// Observer calls are serialized, so the counter needs no synchronization.
+	cancelAt := options.ObserverFunc(func(p options.Progress) error {
+		if calls++; calls == 3 {
+			cancel()
+		}
+		return nil
+	})
+	r := OneVsRest(WithGradientDescent(options.WithIterativeConvergence(0.1, options.Batch, 1000000).WithObserver(cancelAt, 1)))
+	if _, err := r.Run(ctx, s); err != context.Canceled {
+		t.Fatalf("want %v, got %v", context.Canceled, err)
+	}
+	// Every binary model observes at most one more iteration, since it checks the context after each of them.
+	if calls > 3+4 {
+		t.Errorf("got %d observed iterations, want at most %d", calls, 3+4)
+	}
+}
+
+func TestRun_OneVsRest_Observer(t *testing.T) {
+	s, err := regressiontest.LoadTrainingSet("n=2_m=150_k=3.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	// Binary models are trained in parallel, but observer calls are serialized, so the map needs no synchronization.
+	iterations := make(map[int][]int)
+	observer := options.ObserverFunc(func(p options.Progress) error {
+		iterations[p.Class] = append(iterations[p.Class], p.Iteration)
+		return nil
+	})
+	r := OneVsRest(WithGradientDescent(options.WithIterativeConvergence(0.001, options.Batch, 6).WithObserver(observer, 2)))
+	if _, err := r.Run(context.Background(), s); err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	want := map[int][]int{1: {2, 4, 6}, 2: {2, 4, 6}, 3: {2, 4, 6}}
+	if !reflect.DeepEqual(iterations, want) {
+		t.Errorf("got observed iterations %v, want %v", iterations, want)
+	}
+}
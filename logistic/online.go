@@ -0,0 +1,73 @@
+package logistic
+
+import (
+	"context"
+	"sync"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/gd"
+	"github.com/erni27/regression/internal/ts"
+	"github.com/erni27/regression/options"
+)
+
+// An online is a binary logistic regression learning online with the stochastic gradient descent.
+type online struct {
+	g *gd.Online
+	n int
+	// mu serializes PartialFit calls and guards the progressive validation statistics: the number of training
+	// examples m and the number of them classified correctly.
+	mu      sync.Mutex
+	m       int
+	correct int
+}
+
+// NewOnline returns a new binary logistic regression learning online from training examples with n features.
+// Every training example passed to PartialFit is learned with a single step of the stochastic gradient descent,
+// so options must choose the options.Stochastic variant. A warm start or a checkpoint in options sets
+// the initial coefficients.
+//
+// The accuracy of a snapshot is the accuracy of progressive validation: every training example is classified
+// with the default threshold just before it's learned. It's zero until the first training example.
+func NewOnline(o options.Options, n int) (regression.OnlineRegression[int], error) {
+	if n < 1 {
+		return nil, regression.ErrInvalidFeatureVector
+	}
+	g, err := gd.NewOnline(o, hyphothesis, n+1)
+	if err != nil {
+		return nil, err
+	}
+	return &online{g: g, n: n}, nil
+}
+
+func (r *online) PartialFit(ctx context.Context, s regression.TrainingSet) error {
+	if err := ts.ValidateBinaryBatch(s, r.n); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// If Fit fails, the statistics still account for the training examples learned before the failing one.
+	p, err := r.g.Fit(ctx, ts.AddDummies(s.X), s.Y)
+	for i, hr := range p {
+		if classify(hr, DefaultThreshold) == int(s.Y[i]) {
+			r.correct++
+		}
+	}
+	r.m += len(p)
+	return err
+}
+
+func (r *online) Predict(x []float64) (int, error) {
+	return model{coeffs: r.g.Coefficients()}.Predict(x)
+}
+
+func (r *online) Snapshot() regression.Model[int] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var acc float64
+	if r.m > 0 {
+		acc = float64(r.correct) / float64(r.m)
+	}
+	coeffs := make([]float64, r.n+1)
+	copy(coeffs, r.g.Coefficients())
+	return model{coeffs: coeffs, acc: acc}
+}
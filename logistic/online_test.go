@@ -0,0 +1,90 @@
+package logistic
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
+	"github.com/erni27/regression/options"
+)
+
+func TestOnline_PartialFit(t *testing.T) {
+	r, err := NewOnline(options.WithIterativeConvergence(0.5, options.Stochastic, 1), 2)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	rnd := rand.New(rand.NewSource(1))
+	ctx := context.Background()
+	// Mini-batches of the stream classified by the line x1 + x2 = 1.
+	for k := 0; k < 100; k++ {
+		var s regression.TrainingSet
+		for i := 0; i < 20; i++ {
+			x := []float64{rnd.Float64(), rnd.Float64()}
+			s.X = append(s.X, x)
+			var y float64
+			if x[0]+x[1] >= 1 {
+				y = 1
+			}
+			s.Y = append(s.Y, y)
+		}
+		if err := r.PartialFit(ctx, s); err != nil {
+			t.Fatalf("want nil, got error %v", err)
+		}
+	}
+	m := r.Snapshot()
+	if acc := m.Accuracy(); acc < 0.9 {
+		t.Errorf("got progressive validation accuracy %v, want at least 0.9", acc)
+	}
+	for _, tt := range []struct {
+		x    []float64
+		want int
+	}{{x: []float64{0.1, 0.2}, want: 0}, {x: []float64{0.9, 0.8}, want: 1}} {
+		if got, err := r.Predict(tt.x); err != nil || got != tt.want {
+			t.Errorf("got %d and error %v for %v, want %d", got, err, tt.x, tt.want)
+		}
+	}
+	if _, ok := m.(regression.ProbabilisticModel); !ok {
+		t.Errorf("want a probabilistic model snapshot, got %T", m)
+	}
+}
+
+func TestOnline_PartialFit_NonBinaryTarget(t *testing.T) {
+	r, err := NewOnline(options.WithIterativeConvergence(0.5, options.Stochastic, 1), 1)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	err = r.PartialFit(context.Background(), regression.TrainingSet{X: [][]float64{{1}, {2}}, Y: []float64{0, 2}})
+	var verr *regression.ValidationError
+	if !errors.As(err, &verr) || verr.Reason != regression.NonBinaryTarget || verr.Row != 1 {
+		t.Fatalf("want %v at row 1, got %v", regression.NonBinaryTarget, err)
+	}
+}
+
+func TestOnline_PartialFit_Canceled(t *testing.T) {
+	o := options.WithIterativeConvergence(0.5, options.Stochastic, 1).WithRegularization(0.1)
+	s := regression.TrainingSet{X: [][]float64{{1, 2}, {2, 1}, {3, 5}, {4, 2}}, Y: []float64{1, 0, 1, 0}}
+	ctx := context.Background()
+	want, err := NewOnline(o, 2)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	r, err := NewOnline(o, 2)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	// The context is checked before every training example, so the first three are learned.
+	if err := r.PartialFit(regressiontest.CountdownContext(ctx, 3), s); err != context.Canceled {
+		t.Fatalf("want %v, got %v", context.Canceled, err)
+	}
+	if err := want.PartialFit(ctx, regression.TrainingSet{X: s.X[:3], Y: s.Y[:3]}); err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	got, wm := r.Snapshot(), want.Snapshot()
+	if !reflect.DeepEqual(got.Coefficients(), wm.Coefficients()) || got.Accuracy() != wm.Accuracy() {
+		t.Errorf("got %v with accuracy %v, want %v with accuracy %v", got.Coefficients(), got.Accuracy(), wm.Coefficients(), wm.Accuracy())
+	}
+}
@@ -0,0 +1,60 @@
+package logistic
+
+import (
+	"context"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/cd"
+	"github.com/erni27/regression/internal/long"
+	"github.com/erni27/regression/internal/ts"
+	"github.com/erni27/regression/options"
+)
+
+// RegularizationPath computes the elastic net regularization path for given training set.
+//
+// It trains one model per regularization strength, for a decreasing geometric sequence of strengths. The largest one
+// is derived from the training set as the smallest strength for which all coefficients (except the intercept) equal zero.
+// Each model is warm-started from the coefficients of the previous one, which makes computing the whole path
+// much faster than training every model from scratch.
+func RegularizationPath(ctx context.Context, s regression.TrainingSet, o options.PathOptions) ([]regression.PathPoint[int], error) {
+	if !o.IsValid() {
+		return nil, regression.ErrInvalidRegularizationPath
+	}
+	if err := ts.ValidateBinaryWide(s); err != nil {
+		return nil, err
+	}
+	x := ts.AddDummies(s.X)
+	y := s.Y
+	return long.Run(ctx, func() ([]regression.PathPoint[int], error) {
+		lambdas, coeffs, err := cd.Path(ctx, cd.Logistic, x, y, o.Alpha, o.Lambdas, o.MinRatio)
+		if err != nil {
+			return nil, err
+		}
+		path := make([]regression.PathPoint[int], len(lambdas))
+		w := make([]float64, len(x))
+		for k, l := range lambdas {
+			acc, err := calcAccuracy(x, y, coeffs[k], DefaultThreshold)
+			if err != nil {
+				return nil, err
+			}
+			c, err := cost(x, y, coeffs[k])
+			if err != nil {
+				return nil, err
+			}
+			// Weights of training examples come from the quadratic approximation of the cost function.
+			for i := range x {
+				hr, err := hyphothesis(x[i], coeffs[k])
+				if err != nil {
+					return nil, err
+				}
+				w[i] = hr * (1 - hr)
+			}
+			df, err := cd.DegreesOfFreedom(ctx, x, w, coeffs[k], (1-o.Alpha)*l)
+			if err != nil {
+				return nil, err
+			}
+			path[k] = regression.PathPoint[int]{Lambda: l, Model: model{coeffs: coeffs[k], acc: acc}, Cost: c, DegreesOfFreedom: df}
+		}
+		return path, nil
+	})
+}
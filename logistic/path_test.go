@@ -0,0 +1,90 @@
+package logistic
+
+import (
+	"context"
+	"testing"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
+	"github.com/erni27/regression/options"
+)
+
+func TestRegularizationPath(t *testing.T) {
+	type point struct {
+		lambda float64
+		coeffs []float64
+		acc    float64
+		df     float64
+	}
+	s, err := regressiontest.LoadTrainingSet("n=2_m=100.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	got, err := RegularizationPath(context.Background(), s, options.WithRegularizationPath(1, 4, 0.01))
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	want := []point{
+		{lambda: 544.479, coeffs: []float64{0.405, 0, 0}, acc: 0.6, df: 0},
+		{lambda: 117.304, coeffs: []float64{-8.276, 0.071, 0.065}, acc: 0.91, df: 2},
+		{lambda: 25.272, coeffs: []float64{-16.915, 0.14, 0.134}, acc: 0.89, df: 2},
+		{lambda: 5.445, coeffs: []float64{-22.596, 0.186, 0.181}, acc: 0.89, df: 2},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want %d models, got %d", len(want), len(got))
+	}
+	for k, p := range got {
+		if !regressiontest.AreFloatEqual(p.Lambda, want[k].lambda, 3) {
+			t.Errorf("got lambda %v, want %v", p.Lambda, want[k].lambda)
+		}
+		if coeffs := p.Model.Coefficients(); !regressiontest.AreFloatSlicesEqual(coeffs, want[k].coeffs, 3) {
+			t.Errorf("got coefficients %v for lambda %v, want %v", coeffs, p.Lambda, want[k].coeffs)
+		}
+		if acc := p.Model.Accuracy(); acc != want[k].acc {
+			t.Errorf("got acc %v for lambda %v, want %v", acc, p.Lambda, want[k].acc)
+		}
+		if p.DegreesOfFreedom != want[k].df {
+			t.Errorf("got %v degrees of freedom for lambda %v, want %v", p.DegreesOfFreedom, p.Lambda, want[k].df)
+		}
+		if k > 0 && p.Cost > got[k-1].Cost {
+			t.Errorf("want non-increasing cost, got %v after %v", p.Cost, got[k-1].Cost)
+		}
+	}
+}
+
+func TestRegularizationPath_ElasticNet(t *testing.T) {
+	s, err := regressiontest.LoadTrainingSet("n=2_m=100.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	ctx := context.Background()
+	got, err := RegularizationPath(ctx, s, options.WithRegularizationPath(0.5, 4, 0.01))
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	for k, p := range got {
+		// The L2 penalty shrinks the effective number of parameters below the number of non-zero coefficients.
+		if k > 0 && (p.DegreesOfFreedom <= 0 || p.DegreesOfFreedom >= 2) {
+			t.Errorf("got %v degrees of freedom for lambda %v, want value in (0,2)", p.DegreesOfFreedom, p.Lambda)
+		}
+		// Every model on the path equals the model trained from scratch.
+		want, err := WithElasticNet(0.5*p.Lambda, 0.5*p.Lambda).Run(ctx, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !regressiontest.AreFloatSlicesEqual(p.Model.Coefficients(), want.Coefficients(), 3) {
+			t.Errorf("got coefficients %v for lambda %v, want %v", p.Model.Coefficients(), p.Lambda, want.Coefficients())
+		}
+	}
+}
+
+func TestRegularizationPath_InvalidOptions(t *testing.T) {
+	s, err := regressiontest.LoadTrainingSet("n=2_m=100.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	o := options.WithRegularizationPath(1, 10, 0)
+	if _, err := RegularizationPath(context.Background(), s, o); err != regression.ErrInvalidRegularizationPath {
+		t.Fatalf("want %v, got %v", regression.ErrInvalidRegularizationPath, err)
+	}
+}
@@ -0,0 +1,148 @@
+package logistic
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/gd"
+	"github.com/erni27/regression/internal/long"
+	"github.com/erni27/regression/internal/ts"
+	"github.com/erni27/regression/options"
+)
+
+// WithSoftmax initializes multinomial logistic regression (softmax regression) with numerical approach.
+// It finds the value of coefficients of all classes at once by taking steps in each iteration towards
+// the minimum of a cross-entropy cost function.
+//
+// A target vector must consist of integer class labels. A trained model implements regression.MulticlassModel.
+func WithSoftmax(o options.Options) regression.Regression[int] {
+	var f regression.RegressionFunc[int] = func(ctx context.Context, s regression.TrainingSet) (regression.Model[int], error) {
+		return runSoftmax(ctx, o, s)
+	}
+	return f
+}
+
+// runSoftmax runs softmax regression for given training set.
+//
+// With early stopping, the validation set is held out of the training set and the accuracy is calculated on the rest.
+// Targets of a given validation set must be classes of the training set.
+func runSoftmax(ctx context.Context, o options.Options, s regression.TrainingSet) (regression.Model[int], error) {
+	// The regularization makes the cost function strictly convex even with more features than training examples.
+	validate := ts.Validate
+	if o.Regularization > 0 {
+		validate = ts.ValidateWide
+	}
+	if err := validate(s); err != nil {
+		return nil, err
+	}
+	classes, err := classesOf(s.Y)
+	if err != nil {
+		return nil, err
+	}
+	var v regression.TrainingSet
+	if o.EarlyStopping != nil {
+		if s, v, err = ts.HoldOut(*o.EarlyStopping, s, validate); err != nil {
+			return nil, err
+		}
+	}
+	x := ts.AddDummies(s.X)
+	// Gradient descent works on class indices rather than on class labels.
+	idx := make(map[int]int, len(classes))
+	for k, c := range classes {
+		idx[c] = k
+	}
+	y, err := classIndices(s.Y, idx)
+	if err != nil {
+		return nil, err
+	}
+	vx := ts.AddDummies(v.X)
+	vy, err := classIndices(v.Y, idx)
+	if err != nil {
+		return nil, err
+	}
+	k, n := len(classes), len(x[0])
+	g := gd.NewWithGradient(softmaxGradient(k), softmaxCost(k), k*n)
+	res, err := long.Run(ctx, func() (gd.Result, error) { return g.RunWithValidation(ctx, o, x, y, vx, vy) })
+	if err != nil {
+		return nil, err
+	}
+	m := multiclassModel{classes: classes, coeffs: split(res.Coefficients, k), strategy: softmax, report: &res.Report}
+	if m.acc, err = calcMulticlassAccuracy(x, s.Y, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// classIndices maps class labels of a target vector to class indices idx. Only a validation set may have
+// a target which isn't a known class label, then a *regression.ValidationError is returned.
+func classIndices(y []float64, idx map[int]int) ([]float64, error) {
+	ci := make([]float64, len(y))
+	for i, v := range y {
+		k, ok := 0, isClassLabel(v)
+		if ok {
+			k, ok = idx[int(v)]
+		}
+		if !ok {
+			return nil, &regression.ValidationError{Err: regression.ErrInvalidTrainingSet, Reason: regression.UnknownClass, Row: i, Column: -1, Detail: fmt.Sprintf("validation set, target %v", v)}
+		}
+		ci[i] = float64(k)
+	}
+	return ci, nil
+}
+
+// softmaxCost returns a cross-entropy cost function for softmax regression with k classes.
+// A target vector holds class indices.
+func softmaxCost(k int) gd.CostFunc {
+	return func(x [][]float64, y []float64, coeffs []float64) (float64, error) {
+		cs := split(coeffs, k)
+		var c float64
+		for i := 0; i < len(x); i++ {
+			p, err := softmaxProbabilities(x[i], cs)
+			if err != nil {
+				return 0, err
+			}
+			c -= math.Log(p[int(y[i])])
+		}
+		return c / float64(len(x)), nil
+	}
+}
+
+// softmaxGradient returns a cross-entropy cost function gradient for softmax regression with k classes.
+// A target vector holds class indices.
+//
+// A partial derivative with respect to the j-th coefficient of the k-th class equals sum((p(k)-1{y=k})*Xj).
+func softmaxGradient(k int) gd.GradientFunc {
+	return func(x [][]float64, y []float64, coeffs []float64) ([]float64, error) {
+		cs := split(coeffs, k)
+		n := len(coeffs) / k
+		g := make([]float64, len(coeffs))
+		for i := 0; i < len(x); i++ {
+			p, err := softmaxProbabilities(x[i], cs)
+			if err != nil {
+				return nil, err
+			}
+			for c := 0; c < k; c++ {
+				r := p[c]
+				if c == int(y[i]) {
+					r--
+				}
+				for j := 0; j < n; j++ {
+					g[c*n+j] += r * x[i][j]
+				}
+			}
+		}
+		return g, nil
+	}
+}
+
+// split splits a flat coefficients vector into k vectors of the same length.
+func split(coeffs []float64, k int) [][]float64 {
+	n := len(coeffs) / k
+	cs := make([][]float64, k)
+	for c := 0; c < k; c++ {
+		cs[c] = coeffs[c*n : (c+1)*n]
+	}
+	return cs
+}
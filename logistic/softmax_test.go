@@ -0,0 +1,120 @@
+package logistic
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/regressiontest"
+	"github.com/erni27/regression/options"
+)
+
+func TestRun_WithSoftmax(t *testing.T) {
+	type expected struct {
+		acc    float64
+		coeffs [][]float64
+	}
+	tests := []struct {
+		name    string
+		path    string
+		options options.Options
+		want    expected
+	}{
+		{
+			name:    "batch gd n=2 m=150 k=3 alpha=0.001 i=1000",
+			path:    "n=2_m=150_k=3.txt",
+			options: options.WithIterativeConvergence(0.001, options.Batch, 1000),
+			want: expected{acc: 1, coeffs: [][]float64{
+				{5.129, -0.605, -0.383},
+				{-2.396, 1.121, -0.846},
+				{-2.733, -0.516, 1.229},
+			}},
+		},
+		{
+			name:    "stochastic gd n=2 m=150 k=3 alpha=0.01 i=15000",
+			path:    "n=2_m=150_k=3.txt",
+			options: options.WithIterativeConvergence(0.01, options.Stochastic, 15000),
+			want: expected{acc: 0.993, coeffs: [][]float64{
+				{5.186, -0.618, -0.409},
+				{-2.424, 1.141, -0.851},
+				{-2.762, -0.523, 1.260},
+			}},
+		},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := WithSoftmax(tt.options)
+			s, err := regressiontest.LoadTrainingSet(tt.path)
+			if err != nil {
+				t.Fatalf("cannot load training set %v", err)
+			}
+			got, err := r.Run(ctx, s)
+			if err != nil {
+				t.Fatalf("want nil, got error %v", err)
+			}
+			m, ok := got.(regression.MulticlassModel)
+			if !ok {
+				t.Fatalf("want multiclass model, got %T", got)
+			}
+			if coeffs := m.ClassCoefficients(); !regressiontest.Are2DFloatSlicesEqual(coeffs, tt.want.coeffs, 3) {
+				t.Errorf("got coefficients %v, want %v", coeffs, tt.want.coeffs)
+			}
+			if acc := got.Accuracy(); !regressiontest.AreFloatEqual(acc, tt.want.acc, 3) {
+				t.Errorf("got acc %v, want %v", acc, tt.want.acc)
+			}
+		})
+	}
+}
+
+func TestSoftmaxGradient(t *testing.T) {
+	x := [][]float64{{1, 2}, {1, -1}, {1, 0.5}}
+	y := []float64{0, 1, 2}
+	coeffs := []float64{0.1, -0.2, 0.3, 0.4, -0.5, 0.6}
+	cost, grad := softmaxCost(3), softmaxGradient(3)
+	got, err := grad(x, y, coeffs)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	// Compare with the numerical derivative of the cost function scaled by the number of training examples.
+	const h = 1e-6
+	for j := range coeffs {
+		cp := append([]float64{}, coeffs...)
+		cp[j] += h
+		cph, _ := cost(x, y, cp)
+		cp[j] -= 2 * h
+		cmh, _ := cost(x, y, cp)
+		want := (cph - cmh) / (2 * h) * float64(len(x))
+		if !regressiontest.AreFloatEqual(got[j], want, 6) {
+			t.Errorf("got partial derivative %v for coefficient %d, want %v", got[j], j, want)
+		}
+	}
+}
+
+func TestRun_WithSoftmax_EarlyStopping(t *testing.T) {
+	s, err := regressiontest.LoadTrainingSet("n=2_m=150_k=3.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	o := options.WithIterativeConvergence(0, options.LBFGS, 100)
+	m, err := WithSoftmax(o.WithEarlyStopping(options.WithValidationSplit(0.2, 1, 3))).Run(context.Background(), s)
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	if r, _ := m.(regression.ReportingModel).Report(); r.BestIteration == 0 || r.BestIteration > r.Iterations {
+		t.Errorf("got best iteration %d of %d", r.BestIteration, r.Iterations)
+	}
+	v := regression.TrainingSet{X: [][]float64{{1, 2}, {3, 4}}, Y: []float64{1, 4}}
+	_, err = WithSoftmax(o.WithEarlyStopping(options.WithValidationSet(v, 3))).Run(context.Background(), s)
+	var verr *regression.ValidationError
+	if !errors.As(err, &verr) || verr.Reason != regression.UnknownClass || verr.Row != 1 {
+		t.Errorf("want %v at row 1, got %v", regression.UnknownClass, err)
+	}
+	// A target out of the range of int isn't converted to a class label.
+	v.Y[1] = 1e300
+	_, err = WithSoftmax(o.WithEarlyStopping(options.WithValidationSet(v, 3))).Run(context.Background(), s)
+	if !errors.As(err, &verr) || verr.Reason != regression.UnknownClass || verr.Row != 1 {
+		t.Errorf("want %v at row 1, got %v", regression.UnknownClass, err)
+	}
+}
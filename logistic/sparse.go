@@ -0,0 +1,65 @@
+package logistic
+
+import (
+	"context"
+	"math"
+
+	"github.com/erni27/regression"
+	"github.com/erni27/regression/internal/gd"
+	"github.com/erni27/regression/internal/long"
+	"github.com/erni27/regression/internal/ts"
+	"github.com/erni27/regression/options"
+)
+
+var sparseGradientDescent gd.SparseGradientDescent = gd.NewSparse(sparseHyphothesis, sparseCost)
+
+// WithSparseGradientDescent initializes logistic regression with numerical approach for a sparse training set.
+// It finds the value of coefficients by taking steps in each iteration towards the minimum of a cost function.
+// Every step takes time proportional to the number of non-zero features (plus the number of coefficients).
+func WithSparseGradientDescent(o options.Options) regression.SparseRegression[int] {
+	var f regression.SparseRegressionFunc[int] = func(ctx context.Context, s regression.SparseTrainingSet) (regression.SparseModel[int], error) {
+		if err := ts.ValidateSparseBinary(s); err != nil {
+			return nil, err
+		}
+		res, err := long.Run(ctx, func() (gd.Result, error) { return sparseGradientDescent.Run(ctx, o, s.X, s.Y) })
+		if err != nil {
+			return nil, err
+		}
+		coeffs := res.Coefficients
+		var correct int
+		for i := 0; i < s.X.Rows; i++ {
+			hr, err := sparseHyphothesis(s.X.Row(i), coeffs)
+			if err != nil {
+				return nil, err
+			}
+			if classify(hr, DefaultThreshold) == int(s.Y[i]) {
+				correct++
+			}
+		}
+		return model{coeffs: coeffs, acc: float64(correct) / float64(s.X.Rows), report: &res.Report}, nil
+	}
+	return f
+}
+
+// sparseHyphothesis calculates a hyphothesis function value for the logistic regression model and a sparse feature vector.
+// See hyphothesis.
+func sparseHyphothesis(x regression.SparseVector, coeffs []float64) (float64, error) {
+	z, err := ts.Dot(x, coeffs)
+	if err != nil {
+		return 0, err
+	}
+	return 1 / (1 + math.Exp(-z)), nil
+}
+
+// sparseCost calculates a cost function value for the logistic regression on a sparse design matrix.
+func sparseCost(x regression.SparseMatrix, y []float64, coeffs []float64) (float64, error) {
+	var c float64
+	for i := 0; i < x.Rows; i++ {
+		hr, err := sparseHyphothesis(x.Row(i), coeffs)
+		if err != nil {
+			return 0, err
+		}
+		c += -y[i]*math.Log(hr) - (1-y[i])*math.Log(1-hr)
+	}
+	return c / float64(x.Rows), nil
+}
@@ -0,0 +1,41 @@
+package logistic
+
+import (
+	"context"
+	"testing"
+
+	"github.com/erni27/regression/internal/regressiontest"
+	"github.com/erni27/regression/options"
+)
+
+func TestRun_WithSparseGradientDescent(t *testing.T) {
+	s, err := regressiontest.LoadTrainingSet("n=2_m=100.txt")
+	if err != nil {
+		t.Fatalf("cannot load training set %v", err)
+	}
+	got, err := WithSparseGradientDescent(options.WithIterativeConvergence(0, options.LBFGS, 100)).Run(context.Background(), regressiontest.ToSparse(s))
+	if err != nil {
+		t.Fatalf("want nil, got error %v", err)
+	}
+	want := []float64{-25.161, 0.206, 0.201}
+	if coeffs := got.Coefficients(); !regressiontest.AreFloatSlicesEqual(coeffs, want, 3) {
+		t.Errorf("got coefficients %v, want %v", coeffs, want)
+	}
+	if acc := got.Accuracy(); acc != 0.89 {
+		t.Errorf("got acc %v, want %v", acc, 0.89)
+	}
+	sx := regressiontest.ToSparse(s).X
+	for i, x := range s.X {
+		p, err := got.Predict(x)
+		if err != nil {
+			t.Fatalf("want nil, got error %v", err)
+		}
+		sp, err := got.PredictSparse(sx.Row(i))
+		if err != nil {
+			t.Fatalf("want nil, got error %v", err)
+		}
+		if p != sp {
+			t.Fatalf("got sparse prediction %d for example %d, want %d", sp, i, p)
+		}
+	}
+}
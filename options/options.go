@@ -1,6 +1,12 @@
 // Package options contains implementation of types and constants related to the regression options.
 package options
 
+import (
+	"math"
+
+	"github.com/erni27/regression"
+)
+
 // ConvergenceType identifies a convergence type.
 type ConvergenceType int
 
@@ -15,14 +21,257 @@ type GradientDescentVariant int
 const (
 	Batch GradientDescentVariant = iota + 1
 	Stochastic
+	// MiniBatch computes each step on a small batch of training examples. Training examples are shuffled
+	// at the beginning of every epoch (a full pass over the training set).
+	MiniBatch
+	// LBFGS is the limited-memory BFGS quasi-Newton method. It approximates the inverse Hessian of a cost function
+	// with the last steps and finds the step length through a line search, so it doesn't use the learning rate.
+	LBFGS
+	// ConjugateGradient is the nonlinear conjugate gradient method. It finds the step length through a line search,
+	// so it doesn't use the learning rate.
+	ConjugateGradient
+)
+
+// Optimizer identifies an optimizer calculating next values of the coefficients from a cost function gradient.
+type Optimizer int
+
+const (
+	// Vanilla takes steps proportional to the gradient. It's the default optimizer.
+	Vanilla Optimizer = iota
+	// Momentum accelerates steps along consistent gradient directions by accumulating a velocity.
+	Momentum
+	// Nesterov is the momentum with Nesterov's accelerated gradient correction.
+	Nesterov
+	// AdaGrad adapts the learning rate of every coefficient to the accumulated squared gradients.
+	AdaGrad
+	// RMSProp adapts the learning rate of every coefficient to the moving average of squared gradients.
+	RMSProp
+	// Adam combines momentum with RMSProp and corrects the bias of both moving averages.
+	Adam
+)
+
+// LearningRateSchedule identifies how the learning rate changes during training.
+//
+// Schedules are applied per iteration, i.e. per step for the batch and stochastic gradient descent variants
+// and per epoch for the mini-batch one.
+type LearningRateSchedule int
+
+const (
+	// Constant keeps the learning rate unchanged. It's the default schedule.
+	Constant LearningRateSchedule = iota
+	// StepDecay multiplies the learning rate by the decay rate every decay steps iterations, lr*rate^floor(t/steps).
+	StepDecay
+	// ExponentialDecay decays the learning rate smoothly by the decay rate per decay steps iterations, lr*rate^(t/steps).
+	ExponentialDecay
+	// InverseTimeDecay decays the learning rate inversely proportionally to the time, lr/(1+rate*t/steps).
+	InverseTimeDecay
+	// CosineAnnealing decreases the learning rate along a cosine curve over decay steps iterations
+	// down to lr*rate, lr*(rate+(1-rate)*(1+cos(pi*t/steps))/2). Then the learning rate remains constant.
+	CosineAnnealing
+)
+
+// Solver identifies a method solving the linear least squares problem of the normal equation.
+type Solver int
+
+const (
+	// SVD finds the minimum norm solution with the pseudo-inverse computed from the singular value decomposition
+	// of the design matrix. It handles linearly dependent features (e.g. one-hot encoded ones). It's the default solver.
+	// Singular values negligible relative to the largest one are treated as zeros, so it never fails
+	// with regression.ErrIllConditioned: ill-conditioned directions are dropped from the solution instead.
+	SVD Solver = iota
+	// QR uses the Householder QR decomposition of the design matrix. It's faster than SVD and doesn't square
+	// the condition number, but requires linearly independent features.
+	QR
+	// Cholesky uses the Cholesky decomposition of XᵀX. It's the fastest solver, but squares the condition number
+	// and requires linearly independent features.
+	Cholesky
+	// Inverse explicitly inverts XᵀX with the LU decomposition. It squares the condition number
+	// and requires linearly independent features.
+	Inverse
 )
 
 // Options contains training options for a regression with gradient descent.
+//
+// Convergence, learning rate schedules, observers, early stopping and checkpoints count the same iterations.
+// An iteration is a single step, i.e. a single training example for the stochastic gradient descent variant,
+// or a whole epoch for the mini-batch variant.
 type Options struct {
 	LearningRate           float64
 	GradientDescentVariant GradientDescentVariant
 	ConvergenceType        ConvergenceType
 	ConvergenceIndicator   float64
+	// Regularization is the L2 (ridge) regularization strength. Zero disables the regularization.
+	// The intercept is never penalized.
+	Regularization float64
+	// BatchSize is the number of training examples per step of the mini-batch gradient descent variant.
+	// A batch size greater than the training set size means the whole training set.
+	BatchSize int
+	// Seed initializes the random source shuffling training examples in the mini-batch gradient descent variant.
+	Seed int64
+	// Optimizer is the optimizer updating the coefficients. It works with every gradient descent variant.
+	Optimizer Optimizer
+	// Beta1 is the momentum coefficient of Momentum and Nesterov optimizers and the decay rate of the gradients
+	// moving average of Adam. It must be within [0, 1).
+	Beta1 float64
+	// Beta2 is the decay rate of the squared gradients moving average of RMSProp and Adam. It must be within [0, 1).
+	Beta2 float64
+	// Epsilon is the smoothing term of AdaGrad, RMSProp and Adam avoiding division by zero. It must be positive.
+	Epsilon float64
+	// LearningRateSchedule is the schedule of the learning rate. LearningRate is its initial value.
+	LearningRateSchedule LearningRateSchedule
+	// DecayRate is the decay rate of the learning rate schedule. For the cosine annealing it's the ratio
+	// of the final to the initial learning rate.
+	DecayRate float64
+	// DecaySteps is the number of iterations the decay rate refers to.
+	DecaySteps int
+	// WarmupSteps is the number of warmup iterations. The learning rate increases linearly during the warmup
+	// and then follows the schedule. Zero disables the warmup.
+	WarmupSteps int
+	// History is the number of the last steps L-BFGS uses to approximate the inverse Hessian. Zero means 10.
+	History int
+	// Workers is the maximum number of goroutines computing the gradient over the whole training set
+	// (the batch gradient descent variant, L-BFGS and the conjugate gradient method). Zero means GOMAXPROCS.
+	// The number of workers doesn't affect the coefficients.
+	Workers int
+	// LearningCurve records the cost function value and the gradient norm after every iteration
+	// in the training report. It takes an additional pass over the training set per iteration.
+	LearningCurve bool
+	// Observer is called after every ObserveEvery iterations. Nil disables it.
+	Observer Observer
+	// ObserveEvery is the number of iterations between observer calls. Zero means every iteration,
+	// i.e. every training example for the stochastic gradient descent variant.
+	ObserveEvery int
+	// EarlyStopping enables early stopping on a validation set. Nil disables it.
+	EarlyStopping *EarlyStopping
+	// Resume is the checkpoint gradient descent starts from. Nil means zero coefficients.
+	Resume *Checkpoint
+	// Checkpointer is called with a checkpoint after every CheckpointEvery iterations. Nil disables it.
+	Checkpointer Checkpointer
+	// CheckpointEvery is the number of iterations between checkpoints. Zero means every iteration,
+	// i.e. every training example for the stochastic gradient descent variant.
+	CheckpointEvery int
+}
+
+// EarlyStopping contains options for early stopping on a validation set held out from training.
+//
+// The cost function (without the regularization term) is evaluated on the validation set every Every iterations.
+// Gradient descent stops once it hasn't improved for Patience evaluations in a row and returns the coefficients
+// with the lowest validation cost seen, rather than the last ones.
+//
+// Early stopping works with dense training sets only. OneVsRest supports only a validation set carved out
+// of a training set, since class labels of a given validation set aren't separated per binary model.
+type EarlyStopping struct {
+	// Validation is the validation set. If it's empty, Fraction of training examples is held out instead.
+	Validation regression.TrainingSet
+	// Fraction is the fraction of training examples randomly held out as the validation set, within (0, 1).
+	Fraction float64
+	// Seed initializes the random source choosing held out training examples.
+	Seed int64
+	// Every is the number of iterations between evaluations. Zero means every iteration.
+	Every int
+	// Patience is the number of evaluations without improvement after which gradient descent stops.
+	Patience int
+}
+
+// WithValidationSet returns new early stopping options with the given validation set, stopping after patience
+// evaluations without improvement.
+func WithValidationSet(v regression.TrainingSet, patience int) EarlyStopping {
+	return EarlyStopping{Validation: v, Patience: patience}
+}
+
+// WithValidationSplit returns new early stopping options holding out the fraction of training examples chosen
+// with the seed, stopping after patience evaluations without improvement.
+func WithValidationSplit(fraction float64, seed int64, patience int) EarlyStopping {
+	return EarlyStopping{Fraction: fraction, Seed: seed, Patience: patience}
+}
+
+// IsValid checks if early stopping options are valid.
+func (e EarlyStopping) IsValid() bool {
+	if e.Patience < 1 || e.Every < 0 {
+		return false
+	}
+	return len(e.Validation.X) > 0 || (e.Fraction > 0 && e.Fraction < 1)
+}
+
+// Progress describes the state of gradient descent after an iteration.
+type Progress struct {
+	// Iteration is the number of iterations taken so far. An iteration is a single step, i.e. a single training
+	// example for the stochastic gradient descent variant, or a whole epoch for the mini-batch variant.
+	Iteration int
+	// Coefficients are the current coefficients, starting with the intercept. They're a copy owned by the observer.
+	Coefficients []float64
+	// Cost is the current cost function value, including the regularization term.
+	Cost float64
+	// LearningRate is the learning rate of the last step, following the learning rate schedule.
+	// For L-BFGS and the conjugate gradient method it's the step length found by the line search.
+	LearningRate float64
+	// Class is the class label of the binary model being trained by logistic.OneVsRest. It's zero otherwise.
+	Class int
+}
+
+// An Observer observes the progress of gradient descent.
+type Observer interface {
+	// Observe is called synchronously between iterations, so it should return quickly. logistic.OneVsRest trains
+	// binary models in parallel with the same options, then calls of their observer are serialized
+	// and Progress.Class tells the binary models apart.
+	// Returning regression.ErrStopTraining stops gradient descent, which succeeds with the current coefficients.
+	// Any other error aborts the training and is returned by Run.
+	Observe(Progress) error
+}
+
+// ObserverFunc is an adapter to allow the use of plain functions as observers.
+type ObserverFunc func(Progress) error
+
+// Observe calls f(p).
+func (f ObserverFunc) Observe(p Progress) error {
+	return f(p)
+}
+
+// A Checkpoint is a snapshot of gradient descent state between iterations, from which training can be resumed.
+//
+// A checkpoint can be resumed only with the same options and training set it was taken with, then the resumed
+// gradient descent takes exactly the same steps as the interrupted one would. The training report of a resumed
+// gradient descent counts iterations from the beginning, but its learning curve and duration cover only the resumed part.
+// Stochastic and mini-batch steps on a sparse training set with the vanilla optimizer shrink regularized coefficients
+// lazily, so their steps are the same only up to rounding errors.
+//
+// A checkpoint holding only coefficients is a warm start, every other state starts from scratch.
+// Checkpoints refer to a single gradient descent, so they don't apply to OneVsRest, which trains a binary model
+// per class with the same options.
+type Checkpoint struct {
+	// Iteration is the number of iterations taken so far, counted like Progress.Iteration.
+	Iteration int
+	// Coefficients are the coefficients after Iteration iterations, starting with the intercept.
+	Coefficients []float64
+	// Moments is the state of the optimizer, e.g. the moving averages of gradients of Adam, or the search direction
+	// history of L-BFGS and the conjugate gradient method. It's opaque and valid only for the same optimizer.
+	Moments [][]float64
+	// Cursor is the index of the next training example of the stochastic gradient descent variant.
+	Cursor int
+	// BestIteration is the iteration of the coefficients with the lowest validation cost seen with early stopping.
+	BestIteration int
+	// BestCoefficients are the coefficients with the lowest validation cost seen with early stopping,
+	// nil if none have been evaluated yet.
+	BestCoefficients []float64
+	// ValidationCost is the validation cost of BestCoefficients.
+	ValidationCost float64
+	// Stale is the number of evaluations on the validation set without improvement.
+	Stale int
+}
+
+// A Checkpointer saves checkpoints of gradient descent, e.g. to a file.
+type Checkpointer interface {
+	// Checkpoint is called synchronously between iterations with a checkpoint owned by the checkpointer.
+	// An error aborts the training and is returned by Run.
+	Checkpoint(Checkpoint) error
+}
+
+// CheckpointerFunc is an adapter to allow the use of plain functions as checkpointers.
+type CheckpointerFunc func(Checkpoint) error
+
+// Checkpoint calls f(c).
+func (f CheckpointerFunc) Checkpoint(c Checkpoint) error {
+	return f(c)
 }
 
 // WithIterativeConvergence returns new training options with an iterative convergence indicator.
@@ -34,3 +283,200 @@ func WithIterativeConvergence(lr float64, gdv GradientDescentVariant, i uint) Op
 func WithAutomaticConvergence(lr float64, gdv GradientDescentVariant, t float64) Options {
 	return Options{LearningRate: lr, GradientDescentVariant: gdv, ConvergenceType: Automatic, ConvergenceIndicator: t}
 }
+
+// WithRegularization returns a copy of options with the L2 (ridge) regularization strength set to l2.
+func (o Options) WithRegularization(l2 float64) Options {
+	o.Regularization = l2
+	return o
+}
+
+// WithMiniBatch returns a copy of options with the mini-batch gradient descent variant, the given batch size
+// and the seed of the random source shuffling training examples.
+func (o Options) WithMiniBatch(size int, seed int64) Options {
+	o.GradientDescentVariant = MiniBatch
+	o.BatchSize = size
+	o.Seed = seed
+	return o
+}
+
+// WithLBFGS returns a copy of options with the L-BFGS variant which keeps history last steps.
+func (o Options) WithLBFGS(history int) Options {
+	o.GradientDescentVariant = LBFGS
+	o.History = history
+	return o
+}
+
+// WithWorkers returns a copy of options with at most n goroutines computing the gradient.
+func (o Options) WithWorkers(n int) Options {
+	o.Workers = n
+	return o
+}
+
+// WithLearningCurve returns a copy of options recording the learning curve in the training report.
+func (o Options) WithLearningCurve() Options {
+	o.LearningCurve = true
+	return o
+}
+
+// WithObserver returns a copy of options with the observer f called after every n iterations.
+// Zero means every iteration.
+func (o Options) WithObserver(f Observer, n int) Options {
+	o.Observer = f
+	o.ObserveEvery = n
+	return o
+}
+
+// WithEarlyStopping returns a copy of options with early stopping on a validation set.
+func (o Options) WithEarlyStopping(e EarlyStopping) Options {
+	o.EarlyStopping = &e
+	return o
+}
+
+// WithWarmStart returns a copy of options starting gradient descent from the given coefficients,
+// e.g. of a previously trained model, instead of zero coefficients.
+func (o Options) WithWarmStart(coeffs []float64) Options {
+	return o.WithResume(Checkpoint{Coefficients: coeffs})
+}
+
+// WithResume returns a copy of options resuming gradient descent from the checkpoint c.
+func (o Options) WithResume(c Checkpoint) Options {
+	o.Resume = &c
+	return o
+}
+
+// WithCheckpointer returns a copy of options with the checkpointer f called after every n iterations.
+// Zero means every iteration.
+func (o Options) WithCheckpointer(f Checkpointer, n int) Options {
+	o.Checkpointer = f
+	o.CheckpointEvery = n
+	return o
+}
+
+// WithMomentum returns a copy of options with the Momentum optimizer and the momentum coefficient beta.
+func (o Options) WithMomentum(beta float64) Options {
+	o.Optimizer = Momentum
+	o.Beta1 = beta
+	return o
+}
+
+// WithNesterov returns a copy of options with the Nesterov optimizer and the momentum coefficient beta.
+func (o Options) WithNesterov(beta float64) Options {
+	o.Optimizer = Nesterov
+	o.Beta1 = beta
+	return o
+}
+
+// WithAdaGrad returns a copy of options with the AdaGrad optimizer and the smoothing term eps.
+func (o Options) WithAdaGrad(eps float64) Options {
+	o.Optimizer = AdaGrad
+	o.Epsilon = eps
+	return o
+}
+
+// WithRMSProp returns a copy of options with the RMSProp optimizer, the decay rate beta and the smoothing term eps.
+func (o Options) WithRMSProp(beta, eps float64) Options {
+	o.Optimizer = RMSProp
+	o.Beta2 = beta
+	o.Epsilon = eps
+	return o
+}
+
+// WithAdam returns a copy of options with the Adam optimizer, the decay rates beta1, beta2 and the smoothing term eps.
+// Commonly used values are beta1=0.9, beta2=0.999 and eps=1e-8.
+func (o Options) WithAdam(beta1, beta2, eps float64) Options {
+	o.Optimizer = Adam
+	o.Beta1 = beta1
+	o.Beta2 = beta2
+	o.Epsilon = eps
+	return o
+}
+
+// WithStepDecay returns a copy of options with the step decay learning rate schedule. The learning rate is multiplied
+// by rate every steps iterations.
+func (o Options) WithStepDecay(rate float64, steps int) Options {
+	return o.withSchedule(StepDecay, rate, steps)
+}
+
+// WithExponentialDecay returns a copy of options with the exponential decay learning rate schedule.
+// The learning rate decays smoothly by rate per steps iterations.
+func (o Options) WithExponentialDecay(rate float64, steps int) Options {
+	return o.withSchedule(ExponentialDecay, rate, steps)
+}
+
+// WithInverseTimeDecay returns a copy of options with the inverse-time decay learning rate schedule.
+func (o Options) WithInverseTimeDecay(rate float64, steps int) Options {
+	return o.withSchedule(InverseTimeDecay, rate, steps)
+}
+
+// WithCosineAnnealing returns a copy of options with the cosine annealing learning rate schedule. The learning rate
+// decreases over steps iterations down to ratio of the initial one.
+func (o Options) WithCosineAnnealing(steps int, ratio float64) Options {
+	return o.withSchedule(CosineAnnealing, ratio, steps)
+}
+
+// WithWarmup returns a copy of options with steps warmup iterations.
+func (o Options) WithWarmup(steps int) Options {
+	o.WarmupSteps = steps
+	return o
+}
+
+func (o Options) withSchedule(s LearningRateSchedule, rate float64, steps int) Options {
+	o.LearningRateSchedule = s
+	o.DecayRate = rate
+	o.DecaySteps = steps
+	return o
+}
+
+// IsValidRegularization checks if a regularization strength is a non-negative number.
+func IsValidRegularization(r float64) bool {
+	return r >= 0 && !math.IsInf(r, 1)
+}
+
+// PathOptions contains options for a regularization path computation.
+type PathOptions struct {
+	// Alpha is the elastic net mixing parameter within [0, 1]. A regularization strength lambda is split
+	// into the L1 strength alpha*lambda and the L2 strength (1-alpha)*lambda, so 1 means lasso and 0 means ridge.
+	Alpha float64
+	// Lambdas is the number of regularization strengths on the path.
+	Lambdas int
+	// MinRatio is the ratio of the smallest to the largest regularization strength within (0, 1).
+	MinRatio float64
+}
+
+// WithRegularizationPath returns new regularization path options.
+func WithRegularizationPath(alpha float64, lambdas int, ratio float64) PathOptions {
+	return PathOptions{Alpha: alpha, Lambdas: lambdas, MinRatio: ratio}
+}
+
+// IsValid checks if regularization path options are valid.
+func (o PathOptions) IsValid() bool {
+	return o.Alpha >= 0 && o.Alpha <= 1 && o.Lambdas > 0 && o.MinRatio > 0 && o.MinRatio < 1
+}
+
+// LeastSquaresOptions contains options for an iterative least squares solver.
+type LeastSquaresOptions struct {
+	// Tolerance stops the solver once the norm of the normal equation residual drops below Tolerance
+	// times its initial value.
+	Tolerance float64
+	// MaxIterations limits the number of iterations.
+	MaxIterations int
+	// Regularization is the L2 (ridge) regularization strength. Zero disables the regularization.
+	// The intercept is never penalized.
+	Regularization float64
+}
+
+// WithLeastSquares returns new iterative least squares solver options with tolerance t and at most i iterations.
+func WithLeastSquares(t float64, i int) LeastSquaresOptions {
+	return LeastSquaresOptions{Tolerance: t, MaxIterations: i}
+}
+
+// WithRegularization returns a copy of options with the L2 (ridge) regularization strength set to l2.
+func (o LeastSquaresOptions) WithRegularization(l2 float64) LeastSquaresOptions {
+	o.Regularization = l2
+	return o
+}
+
+// IsValid checks if iterative least squares solver options are valid.
+func (o LeastSquaresOptions) IsValid() bool {
+	return o.Tolerance >= 0 && o.Tolerance < 1 && o.MaxIterations > 0 && IsValidRegularization(o.Regularization)
+}
@@ -1,7 +1,10 @@
 package options
 
 import (
+	"math"
 	"testing"
+
+	"github.com/erni27/regression"
 )
 
 func TestWithAutomaticConvergence(t *testing.T) {
@@ -61,3 +64,255 @@ func TestWithIterativeConvergence(t *testing.T) {
 		})
 	}
 }
+
+func TestWithRegularization(t *testing.T) {
+	o := WithIterativeConvergence(0.001, Batch, 1000)
+	r := o.WithRegularization(0.5)
+	if l2 := r.Regularization; l2 != 0.5 {
+		t.Errorf("want %f, got %f", 0.5, l2)
+	}
+	if o.Regularization != 0 {
+		t.Errorf("want original options unchanged, got regularization %f", o.Regularization)
+	}
+	if r.LearningRate != o.LearningRate || r.ConvergenceIndicator != o.ConvergenceIndicator {
+		t.Errorf("want other options unchanged, got %v", r)
+	}
+}
+
+func TestWithMiniBatch(t *testing.T) {
+	o := WithAutomaticConvergence(0.01, Batch, 0.001).WithMiniBatch(32, 42)
+	if o.GradientDescentVariant != MiniBatch {
+		t.Errorf("want %v, got %v", MiniBatch, o.GradientDescentVariant)
+	}
+	if o.BatchSize != 32 {
+		t.Errorf("want batch size %d, got %d", 32, o.BatchSize)
+	}
+	if o.Seed != 42 {
+		t.Errorf("want seed %d, got %d", 42, o.Seed)
+	}
+	if o.LearningRate != 0.01 || o.ConvergenceType != Automatic || o.ConvergenceIndicator != 0.001 {
+		t.Errorf("want other options unchanged, got %v", o)
+	}
+}
+
+func TestWithLBFGS(t *testing.T) {
+	o := WithIterativeConvergence(0, Batch, 100).WithLBFGS(5)
+	if o.GradientDescentVariant != LBFGS {
+		t.Errorf("want %v, got %v", LBFGS, o.GradientDescentVariant)
+	}
+	if o.History != 5 {
+		t.Errorf("want history %d, got %d", 5, o.History)
+	}
+	if o.ConvergenceType != Iterative || o.ConvergenceIndicator != 100 {
+		t.Errorf("want other options unchanged, got %v", o)
+	}
+}
+
+func TestWithOptimizer(t *testing.T) {
+	o := WithIterativeConvergence(0.01, Batch, 1000)
+	tests := []struct {
+		name string
+		got  Options
+		want Options
+	}{
+		{name: "momentum", got: o.WithMomentum(0.9), want: Options{Optimizer: Momentum, Beta1: 0.9}},
+		{name: "nesterov", got: o.WithNesterov(0.8), want: Options{Optimizer: Nesterov, Beta1: 0.8}},
+		{name: "adagrad", got: o.WithAdaGrad(1e-8), want: Options{Optimizer: AdaGrad, Epsilon: 1e-8}},
+		{name: "rmsprop", got: o.WithRMSProp(0.9, 1e-6), want: Options{Optimizer: RMSProp, Beta2: 0.9, Epsilon: 1e-6}},
+		{name: "adam", got: o.WithAdam(0.9, 0.999, 1e-8), want: Options{Optimizer: Adam, Beta1: 0.9, Beta2: 0.999, Epsilon: 1e-8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := o
+			want.Optimizer, want.Beta1, want.Beta2, want.Epsilon = tt.want.Optimizer, tt.want.Beta1, tt.want.Beta2, tt.want.Epsilon
+			if tt.got != want {
+				t.Errorf("want %v, got %v", want, tt.got)
+			}
+		})
+	}
+	if o.Optimizer != Vanilla {
+		t.Errorf("want original options unchanged, got optimizer %v", o.Optimizer)
+	}
+}
+
+func TestWithSchedule(t *testing.T) {
+	o := WithIterativeConvergence(0.01, Stochastic, 1000)
+	tests := []struct {
+		name     string
+		got      Options
+		schedule LearningRateSchedule
+		rate     float64
+		steps    int
+	}{
+		{name: "step decay", got: o.WithStepDecay(0.5, 100), schedule: StepDecay, rate: 0.5, steps: 100},
+		{name: "exponential decay", got: o.WithExponentialDecay(0.9, 10), schedule: ExponentialDecay, rate: 0.9, steps: 10},
+		{name: "inverse-time decay", got: o.WithInverseTimeDecay(1, 50), schedule: InverseTimeDecay, rate: 1, steps: 50},
+		{name: "cosine annealing", got: o.WithCosineAnnealing(1000, 0.01), schedule: CosineAnnealing, rate: 0.01, steps: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got.LearningRateSchedule != tt.schedule || tt.got.DecayRate != tt.rate || tt.got.DecaySteps != tt.steps {
+				t.Errorf("want schedule %v with rate %v and steps %d, got %v", tt.schedule, tt.rate, tt.steps, tt.got)
+			}
+			if tt.got.LearningRate != o.LearningRate || tt.got.GradientDescentVariant != o.GradientDescentVariant {
+				t.Errorf("want other options unchanged, got %v", tt.got)
+			}
+		})
+	}
+	if w := o.WithWarmup(10); w.WarmupSteps != 10 || w.LearningRateSchedule != Constant {
+		t.Errorf("want 10 warmup steps with constant schedule, got %v", w)
+	}
+}
+
+func TestIsValidRegularization(t *testing.T) {
+	tests := []struct {
+		name string
+		r    float64
+		want bool
+	}{
+		{name: "zero", r: 0, want: true},
+		{name: "positive", r: 2.5, want: true},
+		{name: "negative", r: -1, want: false},
+		{name: "infinity", r: math.Inf(1), want: false},
+		{name: "nan", r: math.NaN(), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsValidRegularization(tt.r); got != tt.want {
+				t.Errorf("want %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPathOptionsIsValid(t *testing.T) {
+	tests := []struct {
+		name string
+		o    PathOptions
+		want bool
+	}{
+		{name: "lasso", o: WithRegularizationPath(1, 100, 0.001), want: true},
+		{name: "ridge", o: WithRegularizationPath(0, 100, 0.001), want: true},
+		{name: "negative alpha", o: WithRegularizationPath(-0.1, 100, 0.001), want: false},
+		{name: "alpha greater than one", o: WithRegularizationPath(1.1, 100, 0.001), want: false},
+		{name: "no lambdas", o: WithRegularizationPath(0.5, 0, 0.001), want: false},
+		{name: "zero ratio", o: WithRegularizationPath(0.5, 100, 0), want: false},
+		{name: "ratio equals one", o: WithRegularizationPath(0.5, 100, 1), want: false},
+		{name: "nan alpha", o: WithRegularizationPath(math.NaN(), 100, 0.001), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.o.IsValid(); got != tt.want {
+				t.Errorf("want %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLeastSquaresOptionsIsValid(t *testing.T) {
+	tests := []struct {
+		name string
+		o    LeastSquaresOptions
+		want bool
+	}{
+		{name: "valid", o: WithLeastSquares(1e-6, 100), want: true},
+		{name: "zero tolerance", o: WithLeastSquares(0, 100), want: true},
+		{name: "ridge", o: WithLeastSquares(1e-6, 100).WithRegularization(10), want: true},
+		{name: "negative tolerance", o: WithLeastSquares(-1e-6, 100), want: false},
+		{name: "tolerance equals one", o: WithLeastSquares(1, 100), want: false},
+		{name: "nan tolerance", o: WithLeastSquares(math.NaN(), 100), want: false},
+		{name: "no iterations", o: WithLeastSquares(1e-6, 0), want: false},
+		{name: "negative regularization", o: WithLeastSquares(1e-6, 100).WithRegularization(-1), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.o.IsValid(); got != tt.want {
+				t.Errorf("want %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWithWorkers(t *testing.T) {
+	o := WithIterativeConvergence(0.01, Batch, 1000)
+	w := o.WithWorkers(4)
+	if w.Workers != 4 {
+		t.Errorf("want %d workers, got %d", 4, w.Workers)
+	}
+	if o.Workers != 0 {
+		t.Errorf("want original options unchanged, got %d workers", o.Workers)
+	}
+}
+
+func TestWithLearningCurve(t *testing.T) {
+	o := WithIterativeConvergence(0.01, Batch, 1000)
+	if lc := o.WithLearningCurve(); !lc.LearningCurve {
+		t.Errorf("want learning curve recorded")
+	}
+	if o.LearningCurve {
+		t.Errorf("want original options unchanged")
+	}
+}
+
+func TestWithObserver(t *testing.T) {
+	var calls int
+	o := WithIterativeConvergence(0.01, Batch, 1000).WithObserver(ObserverFunc(func(Progress) error { calls++; return nil }), 5)
+	if o.Observer == nil || o.ObserveEvery != 5 {
+		t.Fatalf("want observer called every %d iterations, got every %d", 5, o.ObserveEvery)
+	}
+	if err := o.Observer.Observe(Progress{}); err != nil || calls != 1 {
+		t.Errorf("want observer called once, got %d calls and error %v", calls, err)
+	}
+}
+
+func TestEarlyStopping_IsValid(t *testing.T) {
+	v := regression.TrainingSet{X: [][]float64{{1}}, Y: []float64{1}}
+	tests := []struct {
+		name string
+		e    EarlyStopping
+		want bool
+	}{
+		{name: "validation set", e: WithValidationSet(v, 3), want: true},
+		{name: "validation split", e: WithValidationSplit(0.2, 1, 3), want: true},
+		{name: "no patience", e: WithValidationSplit(0.2, 1, 0), want: false},
+		{name: "negative cadence", e: EarlyStopping{Fraction: 0.2, Every: -1, Patience: 3}, want: false},
+		{name: "empty validation set", e: WithValidationSet(regression.TrainingSet{}, 3), want: false},
+		{name: "fraction of 1", e: WithValidationSplit(1, 1, 3), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.e.IsValid(); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+	o := WithIterativeConvergence(0.01, Batch, 1000)
+	if e := o.WithEarlyStopping(WithValidationSplit(0.2, 1, 3)).EarlyStopping; e == nil || e.Patience != 3 || o.EarlyStopping != nil {
+		t.Errorf("want early stopping set on a copy of options, got %v", e)
+	}
+}
+
+func TestWithWarmStart(t *testing.T) {
+	o := WithIterativeConvergence(0.01, Batch, 1000)
+	w := o.WithWarmStart([]float64{1, 2})
+	if w.Resume == nil || len(w.Resume.Coefficients) != 2 || w.Resume.Iteration != 0 || w.Resume.Moments != nil {
+		t.Fatalf("want a checkpoint holding only coefficients, got %+v", w.Resume)
+	}
+	if r := o.WithResume(Checkpoint{Iteration: 5}).Resume; r == nil || r.Iteration != 5 {
+		t.Errorf("want resumed after iteration %d, got %+v", 5, r)
+	}
+	if o.Resume != nil {
+		t.Errorf("want original options unchanged")
+	}
+}
+
+func TestWithCheckpointer(t *testing.T) {
+	var calls int
+	o := WithIterativeConvergence(0.01, Batch, 1000).WithCheckpointer(CheckpointerFunc(func(Checkpoint) error { calls++; return nil }), 5)
+	if o.Checkpointer == nil || o.CheckpointEvery != 5 {
+		t.Fatalf("want checkpointer called every %d iterations, got every %d", 5, o.CheckpointEvery)
+	}
+	if err := o.Checkpointer.Checkpoint(Checkpoint{}); err != nil || calls != 1 {
+		t.Errorf("want checkpointer called once, got %d calls and error %v", calls, err)
+	}
+}
@@ -5,6 +5,8 @@ package regression
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 )
 
 var (
@@ -12,6 +14,36 @@ var (
 	ErrCannotConverge = errors.New("cannot converge")
 	// ErrUnsupportedGradientDescentVariant is returned if unsupported gradient descent variant was chosen.
 	ErrUnsupportedGradientDescentVariant = errors.New("unsupported gradient descent variant")
+	// ErrInvalidBatchSize is returned if a batch size of the mini-batch gradient descent variant isn't positive.
+	ErrInvalidBatchSize = errors.New("invalid batch size")
+	// ErrUnsupportedOptimizer is returned if unsupported optimizer was chosen.
+	ErrUnsupportedOptimizer = errors.New("unsupported optimizer")
+	// ErrInvalidOptimizer is returned if optimizer hyperparameters are invalid.
+	ErrInvalidOptimizer = errors.New("invalid optimizer")
+	// ErrUnsupportedLearningRateSchedule is returned if unsupported learning rate schedule was chosen.
+	ErrUnsupportedLearningRateSchedule = errors.New("unsupported learning rate schedule")
+	// ErrInvalidLearningRateSchedule is returned if learning rate schedule parameters are invalid.
+	ErrInvalidLearningRateSchedule = errors.New("invalid learning rate schedule")
+	// ErrInvalidHistory is returned if the L-BFGS history size is negative.
+	ErrInvalidHistory = errors.New("invalid history")
+	// ErrInvalidWorkers is returned if the number of workers is negative.
+	ErrInvalidWorkers = errors.New("invalid workers")
+	// ErrInvalidObserver is returned if the number of iterations between observer calls is negative.
+	ErrInvalidObserver = errors.New("invalid observer")
+	// ErrInvalidCheckpoint is returned if a checkpoint or initial coefficients don't match training options or
+	// a training set, or the number of iterations between checkpoints is negative.
+	ErrInvalidCheckpoint = errors.New("invalid checkpoint")
+	// ErrInvalidEarlyStopping is returned if early stopping options are invalid or early stopping isn't supported
+	// by a regression.
+	ErrInvalidEarlyStopping = errors.New("invalid early stopping")
+	// ErrStopTraining is returned by an observer to stop gradient descent early. Gradient descent then succeeds
+	// with the current coefficients, so Run never returns it.
+	ErrStopTraining = errors.New("stop training")
+	// ErrUnsupportedSolver is returned if unsupported normal equation solver was chosen.
+	ErrUnsupportedSolver = errors.New("unsupported solver")
+	// ErrIllConditioned is returned if the normal equation is too ill-conditioned to be solved accurately.
+	// Scaling features or choosing a more robust solver usually helps.
+	ErrIllConditioned = errors.New("ill-conditioned normal equation")
 	// ErrUnsupportedConvergenceType is returned if unsupported convergence type was chosen.
 	ErrUnsupportedConvergenceType = errors.New("unsupported convergence type")
 	// ErrInvalidTrainingSet is returned if a design matrix is invalid or doesn't have the same length as a target vector.
@@ -20,6 +52,23 @@ var (
 	ErrInvalidFeatureVector = errors.New("invalid feature vector")
 	// ErrInvalidDesignMatrix is returned if a design matrix is invalid in a given context.
 	ErrInvalidDesignMatrix = errors.New("invalid design matrix")
+	// ErrInvalidRegularization is returned if a regularization strength is negative or not a finite number.
+	ErrInvalidRegularization = errors.New("invalid regularization")
+	// ErrInvalidRegularizationPath is returned if regularization path options are invalid.
+	ErrInvalidRegularizationPath = errors.New("invalid regularization path")
+	// ErrInvalidForgettingFactor is returned if a forgetting factor of recursive least squares isn't within (0, 1].
+	ErrInvalidForgettingFactor = errors.New("invalid forgetting factor")
+	// ErrInvalidLeastSquares is returned if iterative least squares solver options are invalid.
+	ErrInvalidLeastSquares = errors.New("invalid least squares options")
+	// ErrInvalidAccumulator is returned if serialized sufficient statistics are malformed or accumulators
+	// of training sets with different numbers of features are merged.
+	ErrInvalidAccumulator = errors.New("invalid accumulator")
+	// ErrInvalidModel is returned if a serialized model is malformed.
+	ErrInvalidModel = errors.New("invalid model")
+	// ErrUnsupportedModelVersion is returned if a serialized model was saved in an unsupported format version.
+	ErrUnsupportedModelVersion = errors.New("unsupported model version")
+	// ErrModelKindMismatch is returned if a serialized model was saved by a different package than the one loading it.
+	ErrModelKindMismatch = errors.New("model kind mismatch")
 )
 
 // TargetType is a constraint that permits two types (float64 or integer) for target value.
@@ -39,6 +88,27 @@ type Model[T TargetType] interface {
 	Accuracy() float64
 }
 
+// A ProbabilisticModel is a trained binary classification model which estimates
+// the probability of the positive class.
+type ProbabilisticModel interface {
+	Model[int]
+	// PredictProba returns the estimated probability that the given input belongs to the positive class.
+	PredictProba([]float64) (float64, error)
+}
+
+// A MulticlassModel is a trained classification model which distinguishes between more than two classes.
+//
+// Its Coefficients method returns coefficient vectors of all classes concatenated in the order of Classes.
+type MulticlassModel interface {
+	Model[int]
+	// Classes returns class labels known to the model in ascending order.
+	Classes() []int
+	// ClassProbabilities returns the estimated probability of each class for the given input, in the order of Classes.
+	ClassProbabilities([]float64) ([]float64, error)
+	// ClassCoefficients returns the coefficients vector of each class, in the order of Classes.
+	ClassCoefficients() [][]float64
+}
+
 // A Regression is a regression runner. It provides an abstraction for model training.
 type Regression[T TargetType] interface {
 	// Run runs regression against input training set.
@@ -54,6 +124,98 @@ func (f RegressionFunc[T]) Run(ctx context.Context, s TrainingSet) (Model[T], er
 	return f(ctx, s)
 }
 
+// An OnlineRegression learns incrementally from training examples as they arrive, without keeping them in memory.
+// Its methods are safe for concurrent use, Predict doesn't wait for PartialFit to finish.
+type OnlineRegression[T TargetType] interface {
+	// PartialFit updates the model with a mini-batch of training examples.
+	PartialFit(context.Context, TrainingSet) error
+	// Predict returns the predicated target value for the given input with the coefficients learned so far.
+	Predict([]float64) (T, error)
+	// Snapshot returns a model with the coefficients learned so far. It doesn't change with further updates.
+	Snapshot() Model[T]
+}
+
+// A PathPoint is a model trained with a single regularization strength, being a part of a regularization path.
+type PathPoint[T TargetType] struct {
+	// Lambda is the regularization strength.
+	Lambda float64
+	// Model is the trained model.
+	Model Model[T]
+	// Cost is the training cost function value without the penalty term.
+	Cost float64
+	// DegreesOfFreedom is the effective number of model's parameters, excluding the intercept.
+	DegreesOfFreedom float64
+}
+
+// StopReason describes why gradient descent stopped.
+type StopReason int
+
+const (
+	// IterationLimit means gradient descent took the number of iterations set by the iterative convergence.
+	IterationLimit StopReason = iota + 1
+	// Converged means a cost function decreased by less than the threshold of the automatic convergence.
+	Converged
+	// Stationary means a cost function couldn't be decreased any further within the floating point precision.
+	Stationary
+	// Stopped means an observer requested to stop with ErrStopTraining.
+	Stopped
+	// EarlyStopped means the validation cost didn't improve for the number of evaluations set by early stopping.
+	EarlyStopped
+)
+
+var stopReasons = [...]string{
+	IterationLimit: "iteration limit",
+	Converged:      "converged",
+	Stationary:     "stationary point",
+	Stopped:        "stopped by observer",
+	EarlyStopped:   "early stopped",
+}
+
+func (r StopReason) String() string {
+	if r > 0 && int(r) < len(stopReasons) {
+		return stopReasons[r]
+	}
+	return fmt.Sprintf("StopReason(%d)", int(r))
+}
+
+// A Report describes how a model was trained with gradient descent.
+type Report struct {
+	// Iterations is the number of iterations taken. An iteration is a single step, i.e. a single training example
+	// for the stochastic gradient descent variant, or a whole epoch for the mini-batch variant.
+	Iterations int
+	// StopReason tells why gradient descent stopped.
+	StopReason StopReason
+	// Cost is the cost function value of the returned coefficients, including the regularization term.
+	Cost float64
+	// BestIteration is the iteration after which the returned coefficients had the lowest validation cost.
+	// It's set only with early stopping, which returns the best coefficients rather than the last ones.
+	BestIteration int
+	// ValidationCost is the cost function value of the returned coefficients on the validation set, excluding
+	// the regularization term. It's set only with early stopping.
+	ValidationCost float64
+	// Duration is the wall time of gradient descent.
+	Duration time.Duration
+	// LearningCurve holds the course of every iteration if it was requested in options, otherwise it's nil.
+	LearningCurve []Iteration
+}
+
+// An Iteration describes the state of gradient descent after a single iteration.
+type Iteration struct {
+	// Cost is the cost function value, including the regularization term.
+	Cost float64
+	// GradientNorm is the Euclidean norm of the cost function gradient averaged over training examples
+	// of the last step. For the stochastic gradient descent variant it's the gradient of a single training example,
+	// for the mini-batch variant it's the gradient of the last mini-batch of an epoch.
+	GradientNorm float64
+}
+
+// A ReportingModel is a model which may report how it was trained.
+type ReportingModel interface {
+	// Report returns the report of the model's training. It returns false if the model wasn't trained
+	// with gradient descent, e.g. it was computed with the normal equation or loaded from its serialized form.
+	Report() (Report, bool)
+}
+
 // TrainingSet represents a set of traning examples.
 type TrainingSet struct {
 	// X is a design matrix.
@@ -0,0 +1,64 @@
+package regression
+
+import "context"
+
+// A SparseVector is a feature vector which keeps only its non-zero features.
+type SparseVector struct {
+	// Indices are indices of non-zero features in ascending order.
+	Indices []int
+	// Values are values of non-zero features, Values[k] is the value of the feature Indices[k].
+	Values []float64
+}
+
+// A SparseMatrix is a design matrix in the compressed sparse row (CSR) format. Only non-zero features are stored.
+//
+// Indices and values of non-zero features of the i-th row are ColIndices[RowOffsets[i]:RowOffsets[i+1]]
+// and Values[RowOffsets[i]:RowOffsets[i+1]] respectively.
+type SparseMatrix struct {
+	// Rows is the number of rows (training examples).
+	Rows int
+	// Cols is the number of columns (features).
+	Cols int
+	// RowOffsets has Rows+1 elements. It starts with zero and ends with the number of non-zero features.
+	RowOffsets []int
+	// ColIndices are column indices of non-zero features, in ascending order within every row.
+	ColIndices []int
+	// Values are values of non-zero features.
+	Values []float64
+}
+
+// Row returns the i-th row as a sparse vector. It shares storage with the matrix.
+func (m SparseMatrix) Row(i int) SparseVector {
+	s, e := m.RowOffsets[i], m.RowOffsets[i+1]
+	return SparseVector{Indices: m.ColIndices[s:e], Values: m.Values[s:e]}
+}
+
+// SparseTrainingSet represents a set of training examples with a sparse design matrix.
+type SparseTrainingSet struct {
+	// X is a sparse design matrix.
+	X SparseMatrix
+	// Y is a target vector.
+	Y []float64
+}
+
+// A SparseModel is a trained regression model which accepts sparse feature vectors as well.
+type SparseModel[T TargetType] interface {
+	Model[T]
+	// PredictSparse returns the predicated target value for the given sparse input.
+	PredictSparse(SparseVector) (T, error)
+}
+
+// A SparseRegression is a regression runner for training sets with a sparse design matrix.
+type SparseRegression[T TargetType] interface {
+	// Run runs regression against input sparse training set.
+	// It returns trained SparseModel if succeeded, otherwise returns an error.
+	Run(context.Context, SparseTrainingSet) (SparseModel[T], error)
+}
+
+// SparseRegressionFunc is an adapter to allow the use of plain functions as sparse regressions.
+type SparseRegressionFunc[T TargetType] func(context.Context, SparseTrainingSet) (SparseModel[T], error)
+
+// Run calls f(s).
+func (f SparseRegressionFunc[T]) Run(ctx context.Context, s SparseTrainingSet) (SparseModel[T], error) {
+	return f(ctx, s)
+}
@@ -0,0 +1,88 @@
+package regression
+
+import "fmt"
+
+// ValidationReason describes why a training set or a feature vector is invalid.
+type ValidationReason int
+
+const (
+	// EmptyTrainingSet means a training set without training examples or features.
+	EmptyTrainingSet ValidationReason = iota + 1
+	// LengthMismatch means a target vector doesn't have as many elements as a design matrix has rows,
+	// or a feature vector doesn't have as many features as a model expects.
+	LengthMismatch
+	// RaggedRow means a row of a design matrix doesn't have as many features as the first one.
+	RaggedRow
+	// MalformedRow means a row of a sparse design matrix has inconsistent offsets, indices or values.
+	MalformedRow
+	// NonFiniteFeature means a feature equals NaN or infinity.
+	NonFiniteFeature
+	// NonFiniteTarget means a target equals NaN or infinity.
+	NonFiniteTarget
+	// NonBinaryTarget means a target of a binary classification is neither 0 nor 1.
+	NonBinaryTarget
+	// NonIntegerTarget means a target of a multiclass classification isn't an integer class label within the range of int.
+	NonIntegerTarget
+	// SingleClass means a target vector of a classification contains fewer than two classes.
+	SingleClass
+	// TooFewRows means a design matrix doesn't have more rows than features.
+	TooFewRows
+	// UnknownClass means a target of a validation set isn't one of the classes of a training set.
+	UnknownClass
+)
+
+var validationReasons = [...]string{
+	EmptyTrainingSet: "empty training set",
+	LengthMismatch:   "length mismatch",
+	RaggedRow:        "ragged row",
+	MalformedRow:     "malformed row",
+	NonFiniteFeature: "non-finite feature",
+	NonFiniteTarget:  "non-finite target",
+	NonBinaryTarget:  "non-binary target",
+	NonIntegerTarget: "non-integer target",
+	SingleClass:      "single class",
+	TooFewRows:       "fewer rows than features",
+	UnknownClass:     "unknown class",
+}
+
+func (r ValidationReason) String() string {
+	if r > 0 && int(r) < len(validationReasons) {
+		return validationReasons[r]
+	}
+	return fmt.Sprintf("ValidationReason(%d)", int(r))
+}
+
+// A ValidationError describes an invalid training set or feature vector. It wraps ErrInvalidTrainingSet
+// or ErrInvalidFeatureVector, so it can be checked with errors.Is.
+type ValidationError struct {
+	// Err is either ErrInvalidTrainingSet or ErrInvalidFeatureVector.
+	Err error
+	// Reason describes what is wrong.
+	Reason ValidationReason
+	// Row is the index of the offending training example, -1 if the error doesn't refer to a single one.
+	Row int
+	// Column is the index of the offending feature, -1 if the error doesn't refer to a single one.
+	Column int
+	// Detail optionally explains the error, e.g. with expected and actual lengths.
+	Detail string
+}
+
+func (e *ValidationError) Error() string {
+	s := fmt.Sprintf("%v: %v", e.Err, e.Reason)
+	switch {
+	case e.Row >= 0 && e.Column >= 0:
+		s += fmt.Sprintf(" at row %d, column %d", e.Row, e.Column)
+	case e.Row >= 0:
+		s += fmt.Sprintf(" at row %d", e.Row)
+	case e.Column >= 0:
+		s += fmt.Sprintf(" at column %d", e.Column)
+	}
+	if e.Detail != "" {
+		s += " (" + e.Detail + ")"
+	}
+	return s
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}